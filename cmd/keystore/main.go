@@ -0,0 +1,79 @@
+// Command keystore creates and inspects the encrypted keystore used by the
+// "file" key provider.
+//
+//	keystore seal -in secrets.json -out keystore.json -passphrase-file pass.txt
+//	keystore list -in keystore.json -passphrase-file pass.txt
+//
+// secrets.json is a flat JSON object of secret name to value, e.g.
+// {"ACCOUNT_ACCESS_TOKEN_SECRET_KEY": "...", "SERVICE_ENCRYPT_KEYS": "1:..."}.
+package main
+
+import (
+	"aspire-auth/internal/keyprovider"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: keystore <seal|list> [flags]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	in := flags.String("in", "", "input file")
+	out := flags.String("out", "keystore.json", "output keystore file (seal only)")
+	passphraseFile := flags.String("passphrase-file", "", "file containing the keystore passphrase")
+	flags.Parse(os.Args[2:])
+
+	if *in == "" || *passphraseFile == "" {
+		log.Fatal("-in and -passphrase-file are required")
+	}
+
+	passphrase, err := keyprovider.ReadPassphraseFile(*passphraseFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", *in, err)
+	}
+
+	switch os.Args[1] {
+	case "seal":
+		secrets := map[string]string{}
+		if err := json.Unmarshal(data, &secrets); err != nil {
+			log.Fatalf("Error parsing secrets: %v", err)
+		}
+		sealed, err := keyprovider.SealKeystore(secrets, passphrase)
+		if err != nil {
+			log.Fatalf("Error sealing keystore: %v", err)
+		}
+		if err := os.WriteFile(*out, sealed, 0600); err != nil {
+			log.Fatalf("Error writing keystore: %v", err)
+		}
+		log.Printf("Sealed %d secrets into %s", len(secrets), *out)
+
+	case "list":
+		secrets, err := keyprovider.OpenKeystore(data, passphrase)
+		if err != nil {
+			log.Fatal(err)
+		}
+		names := make([]string, 0, len(secrets))
+		for name := range secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Println(name)
+		}
+
+	default:
+		log.Fatalf("unknown command %q", os.Args[1])
+	}
+}
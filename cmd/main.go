@@ -66,10 +66,6 @@ func main() {
 	log.Printf("Database URL: %s", maskSensitiveInfo(os.Getenv("DB_CONNECTION_URL")))
 	log.Printf("Redis Address: %s", os.Getenv("REDIS_ADDRESS"))
 
-	// Print JWT secrets for debugging (masked)
-	log.Printf("Account Access Secret: %s", maskSensitiveInfo(os.Getenv("ACCOUNT_ACCESS_TOKEN_SECRET_KEY")))
-	log.Printf("Service Access Secret: %s", maskSensitiveInfo(os.Getenv("SERVICE_ACCESS_TOKEN_SECRET_KEY")))
-
 	// Print a configuration summary
 	cfg := config.Load()
 	utils.PrintConfigSummary(cfg)
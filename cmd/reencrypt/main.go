@@ -0,0 +1,92 @@
+// Command reencrypt migrates every stored service secret to the current
+// master key version. It is safe to run repeatedly; rows already encrypted
+// with the current key are skipped.
+package main
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/keyprovider"
+	"aspire-auth/internal/models"
+	"flag"
+	"log"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report rows that need re-encryption without updating them")
+	batchSize := flag.Int("batch-size", 100, "number of services loaded per batch")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: Could not load .env file, using environment variables")
+	}
+
+	cfg := config.Load()
+	provider, err := keyprovider.New(cfg.Keys)
+	if err != nil {
+		log.Fatalf("Error initializing key provider: %v", err)
+	}
+	jwtHelpers, err := helpers.InitJWTHelpers(cfg, provider)
+	if err != nil {
+		log.Fatalf("Error loading secrets: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.Database.URL))
+	if err != nil {
+		log.Fatalf("Error connecting to database: %v", err)
+	}
+
+	log.Printf("Re-encrypting service secrets with key version %d", jwtHelpers.CurrentKeyVersion())
+
+	var migrated, skipped, failed int
+	var services []models.Service
+	result := db.Select("id", "secret_key").FindInBatches(&services, *batchSize, func(tx *gorm.DB, batch int) error {
+		for _, service := range services {
+			if !jwtHelpers.ServiceSecretNeedsReencryption(service.SecretKey) {
+				skipped++
+				continue
+			}
+
+			reencrypted, err := jwtHelpers.ReencryptServiceSecretKey(service.SecretKey)
+			if err != nil {
+				log.Printf("Failed to re-encrypt secret for service %s: %v", service.ID, err)
+				failed++
+				continue
+			}
+
+			if *dryRun {
+				migrated++
+				continue
+			}
+
+			// Only overwrite the value we read, in case it was rotated concurrently
+			update := db.Model(&models.Service{}).
+				Where("id = ? AND secret_key = ?", service.ID, service.SecretKey).
+				Update("secret_key", reencrypted)
+			if update.Error != nil {
+				log.Printf("Failed to update secret for service %s: %v", service.ID, update.Error)
+				failed++
+				continue
+			}
+			migrated++
+		}
+		return nil
+	})
+	if result.Error != nil {
+		log.Fatalf("Error loading services: %v", result.Error)
+	}
+
+	if *dryRun {
+		log.Printf("Dry run: %d to migrate, %d already current, %d failed", migrated, skipped, failed)
+	} else {
+		log.Printf("Done: %d migrated, %d already current, %d failed", migrated, skipped, failed)
+	}
+
+	if failed > 0 {
+		log.Fatalf("%d service secrets could not be re-encrypted", failed)
+	}
+}
@@ -0,0 +1,27 @@
+// Command s3fake is a local stand-in for an S3-compatible object store. It
+// keeps objects in memory, serves path-style PUT, GET, HEAD and DELETE, and
+// rejects requests whose SigV4 signature or payload hash does not check out,
+// including presigned GET URLs. Run it and set STORAGE_BACKEND=s3,
+// S3_ENDPOINT=http://localhost:4020 and the same bucket and keys.
+package main
+
+import (
+	"aspire-auth/internal/storage/s3fake"
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:4020", "address to listen on")
+	accessKey := flag.String("access-key", "fake-access-key", "access key clients must sign with")
+	secretKey := flag.String("secret-key", "fake-secret-key", "secret key clients must sign with")
+	region := flag.String("region", "us-east-1", "region clients must sign for")
+	maxSize := flag.Int64("max-size", 64<<20, "largest object accepted, in bytes")
+	flag.Parse()
+
+	http.Handle("/", s3fake.New(*accessKey, *secretKey, *region, *maxSize))
+
+	log.Printf("Fake S3 listening on http://%s (access key %q, region %q)", *addr, *accessKey, *region)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,24 @@
+// Command smsfake is a local stand-in for an SMS gateway. It accepts the
+// JSON messages sent by the http SMS provider, prints them, and keeps the
+// most recent ones available at GET /messages so codes can be read back
+// during development. Point SMS_HTTP_URL at http://localhost:4010/messages.
+package main
+
+import (
+	"aspire-auth/internal/sms/smsfake"
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:4010", "address to listen on")
+	apiKey := flag.String("api-key", "", "bearer key required on POST, if set")
+	keep := flag.Int("keep", 50, "number of messages kept for GET /messages")
+	flag.Parse()
+
+	http.Handle("/messages", smsfake.New(*apiKey, *keep))
+
+	log.Printf("Fake SMS gateway listening on http://%s/messages", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
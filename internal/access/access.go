@@ -0,0 +1,70 @@
+// Package access resolves what an account may do with a service. A service
+// without an organization is managed by its owner alone; one that belongs to
+// an organization is managed by the organization's members, each according
+// to their role.
+package access
+
+import (
+	"aspire-auth/internal/models"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrganizationRole returns the role accountID holds in the organization, or
+// the empty role when it is not a member.
+func OrganizationRole(db *gorm.DB, organizationID, accountID uuid.UUID) (models.OrganizationRole, error) {
+	var member models.OrganizationMember
+	err := db.Select("role").Where("organization_id = ? AND account_id = ?", organizationID, accountID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// ServiceRole returns the role accountID holds on service: OWNER for the
+// owner of a personal service, its organization role otherwise, and the
+// empty role when it has no access at all.
+func ServiceRole(db *gorm.DB, service *models.Service, accountID uuid.UUID) (models.OrganizationRole, error) {
+	if service.OrganizationID == nil {
+		if service.OwnerID == accountID {
+			return models.OrganizationOwner, nil
+		}
+		return "", nil
+	}
+	return OrganizationRole(db, *service.OrganizationID, accountID)
+}
+
+// ManagedServices narrows a services query to those accountID can manage in
+// any role.
+func ManagedServices(db *gorm.DB, accountID uuid.UUID) *gorm.DB {
+	return db.Where("(services.organization_id IS NULL AND services.owner_id = ?) OR services.organization_id IN (?)",
+		accountID,
+		db.Session(&gorm.Session{NewDB: true}).Model(&models.OrganizationMember{}).Select("organization_id").Where("account_id = ?", accountID))
+}
+
+// Successor picks the member to take over from leaving: the highest-ranked
+// remaining member, the longest-standing one among equals. It returns nil
+// when leaving is the only member.
+func Successor(db *gorm.DB, organizationID, leaving uuid.UUID) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	err := db.Where("organization_id = ? AND account_id <> ?", organizationID, leaving).
+		Order(gorm.Expr("CASE role WHEN ? THEN 0 WHEN ? THEN 1 WHEN ? THEN 2 ELSE 3 END",
+			models.OrganizationOwner, models.OrganizationAdmin, models.OrganizationDeveloper)).
+		Order("created_at").
+		First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// CanOwnServices reports whether account may be handed a service.
+func CanOwnServices(account *models.Account) bool {
+	return account.IsVerified && account.DisabledAt == nil && account.DeletionScheduledAt == nil
+}
@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"aspire-auth/internal/models"
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Event types
+const (
+	EventLogin             = "auth.login"
+	EventServiceLogin      = "service.login"
+	EventServiceSignup     = "service.signup"
+	EventAccountSignup     = "account.signup"
+	EventAccountVerify     = "account.verify"
+	EventAccountResendCode = "account.resend_code"
+
+	EventPasswordChange       = "account.password_change"
+	EventPasswordResetRequest = "account.password_reset_request"
+	EventPasswordReset        = "account.password_reset"
+
+	EventEmailChangeRequest = "account.email_change_request"
+	EventEmailChange        = "account.email_change"
+	EventEmailChangeRevert  = "account.email_change_revert"
+
+	EventMagicLinkRequest        = "auth.magic_link_request"
+	EventMagicLinkLogin          = "auth.magic_link_login"
+	EventServiceMagicLinkRequest = "service.magic_link_request"
+	EventServiceMagicLinkLogin   = "service.magic_link_login"
+
+	EventPhoneChange       = "account.phone_change"
+	EventPhoneVerify       = "account.phone_verify"
+	EventMFASettingsChange = "account.mfa_settings_change"
+	EventAvatarChange      = "account.avatar_change"
+	EventMFAChallenge      = "auth.mfa_challenge"
+	EventMFAVerify         = "auth.mfa_verify"
+
+	EventAccountDeletionRequest = "account.deletion_request"
+	EventAccountDeletionCancel  = "account.deletion_cancel"
+	EventAccountPurge           = "account.purge"
+
+	EventDataExportRequest  = "account.data_export_request"
+	EventDataExportDownload = "account.data_export_download"
+
+	EventTokenRefresh        = "auth.token_refresh"
+	EventServiceTokenRefresh = "service.token_refresh"
+	EventAccountUnlock       = "account.unlock"
+	EventAccountUpdate       = "account.update"
+
+	EventServiceCreate           = "service.create"
+	EventServiceUpdate           = "service.update"
+	EventServiceDelete           = "service.delete"
+	EventServiceSecretRegenerate = "service.secret_regenerate"
+	EventServiceLeave            = "service.leave"
+	EventServiceLogoChange       = "service.logo_change"
+	EventServiceAccessDenied     = "service.access_denied"
+	EventServiceOrganization     = "service.organization_change"
+	EventServiceTransferRequest  = "service.transfer_request"
+	EventServiceTransferCancel   = "service.transfer_cancel"
+	EventServiceTransfer         = "service.transfer"
+
+	EventWebhookCreate    = "service.webhook_create"
+	EventWebhookUpdate    = "service.webhook_update"
+	EventWebhookDelete    = "service.webhook_delete"
+	EventWebhookRedeliver = "service.webhook_redeliver"
+	EventWebhookDisable   = "service.webhook_disable"
+
+	EventOrganizationCreate       = "organization.create"
+	EventOrganizationUpdate       = "organization.update"
+	EventOrganizationDelete       = "organization.delete"
+	EventOrganizationAccessDenied = "organization.access_denied"
+	EventOrganizationMemberAdd    = "organization.member_add"
+	EventOrganizationMemberUpdate = "organization.member_update"
+	EventOrganizationMemberRemove = "organization.member_remove"
+
+	EventAdminAccessDenied   = "admin.access_denied"
+	EventAdminAccountVerify  = "admin.account_verify"
+	EventAdminAccountDisable = "admin.account_disable"
+	EventAdminAccountEnable  = "admin.account_enable"
+	EventAdminMFAReset       = "admin.mfa_reset"
+	EventAdminSessionsRevoke = "admin.sessions_revoke"
+	EventAdminServiceDisable = "admin.service_disable"
+	EventAdminServiceEnable  = "admin.service_enable"
+	EventAdminAuditExport    = "admin.audit_export"
+)
+
+// Event is what handlers report; the recorder turns it into a models.AuditEvent.
+type Event struct {
+	Type      string
+	Outcome   models.AuditOutcome
+	AccountID *uuid.UUID
+	ServiceID *uuid.UUID
+	// Subject identifies the target when no account is known, e.g. the submitted email
+	Subject   string
+	Reason    string
+	IPAddress string
+	UserAgent string
+	Metadata  map[string]interface{}
+}
+
+// Recorder is the single entry point for writing audit events.
+type Recorder interface {
+	Record(ctx context.Context, event Event)
+}
+
+// DBRecorder appends events to the audit_events table. Failures are logged
+// rather than returned so auditing never breaks the request it describes.
+type DBRecorder struct {
+	db *gorm.DB
+}
+
+func NewDBRecorder(db *gorm.DB) *DBRecorder {
+	return &DBRecorder{db: db}
+}
+
+func (r *DBRecorder) Record(ctx context.Context, event Event) {
+	record := models.AuditEvent{
+		EventType: event.Type,
+		Outcome:   event.Outcome,
+		AccountID: event.AccountID,
+		ServiceID: event.ServiceID,
+		Subject:   event.Subject,
+		Reason:    event.Reason,
+		IPAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+	}
+
+	if len(event.Metadata) > 0 {
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			log.Printf("Audit: could not encode metadata for %s: %v", event.Type, err)
+		} else {
+			encoded := string(metadata)
+			record.Metadata = &encoded
+		}
+	}
+
+	if err := r.db.WithContext(ctx).Create(&record).Error; err != nil {
+		log.Printf("Audit: failed to record %s (%s): %v", event.Type, event.Outcome, err)
+	}
+}
+
+// Success starts a successful event populated with the request's client details.
+func Success(c *fiber.Ctx, eventType string) Event {
+	return fromRequest(c, eventType, models.AuditSuccess)
+}
+
+// Failure starts a failed event with the detailed reason.
+func Failure(c *fiber.Ctx, eventType, reason string) Event {
+	event := fromRequest(c, eventType, models.AuditFailure)
+	event.Reason = reason
+	return event
+}
+
+func fromRequest(c *fiber.Ctx, eventType string, outcome models.AuditOutcome) Event {
+	return Event{
+		Type:      eventType,
+		Outcome:   outcome,
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
+
+// System starts a successful event raised by a background job rather than
+// a client request, so it has no client details.
+func System(eventType string) Event {
+	return Event{Type: eventType, Outcome: models.AuditSuccess}
+}
+
+// WithAccount sets the account the event is about.
+func (e Event) WithAccount(id uuid.UUID) Event {
+	e.AccountID = &id
+	return e
+}
+
+// WithService sets the service the event is about.
+func (e Event) WithService(id uuid.UUID) Event {
+	e.ServiceID = &id
+	return e
+}
+
+// WithSubject records an identifier supplied by the client, such as an email.
+func (e Event) WithSubject(subject string) Event {
+	e.Subject = subject
+	return e
+}
+
+// WithMetadata attaches extra structured details to the event.
+func (e Event) WithMetadata(metadata map[string]interface{}) Event {
+	e.Metadata = metadata
+	return e
+}
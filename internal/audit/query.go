@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"aspire-auth/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+	exportBatchSize = 500
+)
+
+// Filter narrows a query over recorded events. Zero fields match everything.
+// EventType ending in "." matches every event in that family, e.g. "service.".
+type Filter struct {
+	AccountID *uuid.UUID
+	ServiceID *uuid.UUID
+	EventType string
+	Outcome   models.AuditOutcome
+	IPAddress string
+	Since     time.Time
+	Until     time.Time
+}
+
+// Query is a filter plus the page of results wanted.
+type Query struct {
+	Filter
+	Page  int
+	Limit int
+}
+
+// ParseQuery reads a query from request parameters: type, outcome, ip,
+// since and until (RFC 3339), account_id and service_id, page and limit.
+// Callers scoping results to one account or service overwrite those fields.
+func ParseQuery(c *fiber.Ctx) (Query, error) {
+	q := Query{
+		Filter: Filter{
+			EventType: c.Query("type"),
+			IPAddress: c.Query("ip"),
+		},
+		Page:  max(c.QueryInt("page", 1), 1),
+		Limit: c.QueryInt("limit", defaultPageSize),
+	}
+	if q.Limit < 1 || q.Limit > maxPageSize {
+		q.Limit = defaultPageSize
+	}
+
+	if outcome := strings.ToUpper(c.Query("outcome")); outcome != "" {
+		q.Outcome = models.AuditOutcome(outcome)
+		if q.Outcome != models.AuditSuccess && q.Outcome != models.AuditFailure {
+			return q, errors.New("outcome must be SUCCESS or FAILURE")
+		}
+	}
+
+	var err error
+	if q.Since, err = parseTime(c.Query("since")); err != nil {
+		return q, fmt.Errorf("since %w", err)
+	}
+	if q.Until, err = parseTime(c.Query("until")); err != nil {
+		return q, fmt.Errorf("until %w", err)
+	}
+	if q.AccountID, err = parseID(c.Query("account_id")); err != nil {
+		return q, fmt.Errorf("account_id %w", err)
+	}
+	if q.ServiceID, err = parseID(c.Query("service_id")); err != nil {
+		return q, fmt.Errorf("service_id %w", err)
+	}
+	return q, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("must be an RFC 3339 time such as 2024-01-02T15:04:05Z")
+	}
+	return t, nil
+}
+
+func parseID(value string) (*uuid.UUID, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, errors.New("must be a UUID")
+	}
+	return &id, nil
+}
+
+func (f Filter) apply(db *gorm.DB) *gorm.DB {
+	if f.AccountID != nil {
+		db = db.Where("account_id = ?", *f.AccountID)
+	}
+	if f.ServiceID != nil {
+		db = db.Where("service_id = ?", *f.ServiceID)
+	}
+	if family, ok := strings.CutSuffix(f.EventType, "."); ok {
+		db = db.Where("event_type LIKE ?", strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(family)+".%")
+	} else if f.EventType != "" {
+		db = db.Where("event_type = ?", f.EventType)
+	}
+	if f.Outcome != "" {
+		db = db.Where("outcome = ?", f.Outcome)
+	}
+	if f.IPAddress != "" {
+		db = db.Where("ip_address = ?", f.IPAddress)
+	}
+	if !f.Since.IsZero() {
+		db = db.Where("created_at >= ?", f.Since)
+	}
+	if !f.Until.IsZero() {
+		db = db.Where("created_at < ?", f.Until)
+	}
+	return db
+}
+
+// Search returns one page of matching events, newest first, and how many
+// match in total.
+func Search(ctx context.Context, db *gorm.DB, q Query) ([]models.AuditEvent, int64, error) {
+	total, err := Count(ctx, db, q.Filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	events := []models.AuditEvent{}
+	err = q.Filter.apply(db.WithContext(ctx).Model(&models.AuditEvent{})).
+		Order("created_at DESC, id").
+		Offset((q.Page - 1) * q.Limit).
+		Limit(q.Limit).
+		Find(&events).Error
+	return events, total, err
+}
+
+// Count returns how many events match f.
+func Count(ctx context.Context, db *gorm.DB, f Filter) (int64, error) {
+	var total int64
+	err := f.apply(db.WithContext(ctx).Model(&models.AuditEvent{})).Count(&total).Error
+	return total, err
+}
+
+// Export calls fn with every matching event, oldest first, stopping after
+// limit events.
+func Export(ctx context.Context, db *gorm.DB, f Filter, limit int, fn func(*models.AuditEvent) error) error {
+	var last *models.AuditEvent
+	seen := 0
+	for {
+		query := f.apply(db.WithContext(ctx).Model(&models.AuditEvent{}))
+		// Keyset pagination keeps later batches as cheap as the first
+		if last != nil {
+			query = query.Where("(created_at, id) > (?, ?)", last.CreatedAt, last.ID)
+		}
+
+		var batch []models.AuditEvent
+		if err := query.Order("created_at, id").Limit(min(exportBatchSize, limit-seen)).Find(&batch).Error; err != nil {
+			return err
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		seen += len(batch)
+		if len(batch) < exportBatchSize || seen >= limit {
+			return nil
+		}
+		last = &batch[len(batch)-1]
+	}
+}
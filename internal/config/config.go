@@ -1,22 +1,48 @@
 package config
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Email    EmailConfig
+	Server         ServerConfig
+	Database       DatabaseConfig
+	Redis          RedisConfig
+	JWT            JWTConfig
+	Email          EmailConfig
+	SMS            SMSConfig
+	Keys           KeyProviderConfig
+	Lockout        LockoutConfig
+	OTP            OTPConfig
+	EmailChange    EmailChangeConfig
+	MagicLink      MagicLinkConfig
+	Transfer       TransferConfig
+	MFA            MFAConfig
+	Deletion       DeletionConfig
+	Export         ExportConfig
+	Webhook        WebhookConfig
+	Events         EventsConfig
+	Avatar         AvatarConfig
+	Logo           LogoConfig
+	Storage        StorageConfig
+	Password       PasswordConfig
+	PasswordPolicy PasswordPolicyConfig
+	RateLimit      RateLimitConfig
+	Security       SecurityConfig
 }
 
 type ServerConfig struct {
 	Port         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	// PublicURL is the externally reachable base URL used in emailed links
+	PublicURL string
+	// BodyLimit is the largest request body accepted, in bytes
+	BodyLimit int
 }
 
 type DatabaseConfig struct {
@@ -29,19 +55,18 @@ type RedisConfig struct {
 	DB       int
 }
 
+// Signing and encryption secrets are not part of Config; JWTHelpers resolves
+// them through the configured key provider (see KeyProviderConfig).
 type JWTAccountConfig struct {
-	AccessTokenSecret  string
-	RefreshTokenSecret string
-	AccessExpiry       time.Duration
-	RefreshExpiry      time.Duration
+	AccessExpiry  time.Duration
+	RefreshExpiry time.Duration
 }
 
 type JWTServiceConfig struct {
-	AccessTokenSecret    string
-	RefreshTokenSecret   string
-	AccessExpiry         time.Duration
-	RefreshExpiry        time.Duration
-	ServiceEncryptSecret string
+	AccessExpiry  time.Duration
+	RefreshExpiry time.Duration
+	// ServiceEncryptKeyVersion selects which master key encrypts new values, 0 means the latest
+	ServiceEncryptKeyVersion int
 }
 
 type JWTConfig struct {
@@ -49,20 +74,285 @@ type JWTConfig struct {
 	Service JWTServiceConfig
 }
 
+// EmailConfig selects how mail is delivered. Provider is "smtp" (default),
+// "file" (write .eml files to FileDir, or log them when FileDir is empty) or
+// "http" (POST JSON to HTTPURL).
 type EmailConfig struct {
+	Provider string
 	From     string
 	Username string
 	Password string
 	Host     string
 	Port     int
+	// InsecureSkipVerify disables SMTP certificate checks; only for local relays
+	InsecureSkipVerify bool
+
+	FileDir    string
+	HTTPURL    string
+	HTTPAPIKey string
+	Timeout    time.Duration
+
+	// Outbox sender: pending messages are polled every PollInterval and
+	// retried with exponential backoff from RetryBaseDelay up to MaxAttempts.
+	// Sent and failed messages are kept for Retention.
+	PollInterval   time.Duration
+	BatchSize      int
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	Retention      time.Duration
+
+	// Templates are embedded in the binary; files in TemplateDir override
+	// them by name. Brand* apply to emails not sent on behalf of a service.
+	TemplateDir   string
+	DefaultLocale string
+	BrandName     string
+	BrandLogoURL  string
+	BrandColor    string
+}
+
+// SMSConfig selects how text messages are delivered. Provider is "log"
+// (default, only logs the recipient) or "http" (POST JSON to HTTPURL).
+type SMSConfig struct {
+	Provider   string
+	From       string
+	HTTPURL    string
+	HTTPAPIKey string
+	Timeout    time.Duration
+}
+
+// StorageConfig selects where uploaded files and data exports are kept.
+// Backend is "local" (default, a directory under LocalDir) or "s3" (any
+// S3-compatible bucket; path-style addressing suits MinIO and cmd/s3fake).
+type StorageConfig struct {
+	Backend           string
+	LocalDir          string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PathStyle       bool
+	Timeout           time.Duration
+}
+
+// LockoutConfig controls failed-login throttling. Failures beyond FreeAttempts
+// add an exponentially growing delay (BaseDelay, 2*BaseDelay, ... up to
+// MaxDelay); reaching the max failure count locks the account or IP for
+// LockoutDuration.
+type LockoutConfig struct {
+	Enabled            bool
+	FreeAttempts       int
+	AccountMaxFailures int
+	IPMaxFailures      int
+	BaseDelay          time.Duration
+	MaxDelay           time.Duration
+	FailureWindow      time.Duration
+	LockoutDuration    time.Duration
+	UnlockTokenTTL     time.Duration
+}
+
+// OTPConfig controls one-time codes. A code may be guessed MaxAttempts times
+// before it is invalidated, and at most MaxSends codes may be issued per
+// subject and purpose within SendWindow, at least ResendCooldown apart.
+type OTPConfig struct {
+	Length         int
+	TTL            time.Duration
+	MaxAttempts    int
+	ResendCooldown time.Duration
+	MaxSends       int
+	SendWindow     time.Duration
+}
+
+// EmailChangeConfig controls how long the previous address can undo an email
+// change using the link it is sent.
+type EmailChangeConfig struct {
+	RevertTTL time.Duration
+}
+
+// MagicLinkConfig controls the signed single-use links sent for account
+// verification and passwordless sign-in.
+type MagicLinkConfig struct {
+	TTL           time.Duration
+	SignInEnabled bool
+}
+
+// TransferConfig controls how long a nominated account has to accept the
+// ownership of a service.
+type TransferConfig struct {
+	TTL time.Duration
+}
+
+// MFAConfig controls the second step of sign-in for accounts with MFA
+// enabled. ChallengeTTL bounds how long the code may be entered after the
+// password was accepted.
+type MFAConfig struct {
+	ChallengeTTL time.Duration
+}
+
+// DeletionConfig controls account deletion. A deleted account is kept for
+// GracePeriod so the owner can cancel, then purged by a background job that
+// checks for due accounts every PurgeInterval.
+type DeletionConfig struct {
+	GracePeriod    time.Duration
+	PurgeInterval  time.Duration
+	PurgeBatchSize int
+}
+
+// ExportConfig controls personal data exports. Archives are kept in blob
+// storage, downloadable through links valid for LinkTTL, and removed after
+// Retention.
+type ExportConfig struct {
+	LinkTTL      time.Duration
+	Retention    time.Duration
+	PollInterval time.Duration
+}
+
+// WebhookConfig controls outgoing webhook deliveries. Pending deliveries are
+// polled every PollInterval; failed attempts are retried with exponential
+// backoff starting at RetryBaseDelay, up to MaxAttempts. An endpoint that has
+// not succeeded for DisableAfter is disabled. The delivery log is kept for
+// Retention.
+type WebhookConfig struct {
+	PollInterval   time.Duration
+	BatchSize      int
+	Timeout        time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	DisableAfter   time.Duration
+	Retention      time.Duration
+	// AllowInsecure accepts plain http URLs and private or loopback
+	// addresses, for local development only
+	AllowInsecure bool
+}
+
+// EventsConfig controls the event bus dispatcher. Pending events are polled
+// every PollInterval; an event whose subscribers keep failing is retried with
+// exponential backoff from RetryBaseDelay and given up after MaxAttempts.
+// Handled events are kept for Retention.
+type EventsConfig struct {
+	PollInterval   time.Duration
+	BatchSize      int
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	Retention      time.Duration
+}
+
+// AvatarConfig bounds avatar uploads. Larger images are accepted up to
+// MaxDimension and scaled down.
+type AvatarConfig struct {
+	MaxBytes     int64
+	MinDimension int
+	MaxDimension int
+}
+
+// LogoConfig bounds service logo uploads. Logos keep their aspect ratio and
+// are scaled down to fit the stored sizes.
+type LogoConfig struct {
+	MaxBytes     int64
+	MinDimension int
+	MaxDimension int
+}
+
+// PasswordConfig selects the algorithm and cost for new password hashes.
+// Stored hashes using another algorithm or weaker parameters are upgraded on
+// the next successful sign-in.
+type PasswordConfig struct {
+	// Algorithm is "argon2id" (default) or "bcrypt"
+	Algorithm string
+	// Argon2 memory in KiB, passes and lanes
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+}
+
+// PasswordPolicyConfig is enforced whenever a password is chosen: on account
+// creation, password change and password reset.
+type PasswordPolicyConfig struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// RejectPersonalInfo refuses passwords containing the username, the
+	// email local part or the account holder's name
+	RejectPersonalInfo bool
+	// Blocklist extends the built-in list of common passwords; BlocklistFile
+	// adds one entry per line
+	Blocklist     []string
+	BlocklistFile string
+	// BreachedHashesDir holds HIBP-style range files named by the first five
+	// hex characters of the SHA-1 hash (e.g. 5BAA6.txt), each containing
+	// "<35-char suffix>:<count>" lines. Empty disables the check.
+	BreachedHashesDir string
+	// BreachedMinCount is how often a password must appear in breaches to be
+	// rejected
+	BreachedMinCount int
+	// MaxBytes is the longest password the hash algorithm accepts, in bytes.
+	// It is not read from the environment; the container sets it from the
+	// configured hasher. Zero means no limit.
+	MaxBytes int
+}
+
+type RateLimitScope string
+
+const (
+	RateLimitByIP      RateLimitScope = "ip"
+	RateLimitByAccount RateLimitScope = "account"
+	RateLimitByEmail   RateLimitScope = "email"
+	RateLimitByService RateLimitScope = "service"
+)
+
+// RateLimitPolicy allows Limit requests per Window for each distinct IP,
+// account, email address or service depending on Scope.
+type RateLimitPolicy struct {
+	Scope  RateLimitScope
+	Limit  int
+	Window time.Duration
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Policies are referenced by name from the routes; each can be overridden
+	// with RATE_LIMIT_<NAME>=<limit>/<window>, e.g. RATE_LIMIT_SIGNIN_IP=20/1m
+	Policies map[string]RateLimitPolicy
+}
+
+type SecurityConfig struct {
+	// UniformAuthResponses makes credential and recovery endpoints answer
+	// identically whether or not an account exists; the real reason is only
+	// written to the audit log.
+	UniformAuthResponses bool
+}
+
+// KeyProviderConfig selects where master secrets are loaded from.
+// Backend is one of "env" (default), "file" or "vault".
+type KeyProviderConfig struct {
+	Backend string
+	Timeout time.Duration
+
+	// File keystore
+	KeystorePath           string
+	KeystorePassphraseFile string
+
+	// Vault Transit: secrets are stored as Transit ciphertexts and decrypted at startup
+	VaultAddress     string
+	VaultTokenFile   string
+	VaultNamespace   string
+	VaultMount       string
+	VaultKeyName     string
+	VaultSecretsFile string
 }
 
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		Server: ServerConfig{
 			Port:         os.Getenv("PORT_ADDRESS"),
 			ReadTimeout:  time.Second * 10,
 			WriteTimeout: time.Second * 10,
+			PublicURL:    getEnv("PUBLIC_URL", "http://localhost:4000"),
+			BodyLimit:    getEnvInt("SERVER_BODY_LIMIT", 8<<20),
 		},
 		Database: DatabaseConfig{
 			URL: os.Getenv("DB_CONNECTION_URL"),
@@ -74,26 +364,255 @@ func Load() *Config {
 		},
 		JWT: JWTConfig{
 			Account: JWTAccountConfig{
-				AccessTokenSecret:  os.Getenv("ACCOUNT_ACCESS_TOKEN_SECRET_KEY"),
-				RefreshTokenSecret: os.Getenv("ACCOUNT_REFRESH_TOKEN_SECRET_KEY"),
-				AccessExpiry:       time.Minute * 15,
-				RefreshExpiry:      time.Hour * 24 * 7,
+				AccessExpiry:  time.Minute * 15,
+				RefreshExpiry: time.Hour * 24 * 7,
 			},
 			Service: JWTServiceConfig{
-				AccessTokenSecret:    os.Getenv("SERVICE_ACCESS_TOKEN_SECRET_KEY"),
-				RefreshTokenSecret:   os.Getenv("SERVICE_REFRESH_TOKEN_SECRET_KEY"),
-				AccessExpiry:         time.Minute * 15,
-				RefreshExpiry:        time.Hour * 24 * 7,
-				ServiceEncryptSecret: os.Getenv("SERVICE_ENCRYPT_SECRET_KEY"),
+				AccessExpiry:             time.Minute * 15,
+				RefreshExpiry:            time.Hour * 24 * 7,
+				ServiceEncryptKeyVersion: getEnvInt("SERVICE_ENCRYPT_KEY_VERSION", 0),
 			},
 		},
 		Email: EmailConfig{
-			From:     os.Getenv("EMAIL_FROM"),
-			Username: os.Getenv("EMAIL_USERNAME"),
-			Password: os.Getenv("EMAIL_PASSWORD"),
-			Host:     os.Getenv("SMTP_HOST"),
-			Port:     587,
+			Provider:           getEnv("EMAIL_PROVIDER", "smtp"),
+			From:               os.Getenv("EMAIL_FROM"),
+			Username:           os.Getenv("EMAIL_USERNAME"),
+			Password:           os.Getenv("EMAIL_PASSWORD"),
+			Host:               os.Getenv("SMTP_HOST"),
+			Port:               getEnvInt("SMTP_PORT", 587),
+			InsecureSkipVerify: getEnvBool("SMTP_INSECURE_SKIP_VERIFY", false),
+			FileDir:            os.Getenv("EMAIL_FILE_DIR"),
+			HTTPURL:            os.Getenv("EMAIL_HTTP_URL"),
+			HTTPAPIKey:         os.Getenv("EMAIL_HTTP_API_KEY"),
+			Timeout:            getEnvDuration("EMAIL_TIMEOUT", time.Second*30),
+			PollInterval:       getEnvDuration("EMAIL_OUTBOX_POLL_INTERVAL", time.Second*5),
+			BatchSize:          getEnvInt("EMAIL_OUTBOX_BATCH_SIZE", 20),
+			MaxAttempts:        getEnvInt("EMAIL_OUTBOX_MAX_ATTEMPTS", 8),
+			RetryBaseDelay:     getEnvDuration("EMAIL_OUTBOX_RETRY_BASE_DELAY", time.Second*30),
+			Retention:          getEnvDuration("EMAIL_OUTBOX_RETENTION", time.Hour*24*7),
+			TemplateDir:        os.Getenv("EMAIL_TEMPLATE_DIR"),
+			DefaultLocale:      getEnv("EMAIL_DEFAULT_LOCALE", "en"),
+			BrandName:          getEnv("EMAIL_BRAND_NAME", "Aspire Auth"),
+			BrandLogoURL:       os.Getenv("EMAIL_BRAND_LOGO_URL"),
+			BrandColor:         getEnv("EMAIL_BRAND_COLOR", "#1aa19c"),
+		},
+		Keys: KeyProviderConfig{
+			Backend:                getEnv("KEY_PROVIDER", "env"),
+			Timeout:                time.Second * 10,
+			KeystorePath:           os.Getenv("KEYSTORE_PATH"),
+			KeystorePassphraseFile: os.Getenv("KEYSTORE_PASSPHRASE_FILE"),
+			VaultAddress:           getEnv("VAULT_ADDR", "http://127.0.0.1:8200"),
+			VaultTokenFile:         os.Getenv("VAULT_TOKEN_FILE"),
+			VaultNamespace:         os.Getenv("VAULT_NAMESPACE"),
+			VaultMount:             getEnv("VAULT_TRANSIT_MOUNT", "transit"),
+			VaultKeyName:           getEnv("VAULT_TRANSIT_KEY", "aspire-auth"),
+			VaultSecretsFile:       os.Getenv("VAULT_SECRETS_FILE"),
+		},
+		Lockout: LockoutConfig{
+			Enabled:            getEnvBool("LOCKOUT_ENABLED", true),
+			FreeAttempts:       getEnvInt("LOCKOUT_FREE_ATTEMPTS", 3),
+			AccountMaxFailures: getEnvInt("LOCKOUT_ACCOUNT_MAX_FAILURES", 10),
+			IPMaxFailures:      getEnvInt("LOCKOUT_IP_MAX_FAILURES", 50),
+			BaseDelay:          getEnvDuration("LOCKOUT_BASE_DELAY", time.Second),
+			MaxDelay:           getEnvDuration("LOCKOUT_MAX_DELAY", time.Minute*5),
+			FailureWindow:      getEnvDuration("LOCKOUT_FAILURE_WINDOW", time.Hour),
+			LockoutDuration:    getEnvDuration("LOCKOUT_DURATION", time.Minute*30),
+			UnlockTokenTTL:     getEnvDuration("LOCKOUT_UNLOCK_TOKEN_TTL", time.Hour*24),
+		},
+		OTP: OTPConfig{
+			Length:         getEnvInt("OTP_LENGTH", 6),
+			TTL:            getEnvDuration("OTP_TTL", time.Minute*15),
+			MaxAttempts:    getEnvInt("OTP_MAX_ATTEMPTS", 5),
+			ResendCooldown: getEnvDuration("OTP_RESEND_COOLDOWN", time.Minute),
+			MaxSends:       getEnvInt("OTP_MAX_SENDS", 5),
+			SendWindow:     getEnvDuration("OTP_SEND_WINDOW", time.Hour),
+		},
+		SMS: SMSConfig{
+			Provider:   getEnv("SMS_PROVIDER", "log"),
+			From:       os.Getenv("SMS_FROM"),
+			HTTPURL:    os.Getenv("SMS_HTTP_URL"),
+			HTTPAPIKey: os.Getenv("SMS_HTTP_API_KEY"),
+			Timeout:    getEnvDuration("SMS_TIMEOUT", time.Second*10),
+		},
+		MFA: MFAConfig{
+			ChallengeTTL: getEnvDuration("MFA_CHALLENGE_TTL", time.Minute*10),
+		},
+		Deletion: DeletionConfig{
+			GracePeriod:    getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", time.Hour*24*30),
+			PurgeInterval:  getEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour),
+			PurgeBatchSize: getEnvInt("ACCOUNT_PURGE_BATCH_SIZE", 50),
+		},
+		Storage: StorageConfig{
+			Backend:           getEnv("STORAGE_BACKEND", "local"),
+			LocalDir:          getEnv("STORAGE_LOCAL_DIR", "images"),
+			S3Endpoint:        os.Getenv("S3_ENDPOINT"),
+			S3Region:          getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:          os.Getenv("S3_BUCKET"),
+			S3AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			S3SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			S3PathStyle:       getEnvBool("S3_PATH_STYLE", true),
+			Timeout:           getEnvDuration("STORAGE_TIMEOUT", time.Second*30),
+		},
+		Avatar: AvatarConfig{
+			MaxBytes:     int64(getEnvInt("AVATAR_MAX_BYTES", 5<<20)),
+			MinDimension: getEnvInt("AVATAR_MIN_DIMENSION", 32),
+			MaxDimension: getEnvInt("AVATAR_MAX_DIMENSION", 4096),
+		},
+		Logo: LogoConfig{
+			MaxBytes:     int64(getEnvInt("LOGO_MAX_BYTES", 2<<20)),
+			MinDimension: getEnvInt("LOGO_MIN_DIMENSION", 16),
+			MaxDimension: getEnvInt("LOGO_MAX_DIMENSION", 4096),
+		},
+		Export: ExportConfig{
+			LinkTTL:      getEnvDuration("EXPORT_LINK_TTL", time.Minute*15),
+			Retention:    getEnvDuration("EXPORT_RETENTION", time.Hour*24),
+			PollInterval: getEnvDuration("EXPORT_POLL_INTERVAL", time.Second*15),
+		},
+		Webhook: WebhookConfig{
+			PollInterval:   getEnvDuration("WEBHOOK_POLL_INTERVAL", time.Second*5),
+			BatchSize:      getEnvInt("WEBHOOK_BATCH_SIZE", 20),
+			Timeout:        getEnvDuration("WEBHOOK_TIMEOUT", time.Second*10),
+			MaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			RetryBaseDelay: getEnvDuration("WEBHOOK_RETRY_BASE_DELAY", time.Second*30),
+			DisableAfter:   getEnvDuration("WEBHOOK_DISABLE_AFTER", time.Hour*72),
+			Retention:      getEnvDuration("WEBHOOK_RETENTION", time.Hour*24*30),
+			AllowInsecure:  getEnvBool("WEBHOOK_ALLOW_INSECURE", false),
+		},
+		Events: EventsConfig{
+			PollInterval:   getEnvDuration("EVENTS_POLL_INTERVAL", time.Second*2),
+			BatchSize:      getEnvInt("EVENTS_BATCH_SIZE", 50),
+			MaxAttempts:    getEnvInt("EVENTS_MAX_ATTEMPTS", 10),
+			RetryBaseDelay: getEnvDuration("EVENTS_RETRY_BASE_DELAY", time.Second*10),
+			Retention:      getEnvDuration("EVENTS_RETENTION", time.Hour*24*7),
+		},
+		EmailChange: EmailChangeConfig{
+			RevertTTL: getEnvDuration("EMAIL_CHANGE_REVERT_TTL", time.Hour*24*7),
+		},
+		MagicLink: MagicLinkConfig{
+			TTL:           getEnvDuration("MAGIC_LINK_TTL", time.Minute*15),
+			SignInEnabled: getEnvBool("MAGIC_LINK_SIGNIN_ENABLED", true),
+		},
+		Transfer: TransferConfig{
+			TTL: getEnvDuration("SERVICE_TRANSFER_TTL", time.Hour*72),
+		},
+		Password: PasswordConfig{
+			Algorithm:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+			Argon2Memory:      uint32(getEnvInt("PASSWORD_ARGON2_MEMORY", 64*1024)),
+			Argon2Iterations:  uint32(getEnvInt("PASSWORD_ARGON2_ITERATIONS", 3)),
+			Argon2Parallelism: uint8(getEnvInt("PASSWORD_ARGON2_PARALLELISM", 2)),
+			BcryptCost:        getEnvInt("PASSWORD_BCRYPT_COST", 12),
+		},
+		PasswordPolicy: PasswordPolicyConfig{
+			MinLength:          getEnvInt("PASSWORD_MIN_LENGTH", 8),
+			MaxLength:          getEnvInt("PASSWORD_MAX_LENGTH", 128),
+			RequireUpper:       getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+			RequireLower:       getEnvBool("PASSWORD_REQUIRE_LOWER", false),
+			RequireDigit:       getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+			RequireSymbol:      getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+			RejectPersonalInfo: getEnvBool("PASSWORD_REJECT_PERSONAL_INFO", true),
+			Blocklist:          getEnvList("PASSWORD_BLOCKLIST"),
+			BlocklistFile:      os.Getenv("PASSWORD_BLOCKLIST_FILE"),
+			BreachedHashesDir:  os.Getenv("PASSWORD_BREACHED_HASHES_DIR"),
+			BreachedMinCount:   getEnvInt("PASSWORD_BREACHED_MIN_COUNT", 1),
+		},
+		Security: SecurityConfig{
+			UniformAuthResponses: getEnvBool("UNIFORM_AUTH_RESPONSES", true),
 		},
+		RateLimit: RateLimitConfig{
+			Enabled: getEnvBool("RATE_LIMIT_ENABLED", true),
+			Policies: loadRateLimitPolicies(map[string]RateLimitPolicy{
+				"global_ip":             {Scope: RateLimitByIP, Limit: 300, Window: time.Minute},
+				"signin_ip":             {Scope: RateLimitByIP, Limit: 20, Window: time.Minute},
+				"signup_ip":             {Scope: RateLimitByIP, Limit: 5, Window: time.Hour},
+				"verify_ip":             {Scope: RateLimitByIP, Limit: 30, Window: time.Minute * 10},
+				"verify_account":        {Scope: RateLimitByAccount, Limit: 10, Window: time.Minute * 10},
+				"resend_otp_ip":         {Scope: RateLimitByIP, Limit: 10, Window: time.Hour},
+				"resend_otp_account":    {Scope: RateLimitByAccount, Limit: 5, Window: time.Hour},
+				"refresh_ip":            {Scope: RateLimitByIP, Limit: 60, Window: time.Minute},
+				"forgot_password_ip":    {Scope: RateLimitByIP, Limit: 10, Window: time.Hour},
+				"forgot_password_email": {Scope: RateLimitByEmail, Limit: 5, Window: time.Hour},
+				"reset_password_ip":     {Scope: RateLimitByIP, Limit: 30, Window: time.Minute * 10},
+				"magic_link_ip":         {Scope: RateLimitByIP, Limit: 10, Window: time.Hour},
+				"magic_link_email":      {Scope: RateLimitByEmail, Limit: 5, Window: time.Hour},
+				"service_login_ip":      {Scope: RateLimitByIP, Limit: 20, Window: time.Minute},
+				"service_login_service": {Scope: RateLimitByService, Limit: 600, Window: time.Minute},
+				"account_api_account":   {Scope: RateLimitByAccount, Limit: 120, Window: time.Minute},
+				"data_export_account":   {Scope: RateLimitByAccount, Limit: 3, Window: time.Hour * 24},
+				"download_ip":           {Scope: RateLimitByIP, Limit: 30, Window: time.Minute * 10},
+				"service_api_service":   {Scope: RateLimitByService, Limit: 1200, Window: time.Minute},
+			}),
+		},
+	}
+
+	return cfg
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
 	}
+	return value
+}
 
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvDuration parses Go duration strings such as "30s" or "15m"
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// loadRateLimitPolicies applies RATE_LIMIT_<NAME> overrides on top of the defaults
+func loadRateLimitPolicies(defaults map[string]RateLimitPolicy) map[string]RateLimitPolicy {
+	for name, policy := range defaults {
+		raw := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name))
+		if raw == "" {
+			continue
+		}
+
+		parts := strings.SplitN(raw, "/", 2)
+		limit, err := strconv.Atoi(parts[0])
+		if len(parts) != 2 || err != nil {
+			log.Printf("Warning: ignoring malformed rate limit %s=%q", name, raw)
+			continue
+		}
+		window, err := time.ParseDuration(parts[1])
+		if err != nil {
+			log.Printf("Warning: ignoring malformed rate limit %s=%q", name, raw)
+			continue
+		}
+
+		policy.Limit = limit
+		policy.Window = window
+		defaults[name] = policy
+	}
+	return defaults
 }
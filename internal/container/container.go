@@ -1,8 +1,26 @@
 package container
 
 import (
+	"aspire-auth/internal/audit"
 	"aspire-auth/internal/config"
+	"aspire-auth/internal/deletion"
+	"aspire-auth/internal/emailchange"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/events"
+	"aspire-auth/internal/export"
 	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/lockout"
+	"aspire-auth/internal/magiclink"
+	"aspire-auth/internal/mailer"
+	"aspire-auth/internal/mfa"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/password"
+	"aspire-auth/internal/ratelimit"
+	"aspire-auth/internal/sms"
+	"aspire-auth/internal/storage"
+	"aspire-auth/internal/transfer"
+	"aspire-auth/internal/webhook"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/redis/go-redis/v9"
@@ -15,15 +33,123 @@ type Container struct {
 	Redis  *redis.Client
 	App    *fiber.App
 	JWT    *helpers.JWTHelpers
+
+	Storage   storage.Store
+	Outbox    *mailer.Outbox
+	Events    *events.Bus
+	Purger    *deletion.Purger
+	Exports   *export.Service
+	Webhooks  *webhook.Dispatcher
+	Templates *emailtemplate.Renderer
+	Lockout   *lockout.Guard
+	OTP       *otp.Service
+	SMS       sms.Sender
+	MFA       *mfa.Service
+
+	EmailChange *emailchange.Store
+	MagicLinks  *magiclink.Service
+	Transfers   *transfer.Store
+
+	Passwords      *password.Hasher
+	PasswordPolicy *password.Policy
+
+	RateLimiter ratelimit.Limiter
+	Audit       audit.Recorder
 }
 
-func NewContainer(cfg *config.Config, db *gorm.DB, redis *redis.Client, app *fiber.App, jwt *helpers.JWTHelpers) *Container {
+func NewContainer(cfg *config.Config, db *gorm.DB, redis *redis.Client, app *fiber.App, jwt *helpers.JWTHelpers, mail mailer.Mailer, templates *emailtemplate.Renderer, text sms.Sender, store storage.Store) *Container {
+	outbox := mailer.NewOutbox(db, mail, jwt, cfg.Email)
+	recorder := audit.NewDBRecorder(db)
+	// Lockout notices go out without a request, so they use the defaults
+	lockoutMail := helpers.Mail{Queue: outbox, Templates: templates, Locale: cfg.Email.DefaultLocale}
+
+	codes := otp.NewService(redis, cfg.OTP)
+	passwords := password.NewHasher(cfg.Password)
+	policy := cfg.PasswordPolicy
+	policy.MaxBytes = passwords.MaxBytes()
 
-	return &Container{
+	c := &Container{
 		Config: cfg,
 		DB:     db,
 		Redis:  redis,
 		App:    app,
 		JWT:    jwt,
+
+		Storage:   store,
+		Outbox:    outbox,
+		Events:    events.NewBus(db, cfg.Events),
+		Purger:    deletion.NewPurger(db, store, cfg.Deletion),
+		Exports:   export.NewService(db, jwt, store, cfg.Export, cfg.Server.PublicURL),
+		Webhooks:  webhook.NewDispatcher(db, jwt, recorder, cfg.Webhook),
+		Templates: templates,
+		Lockout:   lockout.NewGuard(redis, cfg, lockoutMail),
+		OTP:       codes,
+		SMS:       text,
+		MFA:       mfa.NewService(mfa.NewStore(redis, cfg.MFA), codes, db),
+
+		EmailChange: emailchange.NewStore(redis, cfg),
+		MagicLinks:  magiclink.NewService(redis, jwt, cfg.MagicLink),
+		Transfers:   transfer.NewStore(redis, cfg.Transfer),
+
+		Passwords:      passwords,
+		PasswordPolicy: password.NewPolicy(policy),
+
+		RateLimiter: ratelimit.NewFallbackLimiter(
+			ratelimit.NewRedisLimiter(redis),
+			ratelimit.NewMemoryLimiter(),
+		),
+		Audit: recorder,
+	}
+	c.registerSubscribers()
+	return c
+}
+
+// MailFor addresses email to account in the language it prefers, falling back
+// to the request's Accept-Language. account may be nil when there is none
+// yet. Pass an empty brand for email sent as the operator.
+func (c *Container) MailFor(ctx *fiber.Ctx, account *models.Account, brand emailtemplate.Brand) helpers.Mail {
+	var preferred *string
+	if account != nil {
+		preferred = account.Locale
+	}
+	return helpers.Mail{
+		Queue:     c.Outbox,
+		Templates: c.Templates,
+		Locale:    c.Templates.ResolveLocale(preferred, ctx.Get(fiber.HeaderAcceptLanguage)),
+		Brand:     brand,
+	}
+}
+
+// CodeSender picks the channel a code for account goes out on: requested if
+// given, otherwise the account's preference. SMS needs a phone number, and it
+// must already be verified unless the code is what verifies the account.
+func (c *Container) CodeSender(mail helpers.Mail, account *models.Account, requested string) (otp.OTPSender, error) {
+	channel, err := otp.ParseChannel(requested, otp.Channel(account.OTPChannel))
+	if err != nil {
+		return nil, err
+	}
+
+	switch channel {
+	case otp.ChannelSMS:
+		if account.PhoneNumber == nil || (account.IsVerified && !account.PhoneVerified) {
+			return nil, otp.ErrNoPhoneNumber
+		}
+		return &helpers.SMSOTPSender{SMS: c.SMS, Mail: mail, Config: c.Config}, nil
+	default:
+		return &helpers.EmailOTPSender{Mail: mail, Config: c.Config}, nil
+	}
+}
+
+// CodeDelivery addresses code to every channel account can receive it on.
+func (c *Container) CodeDelivery(account *models.Account, purpose otp.Purpose, code string) otp.Delivery {
+	delivery := otp.Delivery{
+		Purpose: purpose,
+		Code:    code,
+		TTL:     c.Config.OTP.TTL,
+		Email:   account.Email,
+	}
+	if account.PhoneNumber != nil {
+		delivery.Phone = *account.PhoneNumber
 	}
+	return delivery
 }
@@ -0,0 +1,101 @@
+package container
+
+import (
+	"aspire-auth/internal/events"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/magiclink"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/utils"
+	"aspire-auth/internal/webhook"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// registerSubscribers connects the event bus to everything that reacts to
+// domain events.
+func (c *Container) registerSubscribers() {
+	c.Events.Subscribe("audit", events.AuditSubscriber(c.Audit))
+	c.Events.Subscribe("webhooks", webhook.Subscriber(c.DB), webhook.SubscribedEvents...)
+	c.Events.Subscribe("verification", c.sendVerificationCode, events.AccountCreated)
+	c.Events.Subscribe("cache", c.clearAccountState, events.AccountDeleted)
+}
+
+// VerificationLink returns the one-click link for the verification email, or
+// "" when none could be issued so the email still goes out with the code.
+func (c *Container) VerificationLink(ctx context.Context, accountID uuid.UUID) string {
+	token, err := c.MagicLinks.Issue(ctx, magiclink.PurposeVerify, accountID, nil)
+	if err != nil {
+		log.Printf("Error issuing verification link: %v", err)
+		return ""
+	}
+	return fmt.Sprintf("%s/verify/%s", strings.TrimRight(c.Config.Server.PublicURL, "/"), token)
+}
+
+// sendVerificationCode sends a new account its first verification code over
+// its chosen channel. A retry sends a fresh code, replacing the previous one.
+func (c *Container) sendVerificationCode(ctx context.Context, event *events.Envelope) error {
+	var payload events.AccountCreatedPayload
+	if err := event.Decode(&payload); err != nil {
+		return err
+	}
+
+	var account models.Account
+	err := c.DB.WithContext(ctx).Where("id = ?", payload.AccountID).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// Verified through an earlier attempt, or by a resent code
+	if account.IsVerified {
+		return nil
+	}
+
+	mail := helpers.Mail{Queue: c.Outbox, Templates: c.Templates, Locale: payload.Locale}
+	sender, err := c.CodeSender(mail, &account, "")
+	if err != nil {
+		// Retrying cannot help; the account holder can still use /resend-otp
+		log.Printf("Verification code for %s not sent: %v", account.ID, err)
+		return nil
+	}
+
+	code, err := c.OTP.Generate(ctx, otp.PurposeAccountVerification, account.ID.String())
+	if err != nil {
+		return err
+	}
+	if err := c.OTP.RecordChannel(ctx, otp.PurposeAccountVerification, account.ID.String(), sender.Channel()); err != nil {
+		log.Printf("OTP error: %v", err)
+	}
+
+	delivery := c.CodeDelivery(&account, otp.PurposeAccountVerification, code)
+	delivery.Link = c.VerificationLink(ctx, account.ID)
+	return sender.Send(ctx, delivery)
+}
+
+// clearAccountState drops the short-lived Redis state of a purged account:
+// outstanding codes and a pending email change.
+func (c *Container) clearAccountState(ctx context.Context, event *events.Envelope) error {
+	var payload events.AccountDeletedPayload
+	if err := event.Decode(&payload); err != nil {
+		return err
+	}
+
+	subject := payload.AccountID.String()
+	purposes := []otp.Purpose{otp.PurposeAccountVerification, otp.PurposeEmailChange, otp.PurposePhoneVerification, otp.PurposeMFA}
+	for _, purpose := range purposes {
+		if err := c.OTP.Invalidate(ctx, purpose, subject); err != nil {
+			return err
+		}
+	}
+	if err := c.OTP.Invalidate(ctx, otp.PurposePasswordReset, utils.NormalizeEmail(payload.Email)); err != nil {
+		return err
+	}
+	return c.EmailChange.ClearPending(ctx, payload.AccountID)
+}
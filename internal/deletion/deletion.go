@@ -0,0 +1,247 @@
+package deletion
+
+import (
+	"aspire-auth/internal/access"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/events"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/storage"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Purger permanently removes accounts whose deletion grace period is over,
+// together with everything that only makes sense while they exist.
+type Purger struct {
+	db     *gorm.DB
+	store  storage.Store
+	config config.DeletionConfig
+}
+
+func NewPurger(db *gorm.DB, store storage.Store, cfg config.DeletionConfig) *Purger {
+	return &Purger{db: db, store: store, config: cfg}
+}
+
+// Run purges due accounts until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	log.Printf("Account purge job started, checking every %s", p.config.PurgeInterval)
+
+	ticker := time.NewTicker(p.config.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.PurgeDue(ctx); err != nil {
+			log.Printf("Account purge error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeDue removes every account whose deletion is due and returns how many
+// were purged. An account that fails is logged and retried on the next run.
+func (p *Purger) PurgeDue(ctx context.Context) (int, error) {
+	purged := 0
+	for {
+		var ids []uuid.UUID
+		err := p.db.WithContext(ctx).Model(&models.Account{}).
+			Where("deletion_scheduled_at <= ?", time.Now()).
+			Order("deletion_scheduled_at").
+			Limit(p.config.PurgeBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return purged, err
+		}
+
+		failed := 0
+		for _, id := range ids {
+			done, err := p.purge(ctx, id)
+			if err != nil {
+				log.Printf("Account purge: could not purge %s: %v", id, err)
+				failed++
+				continue
+			}
+			if done {
+				purged++
+			}
+		}
+
+		// A batch made entirely of failures would come back unchanged
+		if len(ids) < p.config.PurgeBatchSize || failed == len(ids) {
+			return purged, nil
+		}
+	}
+}
+
+// purge deletes one account. It reports false when the account was skipped
+// because its deletion was cancelled or another instance is purging it.
+func (p *Purger) purge(ctx context.Context, id uuid.UUID) (bool, error) {
+	var account models.Account
+	var handedOver, deleted int64
+	var archives, logos []string
+
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Re-check under lock so a cancellation that wins the race is honoured
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ? AND deletion_scheduled_at <= ?", id, time.Now()).
+			First(&account).Error
+		if err != nil {
+			return err
+		}
+
+		// Organization services stay with their organization; what is left
+		// below are the account's personal services
+		if handedOver, err = handOverOrganizations(tx, account.ID); err != nil {
+			return err
+		}
+
+		// Transfers offered with the deletion have moved the services that
+		// were accepted; the rest go with the account
+		owned := tx.Model(&models.Service{}).Select("id").Where("owner_id = ?", account.ID)
+		if err := tx.Where("service_id IN (?)", owned).Delete(&models.ServicesUser{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("service_id IN (?)", owned).Delete(&models.ServiceRefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Service{}).Where("owner_id = ? AND service_logo IS NOT NULL", account.ID).Pluck("service_logo", &logos).Error; err != nil {
+			return err
+		}
+		result := tx.Where("owner_id = ?", account.ID).Delete(&models.Service{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		// Subscribers run after the rows are gone, so the event carries what they need
+		var memberOf []uuid.UUID
+		if err := tx.Model(&models.ServicesUser{}).Where("user_id = ?", account.ID).Distinct().Pluck("service_id", &memberOf).Error; err != nil {
+			return err
+		}
+		purged := audit.System(audit.EventAccountPurge).WithAccount(account.ID).WithSubject(account.Email).WithMetadata(map[string]interface{}{
+			"services_handed_over": handedOver,
+			"services_deleted":     deleted,
+		})
+		err = events.Publish(ctx, tx, events.Event{
+			Type:    events.AccountDeleted,
+			Payload: events.AccountDeletedPayload{AccountID: account.ID, Email: account.Email, ServiceIDs: memberOf},
+			Audit:   &purged,
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.ID).Delete(&models.ServicesUser{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.ID).Delete(&models.AccountRefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.ID).Delete(&models.ServiceRefreshToken{}).Error; err != nil {
+			return err
+		}
+		// Export rows cascade with the account; their archives are removed below
+		if err := tx.Model(&models.AccountExport{}).Where("account_id = ? AND storage_key IS NOT NULL", account.ID).Pluck("storage_key", &archives).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Account{}, "id = ?", account.ID).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// Files are removed only once the row is gone, so a rollback keeps them
+	if account.Avatar != nil && *account.Avatar != "" {
+		if err := helpers.DeleteAvatar(ctx, p.store, *account.Avatar); err != nil {
+			log.Printf("Account purge: could not delete avatar of %s: %v", account.ID, err)
+		}
+	}
+	for i := range logos {
+		if filename, hosted := helpers.HostedLogo(&logos[i]); hosted {
+			if err := helpers.DeleteLogo(ctx, p.store, filename); err != nil {
+				log.Printf("Account purge: could not delete service logo of %s: %v", account.ID, err)
+			}
+		}
+	}
+	for _, archive := range archives {
+		if err := p.store.Delete(ctx, archive); err != nil {
+			log.Printf("Account purge: could not delete data export of %s: %v", account.ID, err)
+		}
+	}
+
+	return true, nil
+}
+
+// handOverOrganizations keeps the organizations of an account being purged
+// running without it. In each one its services go to the successor picked
+// by access.Successor, who is made an owner if the account was the last one.
+// An organization with no other member is dissolved and its services revert
+// to personal services of their owners.
+func handOverOrganizations(tx *gorm.DB, accountID uuid.UUID) (int64, error) {
+	var memberships []models.OrganizationMember
+	if err := tx.Where("account_id = ?", accountID).Find(&memberships).Error; err != nil {
+		return 0, err
+	}
+	roles := make(map[uuid.UUID]models.OrganizationRole, len(memberships))
+	for _, membership := range memberships {
+		roles[membership.OrganizationID] = membership.Role
+	}
+
+	// A former member may still be recorded as the owner of services
+	var organizationIDs []uuid.UUID
+	err := tx.Model(&models.Service{}).Where("owner_id = ? AND organization_id IS NOT NULL", accountID).
+		Distinct().Pluck("organization_id", &organizationIDs).Error
+	if err != nil {
+		return 0, err
+	}
+	for id := range roles {
+		if !slices.Contains(organizationIDs, id) {
+			organizationIDs = append(organizationIDs, id)
+		}
+	}
+
+	var handedOver int64
+	for _, organizationID := range organizationIDs {
+		successor, err := access.Successor(tx, organizationID, accountID)
+		if err != nil {
+			return 0, err
+		}
+		if successor == nil {
+			if err := tx.Model(&models.Service{}).Where("organization_id = ?", organizationID).Update("organization_id", nil).Error; err != nil {
+				return 0, err
+			}
+			if err := tx.Delete(&models.Organization{}, "id = ?", organizationID).Error; err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		// The successor is the highest-ranked member left, so unless they
+		// are an owner already the account was the only one
+		if roles[organizationID] == models.OrganizationOwner && successor.Role != models.OrganizationOwner {
+			if err := tx.Model(successor).Update("role", models.OrganizationOwner).Error; err != nil {
+				return 0, err
+			}
+		}
+		result := tx.Model(&models.Service{}).Where("owner_id = ? AND organization_id = ?", accountID, organizationID).Update("owner_id", successor.AccountID)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		handedOver += result.RowsAffected
+	}
+	return handedOver, nil
+}
@@ -0,0 +1,184 @@
+package emailchange
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNoPendingChange    = errors.New("no pending email change")
+	ErrInvalidRevertToken = errors.New("invalid or expired revert token")
+)
+
+// Change records a confirmed email change so the previous address can undo it.
+type Change struct {
+	AccountID uuid.UUID `json:"account_id"`
+	OldEmail  string    `json:"old_email"`
+	NewEmail  string    `json:"new_email"`
+}
+
+// Store keeps the requested address until the confirmation code is entered,
+// and the revert tokens sent to previous addresses. Only hashes of the
+// tokens are kept.
+//
+// Each account's revert tokens form a chain in the order its changes were
+// made. Reverting a change also reverts every later one, so it invalidates
+// the token of that change and of all later ones: an address the account
+// moved through afterwards can no longer put itself back. Tokens of earlier
+// changes, sent to addresses the account had before, stay valid.
+type Store struct {
+	redis  *redis.Client
+	config *config.Config
+}
+
+func NewStore(redis *redis.Client, cfg *config.Config) *Store {
+	return &Store{redis: redis, config: cfg}
+}
+
+func pendingKey(accountID uuid.UUID) string {
+	return fmt.Sprintf("email_change:pending:%s", accountID)
+}
+
+const revertKeyPrefix = "email_change:revert:"
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func chainKey(accountID uuid.UUID) string {
+	return fmt.Sprintf("email_change:reverts:%s", accountID)
+}
+
+// SetPending remembers newEmail for accountID for as long as the
+// confirmation code sent to it is valid, replacing any earlier request.
+func (s *Store) SetPending(ctx context.Context, accountID uuid.UUID, newEmail string) error {
+	return s.redis.Set(ctx, pendingKey(accountID), newEmail, s.config.OTP.TTL).Err()
+}
+
+func (s *Store) Pending(ctx context.Context, accountID uuid.UUID) (string, error) {
+	email, err := s.redis.Get(ctx, pendingKey(accountID)).Result()
+	if err == redis.Nil {
+		return "", ErrNoPendingChange
+	}
+	return email, err
+}
+
+func (s *Store) ClearPending(ctx context.Context, accountID uuid.UUID) error {
+	return s.redis.Del(ctx, pendingKey(accountID)).Err()
+}
+
+// CreateRevertToken returns a single-use token that undoes change, and
+// appends it to the account's chain.
+func (s *Store) CreateRevertToken(ctx context.Context, change Change) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	hash := hashToken(token)
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return "", err
+	}
+	ttl := s.config.EmailChange.RevertTTL
+	pipe := s.redis.TxPipeline()
+	pipe.Set(ctx, revertKeyPrefix+hash, payload, ttl)
+	pipe.RPush(ctx, chainKey(change.AccountID), hash)
+	pipe.Expire(ctx, chainKey(change.AccountID), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// LookupRevertToken returns the change a token was issued for without
+// consuming it.
+func (s *Store) LookupRevertToken(ctx context.Context, token string) (*Change, error) {
+	payload, err := s.redis.Get(ctx, revertKeyPrefix+hashToken(token)).Result()
+	if err == redis.Nil {
+		return nil, ErrInvalidRevertToken
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeChange(payload)
+}
+
+// consumeScript removes a revert token together with every later token in
+// its account's chain, and returns the token's change with the latest
+// change in the chain, or nil when the token is no longer part of it.
+var consumeScript = redis.NewScript(`
+local payload = redis.call("GET", ARGV[2] .. ARGV[1])
+if not payload then
+	return false
+end
+local chain = redis.call("LRANGE", KEYS[1], 0, -1)
+local at
+for i, hash in ipairs(chain) do
+	if hash == ARGV[1] then
+		at = i
+		break
+	end
+end
+if not at then
+	return false
+end
+local latest = redis.call("GET", ARGV[2] .. chain[#chain])
+if not latest then
+	return false
+end
+for i = at, #chain do
+	redis.call("DEL", ARGV[2] .. chain[i])
+end
+if at == 1 then
+	redis.call("DEL", KEYS[1])
+else
+	redis.call("LTRIM", KEYS[1], 0, at - 2)
+end
+return {payload, latest}
+`)
+
+// ConsumeRevertToken returns the change a token was issued for and the
+// latest change made to the account since, which is the same change when
+// none was made. It invalidates the token and every later one in the chain.
+func (s *Store) ConsumeRevertToken(ctx context.Context, token string) (*Change, *Change, error) {
+	change, err := s.LookupRevertToken(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := consumeScript.Run(ctx, s.redis, []string{chainKey(change.AccountID)}, hashToken(token), revertKeyPrefix).StringSlice()
+	if err == redis.Nil {
+		return nil, nil, ErrInvalidRevertToken
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to consume revert token: %w", err)
+	}
+
+	if change, err = decodeChange(result[0]); err != nil {
+		return nil, nil, err
+	}
+	latest, err := decodeChange(result[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return change, latest, nil
+}
+
+func decodeChange(payload string) (*Change, error) {
+	var change Change
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return nil, fmt.Errorf("corrupt revert token payload: %w", err)
+	}
+	return &change, nil
+}
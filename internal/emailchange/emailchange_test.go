@@ -0,0 +1,149 @@
+package emailchange
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewStore(client, &config.Config{
+		OTP:         config.OTPConfig{TTL: 10 * time.Minute},
+		EmailChange: config.EmailChangeConfig{RevertTTL: time.Hour},
+	}), mr
+}
+
+// changeChain makes the account move through emails in order and returns
+// the revert token sent to each address it left.
+func changeChain(t *testing.T, s *Store, accountID uuid.UUID, emails ...string) []string {
+	t.Helper()
+	var tokens []string
+	for i := 1; i < len(emails); i++ {
+		token, err := s.CreateRevertToken(context.Background(), Change{AccountID: accountID, OldEmail: emails[i-1], NewEmail: emails[i]})
+		if err != nil {
+			t.Fatalf("CreateRevertToken: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func TestRevertChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		consume   []int    // indexes into the chain's tokens, in order
+		restores  []string // OldEmail restored by each, "" when rejected
+		latestNew []string // NewEmail of the latest change reported
+	}{
+		{
+			name:      "single use",
+			consume:   []int{1, 1},
+			restores:  []string{"b", ""},
+			latestNew: []string{"c", ""},
+		},
+		{
+			name:      "earliest change undoes the later ones",
+			consume:   []int{0, 1},
+			restores:  []string{"a", ""},
+			latestNew: []string{"c", ""},
+		},
+		{
+			// An address the account passed through cannot undo the
+			// original owner's revert
+			name:      "later change leaves earlier links working",
+			consume:   []int{1, 0, 1},
+			restores:  []string{"b", "a", ""},
+			latestNew: []string{"c", "b", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			ctx := context.Background()
+			accountID := uuid.New()
+			tokens := changeChain(t, s, accountID, "a", "b", "c")
+
+			for i, index := range tt.consume {
+				change, latest, err := s.ConsumeRevertToken(ctx, tokens[index])
+				if tt.restores[i] == "" {
+					if !errors.Is(err, ErrInvalidRevertToken) {
+						t.Fatalf("step %d: ConsumeRevertToken = %v, want %v", i, err, ErrInvalidRevertToken)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %d: ConsumeRevertToken: %v", i, err)
+				}
+				if change.OldEmail != tt.restores[i] || latest.NewEmail != tt.latestNew[i] {
+					t.Errorf("step %d: restores %q with latest %q, want %q with latest %q", i, change.OldEmail, latest.NewEmail, tt.restores[i], tt.latestNew[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRevertChainsAreSeparate(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	first := changeChain(t, s, uuid.New(), "a", "b")
+	second := changeChain(t, s, uuid.New(), "x", "y")
+
+	if _, _, err := s.ConsumeRevertToken(ctx, first[0]); err != nil {
+		t.Fatalf("ConsumeRevertToken: %v", err)
+	}
+	if change, _, err := s.ConsumeRevertToken(ctx, second[0]); err != nil || change.OldEmail != "x" {
+		t.Fatalf("other account: ConsumeRevertToken = %+v, %v", change, err)
+	}
+}
+
+func TestLookupRevertToken(t *testing.T) {
+	s, mr := newTestStore(t)
+	ctx := context.Background()
+	tokens := changeChain(t, s, uuid.New(), "a", "b")
+
+	for i := 0; i < 2; i++ {
+		if change, err := s.LookupRevertToken(ctx, tokens[0]); err != nil || change.OldEmail != "a" {
+			t.Fatalf("LookupRevertToken = %+v, %v", change, err)
+		}
+	}
+	if _, err := s.LookupRevertToken(ctx, "unknown"); !errors.Is(err, ErrInvalidRevertToken) {
+		t.Errorf("unknown token: LookupRevertToken = %v, want %v", err, ErrInvalidRevertToken)
+	}
+
+	mr.FastForward(time.Hour)
+	if _, _, err := s.ConsumeRevertToken(ctx, tokens[0]); !errors.Is(err, ErrInvalidRevertToken) {
+		t.Errorf("expired token: ConsumeRevertToken = %v, want %v", err, ErrInvalidRevertToken)
+	}
+}
+
+func TestPending(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	accountID := uuid.New()
+
+	if _, err := s.Pending(ctx, accountID); !errors.Is(err, ErrNoPendingChange) {
+		t.Fatalf("Pending = %v, want %v", err, ErrNoPendingChange)
+	}
+	if err := s.SetPending(ctx, accountID, "new@example.com"); err != nil {
+		t.Fatalf("SetPending: %v", err)
+	}
+	if email, err := s.Pending(ctx, accountID); err != nil || email != "new@example.com" {
+		t.Fatalf("Pending = %q, %v", email, err)
+	}
+	if err := s.ClearPending(ctx, accountID); err != nil {
+		t.Fatalf("ClearPending: %v", err)
+	}
+	if _, err := s.Pending(ctx, accountID); !errors.Is(err, ErrNoPendingChange) {
+		t.Fatalf("Pending after clear = %v, want %v", err, ErrNoPendingChange)
+	}
+}
@@ -0,0 +1,65 @@
+package emailtemplate
+
+import (
+	"aspire-auth/internal/models"
+	"regexp"
+	"strings"
+)
+
+const defaultBrandColor = "#1aa19c"
+
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Brand is the name, logo and accent color an email is rendered with.
+type Brand struct {
+	Name    string
+	LogoURL string
+	Color   string
+}
+
+// ValidColor reports whether color is a #rgb or #rrggbb hex color, the only
+// form accepted for service brand colors.
+func ValidColor(color string) bool {
+	return hexColorPattern.MatchString(color)
+}
+
+// ServiceBrand brands emails sent on behalf of service. Relative logo paths
+// are resolved against publicURL since mail clients need absolute URLs.
+func ServiceBrand(service models.Service, publicURL string) Brand {
+	brand := Brand{Name: service.ServiceName}
+	if service.ServiceLogo != nil && *service.ServiceLogo != "" {
+		logo := *service.ServiceLogo
+		if !strings.HasPrefix(logo, "https://") && !strings.HasPrefix(logo, "http://") {
+			logo = strings.TrimRight(publicURL, "/") + "/" + strings.TrimLeft(logo, "/")
+		}
+		brand.LogoURL = logo
+	}
+	if service.BrandColor != nil {
+		brand.Color = *service.BrandColor
+	}
+	return brand
+}
+
+// withFallback fills the fields b leaves empty from fallback. A service with
+// its own name but no logo should not show the operator's logo, so the logo
+// is only inherited together with the name.
+func (b Brand) withFallback(fallback Brand) Brand {
+	if b.Name == "" {
+		b.Name = fallback.Name
+		b.LogoURL = fallback.LogoURL
+	}
+	if !ValidColor(b.Color) {
+		b.Color = fallback.Color
+	}
+	return b
+}
+
+func (b Brand) withDefaults() Brand {
+	if b.Name == "" {
+		b.Name = "Aspire Auth"
+	}
+	if !ValidColor(b.Color) {
+		b.Color = defaultBrandColor
+	}
+	return b
+}
@@ -0,0 +1,274 @@
+// Package emailtemplate renders the transactional emails in templates/ into
+// localized, branded HTML and plaintext bodies.
+package emailtemplate
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/templates"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Email names match the template file names without their extension.
+const (
+	AccountDeletion         = "account_deletion"
+	AccountLocked           = "account_locked"
+	EmailChangeVerification = "email_change_verification"
+	EmailChanged            = "email_changed"
+	EmailVerification       = "email_verification"
+	ForgotPassword          = "forgot_password"
+	MagicLink               = "magic_link"
+	PasswordResetUnknown    = "password_reset_unknown"
+	ServiceTransfer         = "service_transfer"
+	SignInCode              = "sign_in_code"
+)
+
+var emails = []string{
+	AccountDeletion,
+	AccountLocked,
+	EmailChangeVerification,
+	EmailChanged,
+	EmailVerification,
+	ForgotPassword,
+	MagicLink,
+	PasswordResetUnknown,
+	ServiceTransfer,
+	SignInCode,
+}
+
+// Rendered is a fully rendered email, ready to be queued.
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// view is what every template executes against.
+type view struct {
+	Locale  string
+	Subject string
+	Brand   Brand
+	Year    int
+	Data    interface{}
+}
+
+type localeSet struct {
+	translate func(key string, args ...interface{}) string
+	html      map[string]*htmltemplate.Template
+	text      map[string]*texttemplate.Template
+}
+
+// Renderer holds every email parsed once per locale.
+type Renderer struct {
+	defaultLocale string
+	brand         Brand
+	locales       map[string]*localeSet
+}
+
+// New parses the embedded templates, letting files in cfg.TemplateDir
+// replace them by name, so a broken override fails at startup rather than
+// on the first send.
+func New(cfg config.EmailConfig) (*Renderer, error) {
+	var source fs.FS = templates.FS
+	if cfg.TemplateDir != "" {
+		if info, err := os.Stat(cfg.TemplateDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("EMAIL_TEMPLATE_DIR %q is not a directory", cfg.TemplateDir)
+		}
+		source = overlayFS{top: os.DirFS(cfg.TemplateDir), base: templates.FS}
+	}
+
+	catalogs, err := loadCatalogs(source)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultLocale := normalizeTag(cfg.DefaultLocale)
+	if _, ok := catalogs[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no message catalog for default email locale %q", cfg.DefaultLocale)
+	}
+
+	r := &Renderer{
+		defaultLocale: defaultLocale,
+		brand:         Brand{Name: cfg.BrandName, LogoURL: cfg.BrandLogoURL, Color: cfg.BrandColor}.withDefaults(),
+		locales:       make(map[string]*localeSet, len(catalogs)),
+	}
+
+	for locale, catalog := range catalogs {
+		translate := translator(catalog, catalogs[defaultLocale])
+		funcs := map[string]interface{}{"t": translate}
+		set := &localeSet{
+			translate: translate,
+			html:      make(map[string]*htmltemplate.Template, len(emails)),
+			text:      make(map[string]*texttemplate.Template, len(emails)),
+		}
+		for _, name := range emails {
+			html, err := htmltemplate.New("layout.html").Funcs(funcs).ParseFS(source, "layout.html", name+".html")
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s.html: %w", name, err)
+			}
+			text, err := texttemplate.New("layout.txt").Funcs(funcs).ParseFS(source, "layout.txt", name+".txt")
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s.txt: %w", name, err)
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("%s.txt does not define a subject", name)
+			}
+			set.html[name] = html
+			set.text[name] = text
+		}
+		r.locales[locale] = set
+	}
+
+	return r, nil
+}
+
+// Brand returns the operator's default branding.
+func (r *Renderer) Brand() Brand {
+	return r.brand
+}
+
+// Translate formats key from the catalog of locale, for text sent outside
+// of email such as SMS.
+func (r *Renderer) Translate(locale, key string, args ...interface{}) string {
+	set, ok := r.locales[normalizeTag(locale)]
+	if !ok {
+		set = r.locales[r.defaultLocale]
+	}
+	return set.translate(key, args...)
+}
+
+// Render produces the subject and both bodies of email name in locale,
+// falling back to the default locale when it is not supported.
+func (r *Renderer) Render(name, locale string, brand Brand, data interface{}) (*Rendered, error) {
+	set, ok := r.locales[normalizeTag(locale)]
+	if !ok {
+		locale = r.defaultLocale
+		set = r.locales[locale]
+	}
+
+	html, ok := set.html[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	text := set.text[name]
+
+	v := view{
+		Locale: normalizeTag(locale),
+		Brand:  brand.withFallback(r.brand),
+		Year:   time.Now().Year(),
+		Data:   data,
+	}
+
+	var subject bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", v); err != nil {
+		return nil, err
+	}
+	v.Subject = strings.TrimSpace(subject.String())
+
+	var textBody bytes.Buffer
+	if err := text.Execute(&textBody, v); err != nil {
+		return nil, err
+	}
+
+	var htmlBody bytes.Buffer
+	if err := html.Execute(&htmlBody, v); err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Subject: v.Subject,
+		HTML:    htmlBody.String(),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+	}, nil
+}
+
+// translator looks key up in catalog, then in fallback, and formats it with
+// args. A key missing from both is returned as is so the gap is visible.
+func translator(catalog, fallback map[string]string) func(key string, args ...interface{}) string {
+	return func(key string, args ...interface{}) string {
+		message, ok := catalog[key]
+		if !ok {
+			message, ok = fallback[key]
+		}
+		if !ok {
+			return key
+		}
+		if len(args) == 0 {
+			return message
+		}
+		return fmt.Sprintf(message, args...)
+	}
+}
+
+func loadCatalogs(source fs.FS) (map[string]map[string]string, error) {
+	files, err := fs.Glob(source, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		raw, err := fs.ReadFile(source, file)
+		if err != nil {
+			return nil, err
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(raw, &catalog); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", file, err)
+		}
+		catalogs[normalizeTag(strings.TrimSuffix(path.Base(file), ".json"))] = catalog
+	}
+	if len(catalogs) == 0 {
+		return nil, errors.New("no email message catalogs found")
+	}
+	return catalogs, nil
+}
+
+// overlayFS serves files from top when they exist there and from base
+// otherwise. Glob results are merged so overrides can add new locales.
+type overlayFS struct {
+	top  fs.FS
+	base fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	file, err := o.top.Open(name)
+	if err == nil {
+		return file, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return o.base.Open(name)
+}
+
+func (o overlayFS) Glob(pattern string) ([]string, error) {
+	matches, err := fs.Glob(o.base, pattern)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := fs.Glob(o.top, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		seen[match] = true
+	}
+	for _, match := range extra {
+		if !seen[match] {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,93 @@
+package emailtemplate
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ResolveLocale picks the language for an email: the account's saved
+// preference if it is supported, then the best supported match from an
+// Accept-Language header, then the default locale.
+func (r *Renderer) ResolveLocale(preferred *string, acceptLanguage string) string {
+	if preferred != nil {
+		if locale, ok := r.match(*preferred); ok {
+			return locale
+		}
+	}
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if locale, ok := r.match(tag); ok {
+			return locale
+		}
+	}
+
+	return r.defaultLocale
+}
+
+// SupportsLocale reports whether a message catalog exists for locale or its
+// base language.
+func (r *Renderer) SupportsLocale(locale string) bool {
+	_, ok := r.match(locale)
+	return ok
+}
+
+// match accepts an exact tag ("pt-br") or its base language ("pt").
+func (r *Renderer) match(tag string) (string, bool) {
+	tag = normalizeTag(tag)
+	if tag == "" {
+		return "", false
+	}
+	if _, ok := r.locales[tag]; ok {
+		return tag, true
+	}
+	if base, _, found := strings.Cut(tag, "-"); found {
+		if _, ok := r.locales[base]; ok {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header ordered
+// by descending quality. Wildcards and tags with q=0 are dropped.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/worker"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// claimLease is how long a claimed event is hidden from other dispatchers.
+const claimLease = 5 * time.Minute
+
+// maxRetryDelay caps the exponential backoff between attempts.
+const maxRetryDelay = time.Hour
+
+// cleanupInterval is how often handled events past retention are removed.
+const cleanupInterval = time.Hour
+
+// Handler processes one event for a subscriber. Returning an error retries
+// the event for this subscriber later.
+type Handler func(ctx context.Context, event *Envelope) error
+
+type subscriber struct {
+	name   string
+	types  []string
+	handle Handler
+}
+
+func (s *subscriber) wants(eventType string) bool {
+	return len(s.types) == 0 || slices.Contains(s.types, eventType)
+}
+
+// Bus delivers outbox events to subscribers from a background loop.
+type Bus struct {
+	db          *gorm.DB
+	config      config.EventsConfig
+	subscribers []subscriber
+}
+
+func NewBus(db *gorm.DB, cfg config.EventsConfig) *Bus {
+	return &Bus{db: db, config: cfg}
+}
+
+// Subscribe registers handle for the given event types, or every type when
+// none are given. name identifies the subscriber in the outbox, so it must be
+// unique and stay the same across releases. Subscribe before Run.
+func (b *Bus) Subscribe(name string, handle Handler, types ...string) {
+	b.subscribers = append(b.subscribers, subscriber{name: name, types: types, handle: handle})
+}
+
+// Run delivers pending events until ctx is cancelled.
+func (b *Bus) Run(ctx context.Context) {
+	log.Printf("Event bus started with %d subscribers, polling every %s", len(b.subscribers), b.config.PollInterval)
+
+	worker.Loop{
+		Name:            "Event bus",
+		Interval:        b.config.PollInterval,
+		BatchSize:       b.config.BatchSize,
+		Process:         b.dispatch,
+		Cleanup:         b.cleanup,
+		CleanupInterval: cleanupInterval,
+	}.Run(ctx)
+}
+
+// dispatch claims one batch of due events and hands each to its subscribers.
+func (b *Bus) dispatch(ctx context.Context) (int, error) {
+	batch, err := worker.ClaimDue[models.DomainEvent](ctx, b.db, models.DomainEventPending, b.config.BatchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range batch {
+		b.deliver(ctx, &batch[i])
+	}
+	return len(batch), nil
+}
+
+func (b *Bus) deliver(ctx context.Context, record *models.DomainEvent) {
+	var completed []string
+	if record.Completed != "" {
+		completed = strings.Split(record.Completed, ",")
+	}
+
+	var failures []string
+	event, err := envelope(record)
+	if err != nil {
+		failures = append(failures, fmt.Sprintf("decoding: %v", err))
+	} else {
+		for i := range b.subscribers {
+			sub := &b.subscribers[i]
+			if !sub.wants(event.Type) || slices.Contains(completed, sub.name) {
+				continue
+			}
+			if err := b.call(ctx, sub, event); err != nil {
+				log.Printf("Event %s (%s): subscriber %s failed: %v", record.ID, record.EventType, sub.name, err)
+				failures = append(failures, fmt.Sprintf("%s: %v", sub.name, err))
+				continue
+			}
+			completed = append(completed, sub.name)
+		}
+	}
+
+	attempts := record.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":  attempts,
+		"completed": strings.Join(completed, ","),
+	}
+
+	switch {
+	case len(failures) == 0:
+		updates["status"] = models.DomainEventProcessed
+		updates["processed_at"] = time.Now()
+		updates["last_error"] = ""
+	case attempts >= b.config.MaxAttempts:
+		log.Printf("Giving up on event %s (%s) after %d attempts", record.ID, record.EventType, attempts)
+		updates["status"] = models.DomainEventFailed
+		updates["last_error"] = strings.Join(failures, "; ")
+	default:
+		updates["next_attempt_at"] = time.Now().Add(worker.RetryDelay(b.config.RetryBaseDelay, maxRetryDelay, attempts))
+		updates["last_error"] = strings.Join(failures, "; ")
+	}
+
+	if err := b.db.Model(&models.DomainEvent{}).Where("id = ?", record.ID).Updates(updates).Error; err != nil {
+		log.Printf("Error updating event %s: %v", record.ID, err)
+	}
+}
+
+// call runs one subscriber, turning a panic into an error so one faulty
+// subscriber cannot stop the others.
+func (b *Bus) call(ctx context.Context, sub *subscriber, event *Envelope) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return sub.handle(ctx, event)
+}
+
+// cleanup removes handled events older than the retention period.
+func (b *Bus) cleanup(ctx context.Context) error {
+	return b.db.WithContext(ctx).
+		Where("status <> ? AND created_at < ?", models.DomainEventPending, time.Now().Add(-b.config.Retention)).
+		Delete(&models.DomainEvent{}).Error
+}
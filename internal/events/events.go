@@ -0,0 +1,125 @@
+// Package events is the internal event bus. Handlers publish domain events
+// inside the transaction that makes the change, into the DOMAIN_EVENTS
+// outbox, so an event exists exactly when its change was committed. The Bus
+// then delivers each event to its subscribers from a background loop, at
+// least once: subscribers must tolerate seeing an event again.
+package events
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Domain event types.
+const (
+	AccountCreated = "account.created"
+	AccountUpdated = "account.updated"
+	AccountDeleted = "account.deleted"
+	MemberJoined   = "service.member_joined"
+	MemberLeft     = "service.member_left"
+)
+
+// AccountCreatedPayload is published when an account signs up. Locale is the
+// language its first email should use.
+type AccountCreatedPayload struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Locale    string    `json:"locale"`
+}
+
+// AccountUpdatedPayload is published when profile details change.
+type AccountUpdatedPayload struct {
+	AccountID uuid.UUID `json:"account_id"`
+}
+
+// AccountDeletedPayload is published when an account is purged. The account
+// is gone by the time subscribers run, so it carries what they need.
+type AccountDeletedPayload struct {
+	AccountID  uuid.UUID   `json:"account_id"`
+	Email      string      `json:"email"`
+	ServiceIDs []uuid.UUID `json:"service_ids"`
+}
+
+// MembershipPayload is published when an account joins or leaves a service.
+type MembershipPayload struct {
+	AccountID uuid.UUID `json:"account_id"`
+	ServiceID uuid.UUID `json:"service_id"`
+}
+
+// Event is what handlers publish. Audit, when set, is recorded once the
+// change is committed.
+type Event struct {
+	Type    string
+	Payload interface{}
+	Audit   *audit.Event
+}
+
+// Publish writes event to the outbox through tx, so it is only delivered if
+// tx commits.
+func Publish(ctx context.Context, tx *gorm.DB, event Event) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	record := models.DomainEvent{
+		EventType:     event.Type,
+		Payload:       string(payload),
+		Status:        models.DomainEventPending,
+		NextAttemptAt: time.Now(),
+	}
+	if event.Audit != nil {
+		encoded, err := json.Marshal(event.Audit)
+		if err != nil {
+			return err
+		}
+		auditJSON := string(encoded)
+		record.Audit = &auditJSON
+	}
+	return tx.WithContext(ctx).Create(&record).Error
+}
+
+// Envelope is a published event as subscribers receive it.
+type Envelope struct {
+	ID        uuid.UUID
+	Type      string
+	Payload   json.RawMessage
+	Audit     *audit.Event
+	CreatedAt time.Time
+}
+
+// Decode unmarshals the payload into v.
+func (e *Envelope) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+func envelope(record *models.DomainEvent) (*Envelope, error) {
+	env := &Envelope{
+		ID:        record.ID,
+		Type:      record.EventType,
+		Payload:   json.RawMessage(record.Payload),
+		CreatedAt: record.CreatedAt,
+	}
+	if record.Audit != nil {
+		env.Audit = &audit.Event{}
+		if err := json.Unmarshal([]byte(*record.Audit), env.Audit); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+// AuditSubscriber records the audit event carried by each event.
+func AuditSubscriber(recorder audit.Recorder) Handler {
+	return func(ctx context.Context, event *Envelope) error {
+		if event.Audit != nil {
+			recorder.Record(ctx, *event.Audit)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,273 @@
+package export
+
+import (
+	"archive/zip"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// formatVersion is bumped whenever the layout of the archive changes.
+const formatVersion = 2
+
+// loginEvents are the audit events that make up an account's login history.
+var loginEvents = []string{
+	audit.EventLogin,
+	audit.EventMagicLinkLogin,
+	audit.EventMFAVerify,
+	audit.EventServiceLogin,
+	audit.EventServiceMagicLinkLogin,
+}
+
+type manifest struct {
+	FormatVersion int       `json:"format_version"`
+	AccountID     uuid.UUID `json:"account_id"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	Files         []string  `json:"files"`
+}
+
+type membership struct {
+	ServiceID   uuid.UUID `json:"service_id"`
+	ServiceName string    `json:"service_name"`
+	Role        string    `json:"role"`
+	IsVerified  bool      `json:"is_verified"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+type ownedService struct {
+	ID             uuid.UUID  `json:"id"`
+	Name           string     `json:"name"`
+	Description    *string    `json:"description,omitempty"`
+	Logo           *string    `json:"logo,omitempty"`
+	BrandColor     *string    `json:"brand_color,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	UsersCount     int64      `json:"users_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type organizationMembership struct {
+	OrganizationID   uuid.UUID               `json:"organization_id"`
+	OrganizationName string                  `json:"organization_name"`
+	Role             models.OrganizationRole `json:"role"`
+	JoinedAt         time.Time               `json:"joined_at"`
+}
+
+// session describes a refresh token without the token itself.
+type session struct {
+	ID        uuid.UUID       `json:"id"`
+	Kind      string          `json:"kind"`
+	ServiceID *uuid.UUID      `json:"service_id,omitempty"`
+	RoleType  models.RoleType `json:"role_type"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// build writes the archive for accountID to w.
+func (s *Service) build(ctx context.Context, accountID uuid.UUID, w io.Writer) error {
+	db := s.db.WithContext(ctx)
+
+	var account models.Account
+	if err := db.Where("id = ?", accountID).First(&account).Error; err != nil {
+		return fmt.Errorf("loading account: %w", err)
+	}
+
+	documents := []struct {
+		name string
+		load func(*gorm.DB, *models.Account) (interface{}, error)
+	}{
+		{"account.json", func(*gorm.DB, *models.Account) (interface{}, error) { return account, nil }},
+		{"memberships.json", loadMemberships},
+		{"owned_services.json", loadOwnedServices},
+		{"organizations.json", loadOrganizations},
+		{"sessions.json", loadSessions},
+		{"login_history.json", loadLoginHistory},
+	}
+
+	archive := zip.NewWriter(w)
+	contents := manifest{FormatVersion: formatVersion, AccountID: account.ID, GeneratedAt: time.Now().UTC()}
+
+	for _, document := range documents {
+		value, err := document.load(db, &account)
+		if err != nil {
+			return fmt.Errorf("collecting %s: %w", document.name, err)
+		}
+		if err := writeJSON(archive, document.name, value); err != nil {
+			return err
+		}
+		contents.Files = append(contents.Files, document.name)
+	}
+
+	if account.Avatar != nil && *account.Avatar != "" {
+		key, err := helpers.AvatarKey(*account.Avatar, "")
+		if err != nil {
+			return err
+		}
+		name := "avatar/" + *account.Avatar
+		copied, err := s.writeObject(ctx, archive, name, key)
+		if err != nil {
+			return err
+		}
+		if copied {
+			contents.Files = append(contents.Files, name)
+		}
+	}
+
+	if err := writeJSON(archive, "manifest.json", contents); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("finishing archive: %w", err)
+	}
+	return nil
+}
+
+func loadMemberships(db *gorm.DB, account *models.Account) (interface{}, error) {
+	var rows []models.ServicesUser
+	if err := db.Where("user_id = ?", account.ID).Order("created_at").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	memberships := make([]membership, 0, len(rows))
+	for _, row := range rows {
+		var service models.Service
+		if err := db.Select("id", "owner_id", "service_name").Where("id = ?", row.ServiceID).First(&service).Error; err != nil {
+			return nil, err
+		}
+
+		role := "member"
+		if service.OwnerID == account.ID {
+			role = "owner"
+		}
+		memberships = append(memberships, membership{
+			ServiceID:   service.ID,
+			ServiceName: service.ServiceName,
+			Role:        role,
+			IsVerified:  row.IsVerified,
+			JoinedAt:    row.CreatedAt,
+		})
+	}
+	return memberships, nil
+}
+
+func loadOrganizations(db *gorm.DB, account *models.Account) (interface{}, error) {
+	organizations := []organizationMembership{}
+	err := db.Model(&models.OrganizationMember{}).
+		Select("organization_members.organization_id, organizations.name AS organization_name, organization_members.role, organization_members.created_at AS joined_at").
+		Joins("JOIN organizations ON organizations.id = organization_members.organization_id").
+		Where("organization_members.account_id = ?", account.ID).
+		Order("organization_members.created_at").
+		Scan(&organizations).Error
+	return organizations, err
+}
+
+func loadOwnedServices(db *gorm.DB, account *models.Account) (interface{}, error) {
+	var services []models.Service
+	if err := db.Where("owner_id = ?", account.ID).Order("created_at").Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	owned := make([]ownedService, 0, len(services))
+	for _, service := range services {
+		var usersCount int64
+		if err := db.Model(&models.ServicesUser{}).Where("service_id = ?", service.ID).Count(&usersCount).Error; err != nil {
+			return nil, err
+		}
+		owned = append(owned, ownedService{
+			ID:             service.ID,
+			Name:           service.ServiceName,
+			Description:    service.ServiceDescription,
+			Logo:           service.ServiceLogo,
+			BrandColor:     service.BrandColor,
+			OrganizationID: service.OrganizationID,
+			UsersCount:     usersCount,
+			CreatedAt:      service.CreatedAt,
+		})
+	}
+	return owned, nil
+}
+
+func loadSessions(db *gorm.DB, account *models.Account) (interface{}, error) {
+	var accountTokens []models.AccountRefreshToken
+	if err := db.Where("user_id = ? AND expires_at > ?", account.ID, time.Now()).Order("created_at").Find(&accountTokens).Error; err != nil {
+		return nil, err
+	}
+	var serviceTokens []models.ServiceRefreshToken
+	if err := db.Where("user_id = ? AND expires_at > ?", account.ID, time.Now()).Order("created_at").Find(&serviceTokens).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]session, 0, len(accountTokens)+len(serviceTokens))
+	for _, token := range accountTokens {
+		sessions = append(sessions, session{
+			ID:        token.ID,
+			Kind:      "account",
+			RoleType:  token.RoleType,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+		})
+	}
+	for _, token := range serviceTokens {
+		serviceID := token.ServiceID
+		sessions = append(sessions, session{
+			ID:        token.ID,
+			Kind:      "service",
+			ServiceID: &serviceID,
+			RoleType:  token.RoleType,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+		})
+	}
+	return sessions, nil
+}
+
+func loadLoginHistory(db *gorm.DB, account *models.Account) (interface{}, error) {
+	events := []models.AuditEvent{}
+	err := db.Where("account_id = ? AND event_type IN ?", account.ID, loginEvents).
+		Order("created_at DESC").
+		Find(&events).Error
+	return events, err
+}
+
+func writeJSON(archive *zip.Writer, name string, value interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("adding %s: %w", name, err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeObject copies the stored object at key into the archive. It reports
+// false, without error, when the object does not exist.
+func (s *Service) writeObject(ctx context.Context, archive *zip.Writer, name, key string) (bool, error) {
+	src, _, err := s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("reading %s: %w", key, err)
+	}
+	defer src.Close()
+
+	w, err := archive.Create(name)
+	if err != nil {
+		return false, fmt.Errorf("adding %s: %w", name, err)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return false, fmt.Errorf("writing %s: %w", name, err)
+	}
+	return true, nil
+}
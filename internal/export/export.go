@@ -0,0 +1,212 @@
+package export
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/storage"
+	"aspire-auth/internal/worker"
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// claimLease is how long a claimed export is hidden from other workers. A
+// worker that dies mid-build leaves it to be picked up afterwards.
+const claimLease = 10 * time.Minute
+
+var ErrInvalidLink = errors.New("invalid or expired download link")
+
+// Signer produces the MAC of a download link payload. JWTHelpers implements
+// it with a key from the key provider.
+type Signer interface {
+	SignDownload(payload []byte) []byte
+}
+
+// Service queues personal data exports, builds them in the background and
+// issues the signed links they are downloaded through.
+type Service struct {
+	db        *gorm.DB
+	signer    Signer
+	store     storage.Store
+	config    config.ExportConfig
+	publicURL string
+}
+
+func NewService(db *gorm.DB, signer Signer, store storage.Store, cfg config.ExportConfig, publicURL string) *Service {
+	return &Service{db: db, signer: signer, store: store, config: cfg, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// Request queues an export for accountID. An export that is still being
+// built is returned instead of queueing another; created reports which.
+func (s *Service) Request(ctx context.Context, accountID uuid.UUID) (export *models.AccountExport, created bool, err error) {
+	var existing models.AccountExport
+	err = s.db.WithContext(ctx).
+		Where("account_id = ? AND status IN ?", accountID, []models.AccountExportStatus{models.ExportPending, models.ExportProcessing}).
+		First(&existing).Error
+	if err == nil {
+		return &existing, false, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	export = &models.AccountExport{AccountID: accountID, Status: models.ExportPending}
+	if err := s.db.WithContext(ctx).Create(export).Error; err != nil {
+		return nil, false, err
+	}
+	return export, true, nil
+}
+
+// Link returns a download URL for a ready export, valid for the configured
+// link TTL, and when it stops working.
+func (s *Service) Link(export *models.AccountExport) (string, time.Time) {
+	expiresAt := time.Now().Add(s.config.LinkTTL)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	signature := base64.RawURLEncoding.EncodeToString(s.signer.SignDownload(linkPayload(export.ID, expires)))
+
+	query := url.Values{"expires": {expires}, "signature": {signature}}
+	return fmt.Sprintf("%s/export/%s/download?%s", s.publicURL, export.ID, query.Encode()), expiresAt
+}
+
+// VerifyLink checks the expires and signature parameters of a download link
+// for exportID.
+func (s *Service) VerifyLink(exportID uuid.UUID, expires, signature string) error {
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.signer.SignDownload(linkPayload(exportID, expires))) {
+		return ErrInvalidLink
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidLink
+	}
+	return nil
+}
+
+func linkPayload(exportID uuid.UUID, expires string) []byte {
+	return []byte("export:" + exportID.String() + ":" + expires)
+}
+
+// Run builds queued exports and removes expired ones until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	log.Printf("Data export worker started, storing archives in %s storage", s.store.Name())
+
+	worker.Loop{
+		Name:      "Data export",
+		Interval:  s.config.PollInterval,
+		BatchSize: 1,
+		Process:   s.buildNext,
+		Cleanup:   s.cleanup,
+	}.Run(ctx)
+}
+
+// buildNext builds the oldest queued export, or one whose worker's lease ran
+// out, and reports whether there was one.
+func (s *Service) buildNext(ctx context.Context) (int, error) {
+	claimed, err := worker.Claim[models.AccountExport](ctx, s.db, 1, "created_at",
+		map[string]interface{}{"status": models.ExportProcessing, "updated_at": time.Now()},
+		"status = ? OR (status = ? AND updated_at <= ?)", models.ExportPending, models.ExportProcessing, time.Now().Add(-claimLease))
+	if err != nil || len(claimed) == 0 {
+		return 0, err
+	}
+
+	s.process(ctx, &claimed[0])
+	return 1, nil
+}
+
+func (s *Service) process(ctx context.Context, export *models.AccountExport) {
+	key, size, err := s.save(ctx, export)
+	if err != nil {
+		log.Printf("Data export %s failed: %v", export.ID, err)
+		updates := map[string]interface{}{
+			"status":     models.ExportFailed,
+			"last_error": err.Error(),
+			"expires_at": time.Now().Add(s.config.Retention),
+		}
+		if err := s.db.WithContext(ctx).Model(export).Updates(updates).Error; err != nil {
+			log.Printf("Data export %s: could not record failure: %v", export.ID, err)
+		}
+		return
+	}
+
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(export).Updates(map[string]interface{}{
+		"status":       models.ExportReady,
+		"storage_key":  key,
+		"size_bytes":   size,
+		"completed_at": now,
+		"expires_at":   now.Add(s.config.Retention),
+	}).Error; err != nil {
+		log.Printf("Data export %s: could not mark ready: %v", export.ID, err)
+		s.removeArchive(ctx, key)
+	}
+}
+
+// save builds the archive for export in a temporary file and uploads it,
+// returning its storage key and size.
+func (s *Service) save(ctx context.Context, export *models.AccountExport) (string, int64, error) {
+	key, err := storage.Key("exports", export.ID.String()+".zip")
+	if err != nil {
+		return "", 0, err
+	}
+
+	file, err := os.CreateTemp("", "account-export-*.zip")
+	if err != nil {
+		return "", 0, fmt.Errorf("creating archive: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := s.build(ctx, export.AccountID, file); err != nil {
+		return "", 0, err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	if err := s.store.Put(ctx, key, file, "application/zip"); err != nil {
+		return "", 0, fmt.Errorf("storing archive: %w", err)
+	}
+	return key, size, nil
+}
+
+// cleanup removes exports, and their archives, once they expire.
+func (s *Service) cleanup(ctx context.Context) error {
+	var expired []models.AccountExport
+	err := s.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Find(&expired).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range expired {
+		if expired[i].StorageKey != nil {
+			s.removeArchive(ctx, *expired[i].StorageKey)
+		}
+		if err := s.db.WithContext(ctx).Delete(&expired[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) removeArchive(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("Data export: could not remove %s: %v", key, err)
+	}
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// hmacSigner signs with a fixed key, like JWTHelpers does with its derived one.
+type hmacSigner []byte
+
+func (s hmacSigner) SignDownload(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func TestLink(t *testing.T) {
+	s := NewService(nil, hmacSigner("test-key"), nil, config.ExportConfig{LinkTTL: time.Hour}, "https://auth.example.com/")
+	export := &models.AccountExport{ID: uuid.New()}
+
+	link, expiresAt := s.Link(export)
+	if remaining := time.Until(expiresAt); remaining <= 59*time.Minute || remaining > time.Hour {
+		t.Fatalf("Link() expires in %s, want the link TTL", remaining)
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Link() = %q: %v", link, err)
+	}
+	if want := "/export/" + export.ID.String() + "/download"; parsed.Host != "auth.example.com" || parsed.Path != want {
+		t.Fatalf("Link() = %q, want https://auth.example.com%s", link, want)
+	}
+	query := parsed.Query()
+	if err := s.VerifyLink(export.ID, query.Get("expires"), query.Get("signature")); err != nil {
+		t.Fatalf("VerifyLink() of an issued link: %v", err)
+	}
+}
+
+func TestVerifyLink(t *testing.T) {
+	exportID := uuid.New()
+	sign := func(key string, id uuid.UUID, expires string) string {
+		return base64.RawURLEncoding.EncodeToString(hmacSigner(key).SignDownload(linkPayload(id, expires)))
+	}
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		expires   string
+		signature string
+		wantErr   error
+	}{
+		{name: "valid", expires: future, signature: sign("test-key", exportID, future)},
+		{name: "expired", expires: past, signature: sign("test-key", exportID, past), wantErr: ErrInvalidLink},
+		{name: "extended expiry", expires: strconv.FormatInt(time.Now().Add(48*time.Hour).Unix(), 10), signature: sign("test-key", exportID, future), wantErr: ErrInvalidLink},
+		{name: "other export", expires: future, signature: sign("test-key", uuid.New(), future), wantErr: ErrInvalidLink},
+		{name: "other key", expires: future, signature: sign("other-key", exportID, future), wantErr: ErrInvalidLink},
+		{name: "missing signature", expires: future, wantErr: ErrInvalidLink},
+		{name: "bad signature encoding", expires: future, signature: sign("test-key", exportID, future) + "!", wantErr: ErrInvalidLink},
+		{name: "non-numeric expiry", expires: "soon", signature: sign("test-key", exportID, "soon"), wantErr: ErrInvalidLink},
+		{name: "truncated signature", expires: future, signature: sign("test-key", exportID, future)[:20], wantErr: ErrInvalidLink},
+	}
+
+	s := NewService(nil, hmacSigner("test-key"), nil, config.ExportConfig{LinkTTL: time.Hour}, "https://auth.example.com")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.VerifyLink(exportID, tt.expires, tt.signature); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyLink() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
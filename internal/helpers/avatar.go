@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/imaging"
+	"aspire-auth/internal/storage"
+	"context"
+	"encoding/base64"
+	"strings"
+)
+
+// AvatarPrefix is the storage key prefix, and image directory, of avatars.
+const AvatarPrefix = "avatars"
+
+// AvatarVariants are the sizes every avatar is stored in. The first is what
+// Account.Avatar names; the others are picked with ?size= when served.
+var AvatarVariants = []imaging.Variant{
+	{Name: "large", Size: 512},
+	{Name: "medium", Size: 256},
+	{Name: "small", Size: 64},
+}
+
+// AvatarOptions returns the processing options for avatar uploads.
+func AvatarOptions(cfg config.AvatarConfig) imaging.Options {
+	return imaging.Options{
+		MaxBytes:     cfg.MaxBytes,
+		MinDimension: cfg.MinDimension,
+		MaxDimension: cfg.MaxDimension,
+		Square:       true,
+		Variants:     AvatarVariants,
+	}
+}
+
+// DecodeBase64Image decodes an image sent as base64, with or without a
+// data: URL prefix.
+func DecodeBase64Image(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "data:") {
+		if _, encoded, found := strings.Cut(value, ","); found {
+			value = encoded
+		}
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+}
+
+// AvatarKey returns the storage key of a variant of the avatar filename.
+func AvatarKey(filename, variant string) (string, error) {
+	return imageKey(AvatarPrefix, AvatarVariants, filename, variant)
+}
+
+// SaveAvatar stores every variant of a processed avatar and returns the
+// file name to store on the account.
+func SaveAvatar(ctx context.Context, store storage.Store, result *imaging.Result) (string, error) {
+	return saveImage(ctx, store, AvatarPrefix, AvatarVariants, result)
+}
+
+// DeleteAvatar removes an avatar and all of its variants.
+func DeleteAvatar(ctx context.Context, store storage.Store, filename string) error {
+	return deleteImage(ctx, store, AvatarPrefix, AvatarVariants, filename)
+}
@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"aspire-auth/internal/keyprovider"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+)
+
+func (h *JWTHelpers) loadDownloadKey(ctx context.Context, provider keyprovider.Provider) error {
+	secret, err := keyprovider.Optional(ctx, provider, keyprovider.DownloadURLSecret)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", keyprovider.DownloadURLSecret, err)
+	}
+
+	// Domain-separated like the magic link key, so neither signature fits the other
+	if secret == "" {
+		secret = "aspire-auth:download|" + h.accountRefreshSecret
+	}
+	h.downloadKey = deriveKey(secret)
+	return nil
+}
+
+// SignDownload returns the HMAC-SHA256 of a download link payload.
+func (h *JWTHelpers) SignDownload(payload []byte) []byte {
+	mac := hmac.New(sha256.New, h.downloadKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
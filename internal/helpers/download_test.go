@@ -0,0 +1,25 @@
+package helpers
+
+import (
+	"aspire-auth/internal/keyprovider"
+	"bytes"
+	"testing"
+)
+
+func TestSignDownload(t *testing.T) {
+	payload := []byte("export:payload")
+	fallback := mustHelpers(t, 0, map[string]string{keyprovider.ServiceEncryptKeys: "1:key"})
+
+	if !bytes.Equal(fallback.SignDownload(payload), fallback.SignDownload(payload)) {
+		t.Fatal("SignDownload() is not deterministic")
+	}
+	// The fallback keys come from the same secret but must not be interchangeable
+	if bytes.Equal(fallback.SignDownload(payload), fallback.SignMagicLink(payload)) {
+		t.Fatal("download and magic link signatures match")
+	}
+
+	dedicated := mustHelpers(t, 0, map[string]string{keyprovider.ServiceEncryptKeys: "1:key", keyprovider.DownloadURLSecret: "download"})
+	if bytes.Equal(dedicated.SignDownload(payload), fallback.SignDownload(payload)) {
+		t.Fatal("DownloadURLSecret is not used")
+	}
+}
@@ -2,63 +2,209 @@ package helpers
 
 import (
 	"aspire-auth/internal/config"
-	"bytes"
-	"crypto/tls"
-	"fmt"
-	"html/template"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/mailer"
+	"context"
 	"time"
-
-	"gopkg.in/gomail.v2"
 )
 
+// Mail carries what every email needs besides its content: the queue it is
+// delivered through, the templates it is rendered with, and the language and
+// branding to use. An empty Brand renders with the operator's branding.
+type Mail struct {
+	Queue     mailer.Queue
+	Templates *emailtemplate.Renderer
+	Locale    string
+	Brand     emailtemplate.Brand
+}
+
 type EmailData struct {
+	Email      string
+	OTP        string
+	ExpiresIn  string
+	VerifyLink string
+	LinkExpiry string
+}
+
+type MagicLinkEmailData struct {
+	Email      string
+	SignInLink string
+	LinkExpiry string
+}
+
+type PasswordResetEmailData struct {
+	Email     string
+	ResetCode string
+	ExpiresIn string
+}
+
+type PasswordResetUnknownEmailData struct {
 	Email string
-	OTP   string
-	Year  int
 }
 
-func SendVerificationEmail(to, otp string, config *config.Config) error {
-	// Parse template from file
-	t, err := template.ParseFiles("templates/email_verification.html")
-	if err != nil {
-		return err
-	}
+type EmailChangeCodeEmailData struct {
+	Email     string
+	OTP       string
+	ExpiresIn string
+}
+
+type EmailChangedEmailData struct {
+	Email      string
+	NewEmail   string
+	RevertLink string
+	LinkExpiry string
+}
 
-	// Prepare data
+type AccountLockedEmailData struct {
+	Email      string
+	UnlockLink string
+	LockedFor  string
+	LinkExpiry string
+}
+
+type ServiceTransferEmailData struct {
+	Email       string
+	FromEmail   string
+	ServiceName string
+	AcceptLink  string
+	LinkExpiry  string
+}
+
+type AccountDeletionEmailData struct {
+	Email      string
+	DeleteOn   string
+	TransferTo string
+}
+
+// SendVerificationEmail sends the account verification code and, when
+// verifyLink is set, a one-click alternative
+func SendVerificationEmail(ctx context.Context, mail Mail, to, otp, verifyLink string, config *config.Config) error {
 	data := EmailData{
+		Email:      to,
+		OTP:        otp,
+		ExpiresIn:  config.OTP.TTL.String(),
+		VerifyLink: verifyLink,
+		LinkExpiry: config.MagicLink.TTL.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.EmailVerification, data)
+}
+
+// SendMagicLinkEmail sends a sign-in link; the service being signed in to is
+// named by mail.Brand
+func SendMagicLinkEmail(ctx context.Context, mail Mail, to, signInLink string, config *config.Config) error {
+	data := MagicLinkEmailData{
+		Email:      to,
+		SignInLink: signInLink,
+		LinkExpiry: config.MagicLink.TTL.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.MagicLink, data)
+}
+
+func SendPasswordResetEmail(ctx context.Context, mail Mail, to, code string, expiresIn time.Duration, config *config.Config) error {
+	data := PasswordResetEmailData{
+		Email:     to,
+		ResetCode: code,
+		ExpiresIn: expiresIn.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.ForgotPassword, data)
+}
+
+// SendPasswordResetUnknownEmail tells an address that asked for a password
+// reset that no account uses it
+func SendPasswordResetUnknownEmail(ctx context.Context, mail Mail, to string) error {
+	data := PasswordResetUnknownEmailData{
 		Email: to,
-		OTP:   otp,
-		Year:  time.Now().Year(),
 	}
 
-	// Execute template
-	var body bytes.Buffer
-	if err := t.Execute(&body, data); err != nil {
-		return err
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.PasswordResetUnknown, data)
+}
+
+func SendEmailChangeCodeEmail(ctx context.Context, mail Mail, to, otp string, expiresIn time.Duration, config *config.Config) error {
+	data := EmailChangeCodeEmailData{
+		Email:     to,
+		OTP:       otp,
+		ExpiresIn: expiresIn.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.EmailChangeVerification, data)
+}
+
+// SendEmailChangedEmail notifies the previous address, which is the only one
+// an attacker who changed the email cannot read
+func SendEmailChangedEmail(ctx context.Context, mail Mail, to, newEmail, revertLink string, linkExpiry time.Duration, config *config.Config) error {
+	data := EmailChangedEmailData{
+		Email:      to,
+		NewEmail:   newEmail,
+		RevertLink: revertLink,
+		LinkExpiry: linkExpiry.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.EmailChanged, data)
+}
+
+// SendSignInCodeEmail sends the second-step code for accounts with MFA enabled
+func SendSignInCodeEmail(ctx context.Context, mail Mail, to, otp string, expiresIn time.Duration, config *config.Config) error {
+	data := EmailData{
+		Email:     to,
+		OTP:       otp,
+		ExpiresIn: expiresIn.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.SignInCode, data)
+}
+
+func SendAccountLockedEmail(ctx context.Context, mail Mail, to, unlockLink string, lockedFor, linkExpiry time.Duration, config *config.Config) error {
+	data := AccountLockedEmailData{
+		Email:      to,
+		UnlockLink: unlockLink,
+		LockedFor:  lockedFor.String(),
+		LinkExpiry: linkExpiry.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.AccountLocked, data)
+}
+
+// SendAccountDeletionEmail confirms a scheduled deletion and says how to
+// cancel it. transferTo is the email receiving owned services, if any.
+func SendAccountDeletionEmail(ctx context.Context, mail Mail, to string, deleteOn time.Time, transferTo string, config *config.Config) error {
+	data := AccountDeletionEmailData{
+		Email:      to,
+		DeleteOn:   deleteOn.UTC().Format("January 2, 2006 15:04 MST"),
+		TransferTo: transferTo,
 	}
 
-	// Create new message
-	m := gomail.NewMessage()
-	m.SetHeader("From", config.Email.From)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", "Verify Your Aspire Auth Account")
-	m.SetBody("text/html", body.String())
-
-	// Create dialer
-	d := gomail.NewDialer(
-		config.Email.Host,
-		config.Email.Port,
-		config.Email.Username,
-		config.Email.Password,
-	)
-
-	fmt.Println(config.Email.Host, config.Email.Port, config.Email.Username, config.Email.Password)
-
-	// Set TLS config with InsecureSkipVerify
-	d.TLSConfig = &tls.Config{
-		InsecureSkipVerify: true,
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.AccountDeletion, data)
+}
+
+// SendServiceTransferEmail asks the nominated account to accept ownership of
+// a service
+func SendServiceTransferEmail(ctx context.Context, mail Mail, to, fromEmail, serviceName, acceptLink string, linkExpiry time.Duration, config *config.Config) error {
+	data := ServiceTransferEmailData{
+		Email:       to,
+		FromEmail:   fromEmail,
+		ServiceName: serviceName,
+		AcceptLink:  acceptLink,
+		LinkExpiry:  linkExpiry.String(),
+	}
+
+	return queueTemplateEmail(ctx, mail, to, emailtemplate.ServiceTransfer, data)
+}
+
+// queueTemplateEmail renders the named template and hands the result to the
+// queue; delivery happens in the background
+func queueTemplateEmail(ctx context.Context, mail Mail, to, name string, data interface{}) error {
+	rendered, err := mail.Templates.Render(name, mail.Locale, mail.Brand, data)
+	if err != nil {
+		return err
 	}
 
-	// Send email
-	return d.DialAndSend(m)
+	return mail.Queue.Enqueue(ctx, mailer.Message{
+		To:       to,
+		Subject:  rendered.Subject,
+		HTMLBody: rendered.HTML,
+		TextBody: rendered.Text,
+	})
 }
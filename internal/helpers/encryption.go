@@ -0,0 +1,260 @@
+package helpers
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/keyprovider"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Service secrets are stored as envelopes:
+//
+//	enc:v<key version>:<wrapped data key>:<sealed secret>
+//
+// Each value gets its own random data key which seals the secret with
+// AES-256-GCM; the data key is in turn sealed with the versioned master key.
+// Values without the prefix were written by the old AES-CFB scheme.
+const envelopePrefix = "enc:v"
+
+var ErrUnknownKeyVersion = errors.New("unknown encryption key version")
+
+// deriveKey turns a configured master key string into a 32-byte AES key
+func deriveKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+func (h *JWTHelpers) loadEncryptionKeys(ctx context.Context, provider keyprovider.Provider, cfg config.JWTServiceConfig) error {
+	rawKeys, err := keyprovider.Optional(ctx, provider, keyprovider.ServiceEncryptKeys)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", keyprovider.ServiceEncryptKeys, err)
+	}
+	legacySecret, err := keyprovider.Optional(ctx, provider, keyprovider.ServiceEncryptSecret)
+	if err != nil {
+		return fmt.Errorf("loading %s: %w", keyprovider.ServiceEncryptSecret, err)
+	}
+
+	keys, err := parseKeyRing(rawKeys)
+	if err != nil {
+		return err
+	}
+	// Without an explicit key ring the legacy secret becomes key version 1
+	if len(keys) == 0 && legacySecret != "" {
+		keys = map[int]string{1: legacySecret}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no service encryption keys configured")
+	}
+
+	h.encryptionKeys = make(map[int][]byte, len(keys))
+	for version, secret := range keys {
+		h.encryptionKeys[version] = deriveKey(secret)
+		if version > h.currentKeyVersion && cfg.ServiceEncryptKeyVersion == 0 {
+			h.currentKeyVersion = version
+		}
+	}
+	if cfg.ServiceEncryptKeyVersion != 0 {
+		h.currentKeyVersion = cfg.ServiceEncryptKeyVersion
+	}
+	if _, ok := h.encryptionKeys[h.currentKeyVersion]; !ok {
+		return fmt.Errorf("%w: %d is not in the key ring", ErrUnknownKeyVersion, h.currentKeyVersion)
+	}
+
+	if legacySecret != "" {
+		h.legacyEncryptionKey = deriveKey(legacySecret)
+	}
+	return nil
+}
+
+// parseKeyRing parses "version:key" pairs separated by commas, e.g. "1:old-key,2:new-key".
+func parseKeyRing(raw string) (map[int]string, error) {
+	keys := map[int]string{}
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("malformed key ring entry")
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("malformed key ring version %q", parts[0])
+		}
+		keys[version] = parts[1]
+	}
+	return keys, nil
+}
+
+func sealGCM(key, plaintext, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher creation failed: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("gcm creation failed: %w", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func openGCM(key, sealed, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher creation failed: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("gcm creation failed: %w", err)
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+	return plaintext, nil
+}
+
+// envelopeAAD binds both layers to the key version so the prefix cannot be swapped
+func envelopeAAD(version int, layer string) []byte {
+	return []byte(fmt.Sprintf("aspire-auth:service-secret:v%d:%s", version, layer))
+}
+
+func (h *JWTHelpers) EncryptServiceSecretKey(secretKey string) (string, error) {
+	masterKey, ok := h.encryptionKeys[h.currentKeyVersion]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownKeyVersion, h.currentKeyVersion)
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	wrappedKey, err := sealGCM(masterKey, dataKey, envelopeAAD(h.currentKeyVersion, "key"))
+	if err != nil {
+		return "", err
+	}
+
+	sealedSecret, err := sealGCM(dataKey, []byte(secretKey), envelopeAAD(h.currentKeyVersion, "data"))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s%d:%s:%s",
+		envelopePrefix,
+		h.currentKeyVersion,
+		base64.RawStdEncoding.EncodeToString(wrappedKey),
+		base64.RawStdEncoding.EncodeToString(sealedSecret),
+	), nil
+}
+
+func (h *JWTHelpers) DecryptServiceSecretKey(encryptedKey string) (string, error) {
+	if !strings.HasPrefix(encryptedKey, envelopePrefix) {
+		return h.decryptLegacyServiceSecretKey(encryptedKey)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(encryptedKey, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("malformed key version: %w", err)
+	}
+	masterKey, ok := h.encryptionKeys[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+	sealedSecret, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+
+	dataKey, err := openGCM(masterKey, wrappedKey, envelopeAAD(version, "key"))
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := openGCM(dataKey, sealedSecret, envelopeAAD(version, "data"))
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// decryptLegacyServiceSecretKey reads values written by the old unauthenticated AES-CFB scheme
+func (h *JWTHelpers) decryptLegacyServiceSecretKey(encryptedKey string) (string, error) {
+	if h.legacyEncryptionKey == nil {
+		return "", fmt.Errorf("legacy encrypted value found but SERVICE_ENCRYPT_SECRET_KEY is not set")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encryptedKey)
+	if err != nil {
+		return "", fmt.Errorf("base64 decode failed: %w", err)
+	}
+
+	block, err := aes.NewCipher(h.legacyEncryptionKey)
+	if err != nil {
+		return "", fmt.Errorf("cipher creation failed: %w", err)
+	}
+
+	if len(ciphertext) < aes.BlockSize {
+		return "", fmt.Errorf("ciphertext too short")
+	}
+
+	iv := ciphertext[:aes.BlockSize]
+	ciphertext = ciphertext[aes.BlockSize:]
+
+	stream := cipher.NewCFBDecrypter(block, iv)
+	stream.XORKeyStream(ciphertext, ciphertext)
+
+	return string(ciphertext), nil
+}
+
+// ServiceSecretNeedsReencryption reports whether a stored value uses the legacy
+// scheme or a master key other than the current one.
+func (h *JWTHelpers) ServiceSecretNeedsReencryption(encryptedKey string) bool {
+	return !strings.HasPrefix(encryptedKey, fmt.Sprintf("%s%d:", envelopePrefix, h.currentKeyVersion))
+}
+
+// ReencryptServiceSecretKey decrypts a stored value with whichever key wrote it
+// and encrypts it again under the current master key.
+func (h *JWTHelpers) ReencryptServiceSecretKey(encryptedKey string) (string, error) {
+	secret, err := h.DecryptServiceSecretKey(encryptedKey)
+	if err != nil {
+		return "", err
+	}
+	return h.EncryptServiceSecretKey(secret)
+}
+
+func (h *JWTHelpers) CurrentKeyVersion() int {
+	return h.currentKeyVersion
+}
@@ -0,0 +1,225 @@
+package helpers
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/keyprovider"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mapProvider serves secrets from a map, like the file provider does.
+type mapProvider map[string]string
+
+func (p mapProvider) Name() string { return "map" }
+
+func (p mapProvider) Secret(ctx context.Context, name string) (string, error) {
+	value, ok := p[name]
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: %s", keyprovider.ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// newTestHelpers builds JWTHelpers from the token secrets plus extra.
+func newTestHelpers(t *testing.T, keyVersion int, extra map[string]string) (*JWTHelpers, error) {
+	t.Helper()
+	secrets := mapProvider{
+		keyprovider.AccountAccessTokenSecret:  "account-access",
+		keyprovider.AccountRefreshTokenSecret: "account-refresh",
+		keyprovider.ServiceAccessTokenSecret:  "service-access",
+		keyprovider.ServiceRefreshTokenSecret: "service-refresh",
+	}
+	for name, value := range extra {
+		secrets[name] = value
+	}
+	cfg := &config.Config{Keys: config.KeyProviderConfig{Timeout: time.Second}}
+	cfg.JWT.Service.ServiceEncryptKeyVersion = keyVersion
+	return InitJWTHelpers(cfg, secrets)
+}
+
+func mustHelpers(t *testing.T, keyVersion int, extra map[string]string) *JWTHelpers {
+	t.Helper()
+	h, err := newTestHelpers(t, keyVersion, extra)
+	if err != nil {
+		t.Fatalf("InitJWTHelpers: %v", err)
+	}
+	return h
+}
+
+func TestEncryptionKeyConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		keyVersion  int
+		secrets     map[string]string
+		wantVersion int
+		wantErr     error
+	}{
+		{name: "latest key by default", secrets: map[string]string{keyprovider.ServiceEncryptKeys: "1:old,3:new,2:mid"}, wantVersion: 3},
+		{name: "pinned version", keyVersion: 2, secrets: map[string]string{keyprovider.ServiceEncryptKeys: "1:old,2:mid,3:new"}, wantVersion: 2},
+		{name: "legacy secret becomes version 1", secrets: map[string]string{keyprovider.ServiceEncryptSecret: "legacy"}, wantVersion: 1},
+		{name: "pinned version missing", keyVersion: 4, secrets: map[string]string{keyprovider.ServiceEncryptKeys: "1:old"}, wantErr: ErrUnknownKeyVersion},
+		{name: "no keys", secrets: nil},
+		{name: "malformed entry", secrets: map[string]string{keyprovider.ServiceEncryptKeys: "1:old,new"}},
+		{name: "malformed version", secrets: map[string]string{keyprovider.ServiceEncryptKeys: "0:old"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := newTestHelpers(t, tt.keyVersion, tt.secrets)
+			if tt.wantVersion == 0 {
+				if err == nil {
+					t.Fatal("InitJWTHelpers() succeeded, want an error")
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("InitJWTHelpers() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InitJWTHelpers: %v", err)
+			}
+			if got := h.CurrentKeyVersion(); got != tt.wantVersion {
+				t.Fatalf("CurrentKeyVersion() = %d, want %d", got, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	h := mustHelpers(t, 0, map[string]string{keyprovider.ServiceEncryptKeys: "1:first-key"})
+
+	tests := []string{"", "service-secret", strings.Repeat("long ", 1000), "ünïcödé ✓"}
+	for _, plaintext := range tests {
+		sealed, err := h.EncryptServiceSecretKey(plaintext)
+		if err != nil {
+			t.Fatalf("Encrypt(%q): %v", plaintext, err)
+		}
+		if !strings.HasPrefix(sealed, "enc:v1:") || (plaintext != "" && strings.Contains(sealed, plaintext)) {
+			t.Fatalf("Encrypt(%q) = %q", plaintext, sealed)
+		}
+		again, _ := h.EncryptServiceSecretKey(plaintext)
+		if again == sealed {
+			t.Fatalf("Encrypt(%q) is deterministic", plaintext)
+		}
+
+		opened, err := h.DecryptServiceSecretKey(sealed)
+		if err != nil || opened != plaintext {
+			t.Fatalf("Decrypt(Encrypt(%q)) = %q, %v", plaintext, opened, err)
+		}
+	}
+}
+
+func TestEnvelopeTampering(t *testing.T) {
+	h := mustHelpers(t, 1, map[string]string{keyprovider.ServiceEncryptKeys: "1:first-key,2:second-key"})
+	sealed, err := h.EncryptServiceSecretKey("service-secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	parts := strings.Split(sealed, ":")
+
+	flip := func(encoded string) string {
+		raw, _ := base64.RawStdEncoding.DecodeString(encoded)
+		raw[len(raw)-1] ^= 1
+		return base64.RawStdEncoding.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr error
+	}{
+		{name: "relabelled key version", value: strings.Join([]string{parts[0], "v2", parts[2], parts[3]}, ":")},
+		{name: "unknown key version", value: strings.Join([]string{parts[0], "v9", parts[2], parts[3]}, ":"), wantErr: ErrUnknownKeyVersion},
+		{name: "altered data key", value: strings.Join([]string{parts[0], parts[1], flip(parts[2]), parts[3]}, ":")},
+		{name: "altered secret", value: strings.Join([]string{parts[0], parts[1], parts[2], flip(parts[3])}, ":")},
+		{name: "swapped layers", value: strings.Join([]string{parts[0], parts[1], parts[3], parts[2]}, ":")},
+		{name: "truncated", value: strings.Join(parts[:3], ":")},
+		{name: "not base64", value: strings.Join([]string{parts[0], parts[1], "!!!", parts[3]}, ":")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.DecryptServiceSecretKey(tt.value)
+			if err == nil {
+				t.Fatal("Decrypt() accepted a tampered envelope")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decrypt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvelopeRotation(t *testing.T) {
+	old := mustHelpers(t, 0, map[string]string{keyprovider.ServiceEncryptKeys: "1:first-key"})
+	sealed, err := old.EncryptServiceSecretKey("service-secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	rotated := mustHelpers(t, 0, map[string]string{keyprovider.ServiceEncryptKeys: "1:first-key,2:second-key"})
+	if !rotated.ServiceSecretNeedsReencryption(sealed) {
+		t.Fatal("a value under the old key does not need re-encryption")
+	}
+	reencrypted, err := rotated.ReencryptServiceSecretKey(sealed)
+	if err != nil {
+		t.Fatalf("Reencrypt: %v", err)
+	}
+	if !strings.HasPrefix(reencrypted, "enc:v2:") || rotated.ServiceSecretNeedsReencryption(reencrypted) {
+		t.Fatalf("Reencrypt() = %q, want it under key 2", reencrypted)
+	}
+	if opened, err := rotated.DecryptServiceSecretKey(reencrypted); err != nil || opened != "service-secret" {
+		t.Fatalf("Decrypt() = %q, %v", opened, err)
+	}
+
+	// Once key 1 is retired its values can no longer be read
+	retired := mustHelpers(t, 0, map[string]string{keyprovider.ServiceEncryptKeys: "2:second-key"})
+	if _, err := retired.DecryptServiceSecretKey(sealed); !errors.Is(err, ErrUnknownKeyVersion) {
+		t.Fatalf("Decrypt() with a retired key error = %v, want ErrUnknownKeyVersion", err)
+	}
+}
+
+func TestLegacyValues(t *testing.T) {
+	// Written by the old AES-CFB scheme
+	block, _ := aes.NewCipher(deriveKey("legacy-secret"))
+	iv := make([]byte, aes.BlockSize)
+	ciphertext := make([]byte, aes.BlockSize+len("service-secret"))
+	copy(ciphertext, iv)
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext[aes.BlockSize:], []byte("service-secret"))
+	legacy := base64.StdEncoding.EncodeToString(ciphertext)
+
+	tests := []struct {
+		name    string
+		secrets map[string]string
+		wantErr bool
+	}{
+		{name: "legacy secret configured", secrets: map[string]string{keyprovider.ServiceEncryptKeys: "1:first-key", keyprovider.ServiceEncryptSecret: "legacy-secret"}},
+		{name: "legacy secret removed", secrets: map[string]string{keyprovider.ServiceEncryptKeys: "1:first-key"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := mustHelpers(t, 0, tt.secrets)
+			if !h.ServiceSecretNeedsReencryption(legacy) {
+				t.Fatal("a legacy value does not need re-encryption")
+			}
+			opened, err := h.DecryptServiceSecretKey(legacy)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Decrypt() read a legacy value without the legacy secret")
+				}
+				return
+			}
+			if err != nil || opened != "service-secret" {
+				t.Fatalf("Decrypt() = %q, %v", opened, err)
+			}
+		})
+	}
+}
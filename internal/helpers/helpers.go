@@ -2,11 +2,10 @@ package helpers
 
 import (
 	"aspire-auth/internal/config"
+	"aspire-auth/internal/keyprovider"
 	"aspire-auth/internal/models"
-	"crypto/aes"
-	"crypto/cipher"
+	"context"
 	"crypto/rand"
-	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -23,20 +22,54 @@ type JWTHelpers struct {
 	accountRefreshSecret string
 	serviceAccessSecret  string
 	serviceRefreshSecret string
-	serviceEncryptSecret string
+
+	// Service secret encryption keys, see encryption.go
+	encryptionKeys      map[int][]byte
+	currentKeyVersion   int
+	legacyEncryptionKey []byte
+
+	// Magic link signing key, see magiclink.go
+	magicLinkKey []byte
+
+	// Download link signing key, see download.go
+	downloadKey []byte
 }
 
-func InitJWTHelpers(cfg *config.Config) *JWTHelpers {
-	helper := &JWTHelpers{
-		Config:               cfg,
-		accountAccessSecret:  cfg.JWT.Account.AccessTokenSecret,
-		accountRefreshSecret: cfg.JWT.Account.RefreshTokenSecret,
-		serviceAccessSecret:  cfg.JWT.Service.AccessTokenSecret,
-		serviceRefreshSecret: cfg.JWT.Service.RefreshTokenSecret,
-		serviceEncryptSecret: cfg.JWT.Service.ServiceEncryptSecret,
+// InitJWTHelpers loads every signing and encryption secret from the key
+// provider once at startup.
+func InitJWTHelpers(cfg *config.Config, provider keyprovider.Provider) (*JWTHelpers, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Keys.Timeout)
+	defer cancel()
+
+	helper := &JWTHelpers{Config: cfg}
+
+	required := map[string]*string{
+		keyprovider.AccountAccessTokenSecret:  &helper.accountAccessSecret,
+		keyprovider.AccountRefreshTokenSecret: &helper.accountRefreshSecret,
+		keyprovider.ServiceAccessTokenSecret:  &helper.serviceAccessSecret,
+		keyprovider.ServiceRefreshTokenSecret: &helper.serviceRefreshSecret,
+	}
+	for name, target := range required {
+		value, err := provider.Secret(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("loading %s from %s provider: %w", name, provider.Name(), err)
+		}
+		*target = value
+	}
+
+	if err := helper.loadEncryptionKeys(ctx, provider, cfg.JWT.Service); err != nil {
+		return nil, err
+	}
+
+	if err := helper.loadMagicLinkKey(ctx, provider); err != nil {
+		return nil, err
 	}
 
-	return helper
+	if err := helper.loadDownloadKey(ctx, provider); err != nil {
+		return nil, err
+	}
+
+	return helper, nil
 }
 
 // COMMON HELPERS
@@ -47,7 +80,6 @@ func GenerateJWT(data *jwt.MapClaims, secretKey []byte) (string, error) {
 }
 
 func ParseJWT(tokenString string, claims jwt.Claims, secretKey []byte) (*jwt.Token, error) {
-	fmt.Printf("Debug - Secret key used for parsing: %s\n", string(secretKey)) // Debug log
 	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -67,7 +99,6 @@ func TokenModelToClaims(data *models.AccountRefreshToken) *jwt.MapClaims {
 }
 
 func (h *JWTHelpers) GenerateAccountAccessToken(data *models.AccountRefreshToken) (string, error) {
-	fmt.Printf("Debug - Generating account token with secret: %s\n", h.accountAccessSecret) // Debug log
 	claims := TokenModelToClaims(data)
 	return GenerateJWT(claims, []byte(h.accountAccessSecret))
 }
@@ -78,7 +109,6 @@ func (h *JWTHelpers) GenerateAccountRefreshToken(data *models.AccountRefreshToke
 }
 
 func (h *JWTHelpers) ParseAccountAccessToken(tokenString string, claims *models.AccountAuthorizationToken) error {
-	fmt.Printf("Debug - Using Account Access Token Secret: %s\n", h.accountAccessSecret) // Debug log
 	token, err := ParseJWT(tokenString, claims, []byte(h.accountAccessSecret))
 	if err != nil {
 		return err
@@ -117,61 +147,17 @@ func ServiceSecretKeyToClaims(secretKey string) *jwt.MapClaims {
 	}
 }
 
-// getEncryptionKey creates a valid 32-byte AES key from any string input
-func (h *JWTHelpers) getEncryptionKey() []byte {
-	// Use SHA-256 to get a consistent 32-byte key from the secret
-	hasher := sha256.New()
-	hasher.Write([]byte(h.serviceEncryptSecret))
-	return hasher.Sum(nil) // 32 bytes (256 bits)
-}
+// MinServiceSecretLength is the shortest owner-supplied service secret we accept.
+const MinServiceSecretLength = 16
 
-func (h *JWTHelpers) EncryptServiceSecretKey(secretKey string) (string, error) {
-	// Use a proper 32-byte key derived from your secret
-	key := h.getEncryptionKey()
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", fmt.Errorf("cipher creation failed: %w", err)
-	}
-
-	// IV needs to be unique, but not secure
-	ciphertext := make([]byte, aes.BlockSize+len(secretKey))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return "", fmt.Errorf("failed to generate IV: %w", err)
+// GenerateServiceSecret returns a new high-entropy service signing secret
+// (256 random bits, URL-safe base64 encoded).
+func GenerateServiceSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", fmt.Errorf("failed to generate service secret: %w", err)
 	}
-
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], []byte(secretKey))
-
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
-}
-
-func (h *JWTHelpers) DecryptServiceSecretKey(encryptedKey string) (string, error) {
-	// Use a proper 32-byte key derived from your secret
-	key := h.getEncryptionKey()
-
-	ciphertext, err := base64.StdEncoding.DecodeString(encryptedKey)
-	if err != nil {
-		return "", fmt.Errorf("base64 decode failed: %w", err)
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", fmt.Errorf("cipher creation failed: %w", err)
-	}
-
-	if len(ciphertext) < aes.BlockSize {
-		return "", fmt.Errorf("ciphertext too short")
-	}
-
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-	stream.XORKeyStream(ciphertext, ciphertext)
-
-	return string(ciphertext), nil
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
 func (h *JWTHelpers) GenerateServiceEncryptToken(secretKey string) (string, error) {
@@ -189,7 +175,6 @@ func (h *JWTHelpers) GenerateServiceRefreshTokenWithSecret(data *models.ServiceR
 }
 
 func (h *JWTHelpers) GenerateServiceAccessToken(data *models.ServiceRefreshToken) (string, error) {
-	fmt.Printf("Debug - Generating service token with secret: %s\n", h.serviceAccessSecret) // Debug log
 	claims := ServiceTokenModelToClaims(data)
 	return GenerateJWT(claims, []byte(h.serviceAccessSecret))
 }
@@ -210,82 +195,11 @@ func (h *JWTHelpers) ParseServiceAccessTokenWithSecret(tokenString string, claim
 	return nil
 }
 
-// Add this new function to help with debugging
-func (h *JWTHelpers) DebugTokenType(tokenString string) string {
-	parts := strings.Split(tokenString, ".")
-	if len(parts) != 3 {
-		return "INVALID_TOKEN_FORMAT"
-	}
-
-	// Parse token details without validation
-	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
-	parsedToken, _, err := parser.ParseUnverified(tokenString, jwt.MapClaims{})
-	if err != nil {
-		return "PARSE_ERROR"
-	}
-
-	if mapClaims, ok := parsedToken.Claims.(jwt.MapClaims); ok {
-		if _, hasServiceID := mapClaims["service_id"]; hasServiceID {
-			return "SERVICE_TOKEN"
-		} else if _, hasUserID := mapClaims["user_id"]; hasUserID {
-			return "ACCOUNT_TOKEN"
-		}
-	}
-
-	return "UNKNOWN_TOKEN_TYPE"
-}
-
-// Update ParseServiceAccessToken to use the proper fallback approach only when needed
 func (h *JWTHelpers) ParseServiceAccessToken(tokenString string, claims jwt.Claims) error {
-	// Extract and print token payload for debugging
-	tokenType := h.DebugTokenType(tokenString)
-	fmt.Printf("Token identified as: %s\n", tokenType)
-
-	// Extract and print token payload for debugging
-	parts := strings.Split(tokenString, ".")
-	if len(parts) == 3 {
-		fmt.Printf("Token parts count: %d\n", len(parts))
-		fmt.Printf("Token payload (encoded): %s\n", parts[1])
-
-		// Parse token details without validation to see what's in it
-		parser := jwt.NewParser(jwt.WithoutClaimsValidation())
-		parsedToken, _, err := parser.ParseUnverified(tokenString, jwt.MapClaims{})
-		if err == nil {
-			if mapClaims, ok := parsedToken.Claims.(jwt.MapClaims); ok {
-				fmt.Printf("Token claims: %v\n", mapClaims)
-
-				// If we have a service_id claim, we should try to use that service's secret
-				if serviceID, ok := mapClaims["service_id"].(string); ok {
-					// This would normally query the database to get the service's encrypted secret
-					// But since we can't add that here, we'll just log it
-					fmt.Printf("Should validate with service-specific secret for service ID: %s\n", serviceID)
-					// In a real implementation, you would:
-					// 1. Get the encrypted secret from the database using serviceID
-					// 2. Decrypt it
-					// 3. Use it to validate the token
-				}
-			}
-		}
-	}
-
-	fmt.Printf("Debug - Using Service Access Token Secret: %s\n", h.serviceAccessSecret)
-
-	// First try with the normal service access token secret
 	token, err := ParseJWT(tokenString, claims, []byte(h.serviceAccessSecret))
 	if err != nil {
-		// If the error is specifically about the signature being invalid
-		if strings.Contains(err.Error(), "signature is invalid") {
-			// Try with the account access token secret as a fallback
-			fmt.Printf("Warning: Service token validation failed with service secret, trying with account secret\n")
-			accountToken, accountErr := ParseJWT(tokenString, claims, []byte(h.accountAccessSecret))
-			if accountErr == nil && accountToken.Valid {
-				fmt.Printf("Warning: Service token validated with ACCOUNT secret - this indicates a middleware routing issue\n")
-				return nil
-			}
-		}
 		return err
 	}
-
 	if !token.Valid {
 		return jwt.ErrSignatureInvalid
 	}
@@ -318,15 +232,6 @@ func (h *JWTHelpers) ExtractToken(authorization string) string {
 	return authorization
 }
 
-// Get secret keys for debugging
-func (h *JWTHelpers) GetServiceAccessSecret() string {
-	return h.serviceAccessSecret
-}
-
-func (h *JWTHelpers) GetAccountAccessSecret() string {
-	return h.accountAccessSecret
-}
-
 func FormatTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
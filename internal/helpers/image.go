@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"aspire-auth/internal/imaging"
+	"aspire-auth/internal/storage"
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ImageVariants returns the sizes images under directory are stored in, or
+// false for directories whose images have a single size.
+func ImageVariants(directory string) ([]imaging.Variant, bool) {
+	switch directory {
+	case AvatarPrefix:
+		return AvatarVariants, true
+	case LogoPrefix:
+		return LogoVariants, true
+	default:
+		return nil, false
+	}
+}
+
+// imageKey returns the storage key of a variant of filename under prefix.
+func imageKey(prefix string, variants []imaging.Variant, filename, variant string) (string, error) {
+	return storage.Key(prefix, imaging.VariantName(filename, variants, variant))
+}
+
+// saveImage stores every variant of a processed image under prefix and
+// returns the new file name.
+func saveImage(ctx context.Context, store storage.Store, prefix string, variants []imaging.Variant, result *imaging.Result) (string, error) {
+	filename := uuid.New().String() + result.Ext
+	for _, img := range result.Images {
+		key, err := imageKey(prefix, variants, filename, img.Variant)
+		if err != nil {
+			return "", err
+		}
+		if err := store.Put(ctx, key, bytes.NewReader(img.Data), result.ContentType); err != nil {
+			deleteImage(ctx, store, prefix, variants, filename)
+			return "", fmt.Errorf("failed to save image: %w", err)
+		}
+	}
+	return filename, nil
+}
+
+// deleteImage removes filename and all of its variants from under prefix.
+func deleteImage(ctx context.Context, store storage.Store, prefix string, variants []imaging.Variant, filename string) error {
+	for _, variant := range variants {
+		key, err := imageKey(prefix, variants, filename, variant.Name)
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/imaging"
+	"aspire-auth/internal/storage"
+	"context"
+	"strings"
+)
+
+// LogoPrefix is the storage key prefix, and image directory, of service logos.
+const LogoPrefix = "logos"
+
+// logoPathPrefix is how Service.ServiceLogo refers to a logo hosted here.
+// It is relative so it follows the public URL the server is reached on.
+const logoPathPrefix = "/images/" + LogoPrefix + "/"
+
+// LogoVariants are the sizes every uploaded logo is stored in. Logos keep
+// their aspect ratio; Size bounds the longest side.
+var LogoVariants = []imaging.Variant{
+	{Name: "large", Size: 512},
+	{Name: "small", Size: 128},
+}
+
+// LogoOptions returns the processing options for service logo uploads.
+func LogoOptions(cfg config.LogoConfig) imaging.Options {
+	return imaging.Options{
+		MaxBytes:     cfg.MaxBytes,
+		MinDimension: cfg.MinDimension,
+		MaxDimension: cfg.MaxDimension,
+		Variants:     LogoVariants,
+	}
+}
+
+// LogoPath returns the ServiceLogo value for an uploaded logo.
+func LogoPath(filename string) string {
+	return logoPathPrefix + filename
+}
+
+// HostedLogo returns the file name of logo if it refers to a logo uploaded
+// here rather than an external URL.
+func HostedLogo(logo *string) (string, bool) {
+	if logo == nil {
+		return "", false
+	}
+	filename, found := strings.CutPrefix(*logo, logoPathPrefix)
+	if !found || !IsValidImagePath(LogoPrefix, filename) {
+		return "", false
+	}
+	return filename, true
+}
+
+// SaveLogo stores every variant of a processed logo and returns its file name.
+func SaveLogo(ctx context.Context, store storage.Store, result *imaging.Result) (string, error) {
+	return saveImage(ctx, store, LogoPrefix, LogoVariants, result)
+}
+
+// DeleteLogo removes a logo and all of its variants.
+func DeleteLogo(ctx context.Context, store storage.Store, filename string) error {
+	return deleteImage(ctx, store, LogoPrefix, LogoVariants, filename)
+}
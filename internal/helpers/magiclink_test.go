@@ -0,0 +1,49 @@
+package helpers
+
+import (
+	"aspire-auth/internal/keyprovider"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestSignMagicLink(t *testing.T) {
+	payload := []byte("payload")
+	helpers := func(t *testing.T, extra map[string]string) *JWTHelpers {
+		secrets := map[string]string{keyprovider.ServiceEncryptKeys: "1:key"}
+		for name, value := range extra {
+			secrets[name] = value
+		}
+		return mustHelpers(t, 0, secrets)
+	}
+	fallback := helpers(t, nil).SignMagicLink(payload)
+
+	tests := []struct {
+		name     string
+		secrets  map[string]string
+		payload  []byte
+		wantSame bool
+	}{
+		{name: "deterministic", payload: payload, wantSame: true},
+		{name: "other payload", payload: []byte("payload2")},
+		{name: "dedicated secret", secrets: map[string]string{keyprovider.MagicLinkSecret: "magic"}, payload: payload},
+		{name: "other refresh secret", secrets: map[string]string{keyprovider.AccountRefreshTokenSecret: "rotated"}, payload: payload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := helpers(t, tt.secrets).SignMagicLink(tt.payload)
+			if same := bytes.Equal(got, fallback); same != tt.wantSame {
+				t.Fatalf("signature equal to fallback = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+
+	// The fallback key is derived, never the refresh token secret itself
+	mac := hmac.New(sha256.New, []byte("account-refresh"))
+	mac.Write(payload)
+	if bytes.Equal(fallback, mac.Sum(nil)) {
+		t.Fatal("magic link signed with the raw refresh token secret")
+	}
+}
@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/sms"
+	"context"
+	"fmt"
+)
+
+// EmailOTPSender sends codes with the email template for their purpose.
+type EmailOTPSender struct {
+	Mail   Mail
+	Config *config.Config
+}
+
+func (s *EmailOTPSender) Channel() otp.Channel { return otp.ChannelEmail }
+
+func (s *EmailOTPSender) Send(ctx context.Context, d otp.Delivery) error {
+	switch d.Purpose {
+	case otp.PurposeAccountVerification:
+		return SendVerificationEmail(ctx, s.Mail, d.Email, d.Code, d.Link, s.Config)
+	case otp.PurposePasswordReset:
+		return SendPasswordResetEmail(ctx, s.Mail, d.Email, d.Code, d.TTL, s.Config)
+	case otp.PurposeEmailChange:
+		return SendEmailChangeCodeEmail(ctx, s.Mail, d.Email, d.Code, d.TTL, s.Config)
+	case otp.PurposeMFA:
+		return SendSignInCodeEmail(ctx, s.Mail, d.Email, d.Code, d.TTL, s.Config)
+	default:
+		return fmt.Errorf("no email for %s codes", d.Purpose)
+	}
+}
+
+// SMSOTPSender sends codes as a text message in the recipient's language.
+type SMSOTPSender struct {
+	SMS    sms.Sender
+	Mail   Mail
+	Config *config.Config
+}
+
+func (s *SMSOTPSender) Channel() otp.Channel { return otp.ChannelSMS }
+
+func (s *SMSOTPSender) Send(ctx context.Context, d otp.Delivery) error {
+	if d.Phone == "" {
+		return otp.ErrNoPhoneNumber
+	}
+
+	brand := s.Mail.Brand.Name
+	if brand == "" {
+		brand = s.Mail.Templates.Brand().Name
+	}
+	body := s.Mail.Templates.Translate(s.Mail.Locale, "sms."+string(d.Purpose), brand, d.Code, d.TTL.String())
+	return s.SMS.Send(ctx, d.Phone, body)
+}
@@ -0,0 +1,155 @@
+package helpers
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/mfa"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/sms"
+	"aspire-auth/internal/sms/smsfake"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var smsCodePattern = regexp.MustCompile(`\b[0-9]{6}\b`)
+
+// newSMSSender wires an SMSOTPSender to an in-process fake gateway.
+func newSMSSender(t *testing.T, locale string, brand emailtemplate.Brand) (*SMSOTPSender, *smsfake.Gateway) {
+	t.Helper()
+	templates, err := emailtemplate.New(config.EmailConfig{DefaultLocale: "en", BrandName: "Aspire Auth"})
+	if err != nil {
+		t.Fatalf("emailtemplate.New: %v", err)
+	}
+
+	gateway := smsfake.New("", 10)
+	server := httptest.NewServer(gateway)
+	t.Cleanup(server.Close)
+
+	sender, err := sms.NewHTTPSender(config.SMSConfig{HTTPURL: server.URL, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewHTTPSender: %v", err)
+	}
+	return &SMSOTPSender{SMS: sender, Mail: Mail{Templates: templates, Locale: locale, Brand: brand}}, gateway
+}
+
+func TestSMSOTPSender(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   string
+		brand    emailtemplate.Brand
+		phone    string
+		wantText string
+		wantErr  error
+	}{
+		{name: "default brand", locale: "en", phone: "+14155550123", wantText: "Aspire Auth: your sign-in code is 123456"},
+		{name: "service brand", locale: "en", brand: emailtemplate.Brand{Name: "Acme"}, phone: "+14155550123", wantText: "Acme: your sign-in code is 123456"},
+		{name: "recipient language", locale: "es", phone: "+14155550123", wantText: "tu código de inicio de sesión es 123456"},
+		{name: "unsupported language falls back", locale: "fr", phone: "+14155550123", wantText: "your sign-in code is 123456"},
+		{name: "no phone number", locale: "en", wantErr: otp.ErrNoPhoneNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender, gateway := newSMSSender(t, tt.locale, tt.brand)
+
+			err := sender.Send(context.Background(), otp.Delivery{Purpose: otp.PurposeMFA, Code: "123456", TTL: 5 * time.Minute, Phone: tt.phone})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			messages := gateway.Messages()
+			if len(messages) != 1 || messages[0].To != tt.phone {
+				t.Fatalf("gateway received %+v", messages)
+			}
+			if !strings.Contains(messages[0].Text, tt.wantText) {
+				t.Fatalf("text %q does not contain %q", messages[0].Text, tt.wantText)
+			}
+		})
+	}
+}
+
+// TestMFAChallengeOverSMS runs a sign-in challenge end to end: the code goes
+// out through the gateway and is read back from it to finish the challenge.
+func TestMFAChallengeOverSMS(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func(code string) string
+		wantVerify error
+		wantDone   bool
+	}{
+		{name: "code from the message", tamper: func(code string) string { return code }, wantDone: true},
+		{name: "wrong code", tamper: func(code string) string { return strings.Repeat("0", len(code)-1) + "x" }, wantVerify: otp.ErrInvalidCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			t.Cleanup(func() { client.Close() })
+
+			codes := otp.NewService(client, config.OTPConfig{Length: 6, TTL: 5 * time.Minute, MaxAttempts: 3})
+			challenges := mfa.NewStore(client, config.MFAConfig{ChallengeTTL: 5 * time.Minute})
+			sender, gateway := newSMSSender(t, "en", emailtemplate.Brand{})
+			accountID := uuid.New()
+
+			code, err := codes.Generate(ctx, otp.PurposeMFA, accountID.String())
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			token, err := challenges.CreateChallenge(ctx, accountID, nil)
+			if err != nil {
+				t.Fatalf("CreateChallenge: %v", err)
+			}
+			if err := sender.Send(ctx, otp.Delivery{Purpose: otp.PurposeMFA, Code: code, TTL: 5 * time.Minute, Phone: "+14155550123"}); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			messages := gateway.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("gateway has %d messages, want 1", len(messages))
+			}
+			received := smsCodePattern.FindString(messages[0].Text)
+			if received != code {
+				t.Fatalf("message %q does not carry the code", messages[0].Text)
+			}
+
+			challenge, err := challenges.Challenge(ctx, token)
+			if err != nil || challenge.AccountID != accountID {
+				t.Fatalf("Challenge() = %+v, %v", challenge, err)
+			}
+
+			err = codes.Verify(ctx, otp.PurposeMFA, accountID.String(), tt.tamper(received))
+			if !errors.Is(err, tt.wantVerify) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantVerify)
+			}
+			if err != nil {
+				return
+			}
+
+			done, err := challenges.Complete(ctx, token)
+			if err != nil || done != tt.wantDone {
+				t.Fatalf("Complete() = %v, %v, want %v", done, err, tt.wantDone)
+			}
+			// A finished challenge cannot be replayed
+			if done, _ := challenges.Complete(ctx, token); done {
+				t.Fatal("challenge completed twice")
+			}
+		})
+	}
+}
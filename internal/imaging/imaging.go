@@ -0,0 +1,145 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrTooLarge        = errors.New("image exceeds the maximum size")
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+	ErrDimensions      = errors.New("image dimensions are out of range")
+	ErrInvalidImage    = errors.New("image could not be decoded")
+)
+
+// jpegQuality balances size against artifacts for photos shown at avatar size.
+const jpegQuality = 85
+
+// Variant is one rendition produced from an upload; Size is the length of
+// its longest side. The first variant is the default.
+type Variant struct {
+	Name string
+	Size int
+}
+
+// Options bounds what Process accepts and describes what it produces.
+type Options struct {
+	MaxBytes     int64
+	MinDimension int
+	MaxDimension int
+	// Square crops the image to its centred square before resizing
+	Square   bool
+	Variants []Variant
+}
+
+// Image is an encoded variant.
+type Image struct {
+	Variant string
+	Data    []byte
+}
+
+// Result holds every variant in a single format. Ext and ContentType apply
+// to all of them.
+type Result struct {
+	Ext         string
+	ContentType string
+	Images      []Image
+}
+
+// Process validates an uploaded image by its content rather than its name,
+// then decodes and re-encodes it into each variant. Re-encoding drops EXIF
+// and any other embedded metadata; the orientation it carried is applied to
+// the pixels first.
+func Process(data []byte, opts Options) (*Result, error) {
+	if opts.MaxBytes > 0 && int64(len(data)) > opts.MaxBytes {
+		return nil, ErrTooLarge
+	}
+
+	var result Result
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		result.Ext, result.ContentType = ".jpg", "image/jpeg"
+	case "image/png", "image/gif":
+		// GIFs keep only their first frame, and PNG keeps transparency
+		result.Ext, result.ContentType = ".png", "image/png"
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	// Check the header before decoding so a small file cannot claim huge dimensions
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width < opts.MinDimension || config.Height < opts.MinDimension ||
+		(opts.MaxDimension > 0 && (config.Width > opts.MaxDimension || config.Height > opts.MaxDimension)) {
+		return nil, ErrDimensions
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	src := toRGBA(decoded)
+	if result.ContentType == "image/jpeg" {
+		src = orient(src, jpegOrientation(data))
+	}
+	if opts.Square {
+		src = cropSquare(src)
+	}
+
+	for _, variant := range opts.Variants {
+		resized := fit(src, variant.Size)
+
+		var buf bytes.Buffer
+		if result.ContentType == "image/jpeg" {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: jpegQuality})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Images = append(result.Images, Image{Variant: variant.Name, Data: buf.Bytes()})
+	}
+	return &result, nil
+}
+
+// VariantName returns the file name of a variant of the image stored as
+// filename. The default variant, or an empty name, is filename itself.
+func VariantName(filename string, variants []Variant, variant string) string {
+	if variant == "" || len(variants) == 0 || variant == variants[0].Name {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "_" + variant + ext
+}
+
+// HasVariant reports whether variants includes one called name.
+func HasVariant(variants []Variant, name string) bool {
+	for _, variant := range variants {
+		if variant.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
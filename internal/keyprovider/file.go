@@ -0,0 +1,165 @@
+package keyprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// keystoreFile is the on-disk format of an encrypted keystore. The secrets
+// map is JSON encoded, then sealed with AES-256-GCM under a key derived from
+// the passphrase with scrypt.
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Salt       []byte `json:"salt"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+const (
+	keystoreVersion = 1
+	scryptN         = 1 << 15
+	scryptR         = 8
+	scryptP         = 1
+)
+
+// FileProvider serves secrets from an encrypted keystore file. The file is
+// decrypted once when the provider is created.
+type FileProvider struct {
+	secrets map[string]string
+}
+
+func NewFileProvider(path, passphraseFile string) (*FileProvider, error) {
+	if path == "" || passphraseFile == "" {
+		return nil, fmt.Errorf("file key provider requires KEYSTORE_PATH and KEYSTORE_PASSPHRASE_FILE")
+	}
+
+	passphrase, err := ReadPassphraseFile(passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	secrets, err := OpenKeystore(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileProvider{secrets: secrets}, nil
+}
+
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+func (p *FileProvider) Secret(ctx context.Context, name string) (string, error) {
+	value, ok := p.secrets[name]
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// ReadPassphraseFile reads a passphrase, ignoring a trailing newline.
+func ReadPassphraseFile(path string) ([]byte, error) {
+	passphrase, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore passphrase: %w", err)
+	}
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("keystore passphrase is empty")
+	}
+	return passphrase, nil
+}
+
+// SealKeystore encrypts a set of secrets into the keystore file format.
+func SealKeystore(secrets map[string]string, passphrase []byte) ([]byte, error) {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := keystoreFile{
+		Version: keystoreVersion,
+		KDF:     "scrypt",
+		Salt:    make([]byte, 16),
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+	}
+	if _, err := io.ReadFull(rand.Reader, ks.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := keystoreCipher(passphrase, &ks)
+	if err != nil {
+		return nil, err
+	}
+
+	ks.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, ks.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ks.Ciphertext = gcm.Seal(nil, ks.Nonce, plaintext, nil)
+
+	return json.MarshalIndent(ks, "", "  ")
+}
+
+// OpenKeystore decrypts a keystore produced by SealKeystore.
+func OpenKeystore(data, passphrase []byte) (map[string]string, error) {
+	var ks keystoreFile
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("invalid keystore format: %w", err)
+	}
+	if ks.Version != keystoreVersion || ks.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported keystore version %d (%s)", ks.Version, ks.KDF)
+	}
+
+	gcm, err := keystoreCipher(passphrase, &ks)
+	if err != nil {
+		return nil, err
+	}
+	if len(ks.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid keystore nonce")
+	}
+
+	plaintext, err := gcm.Open(nil, ks.Nonce, ks.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore (wrong passphrase?): %w", err)
+	}
+
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("invalid keystore contents: %w", err)
+	}
+	return secrets, nil
+}
+
+func keystoreCipher(passphrase []byte, ks *keystoreFile) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, ks.Salt, ks.N, ks.R, ks.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("key derivation failed: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cipher creation failed: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
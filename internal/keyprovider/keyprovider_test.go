@@ -0,0 +1,235 @@
+package keyprovider
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeystore(t *testing.T) {
+	secrets := map[string]string{ServiceEncryptKeys: "1:old,2:new", MagicLinkSecret: "magic"}
+	sealed, err := SealKeystore(secrets, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("SealKeystore: %v", err)
+	}
+	if strings.Contains(string(sealed), "magic") {
+		t.Fatal("keystore contains a plaintext secret")
+	}
+
+	tamper := func(field string) []byte {
+		var ks map[string]interface{}
+		json.Unmarshal(sealed, &ks)
+		switch field {
+		case "version":
+			ks["version"] = 2
+		case "ciphertext":
+			ks["ciphertext"] = "AAAA" + ks["ciphertext"].(string)[4:]
+		}
+		data, _ := json.Marshal(ks)
+		return data
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passphrase string
+		wantErr    bool
+	}{
+		{name: "correct passphrase", data: sealed, passphrase: "correct horse"},
+		{name: "wrong passphrase", data: sealed, passphrase: "battery staple", wantErr: true},
+		{name: "altered ciphertext", data: tamper("ciphertext"), passphrase: "correct horse", wantErr: true},
+		{name: "unknown version", data: tamper("version"), passphrase: "correct horse", wantErr: true},
+		{name: "not a keystore", data: []byte("{"), passphrase: "correct horse", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := OpenKeystore(tt.data, []byte(tt.passphrase))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("OpenKeystore() succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OpenKeystore: %v", err)
+			}
+			if len(opened) != len(secrets) || opened[MagicLinkSecret] != "magic" {
+				t.Fatalf("OpenKeystore() = %v", opened)
+			}
+		})
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	sealed, err := SealKeystore(map[string]string{MagicLinkSecret: "magic", DownloadURLSecret: ""}, []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("SealKeystore: %v", err)
+	}
+	keystore := filepath.Join(dir, "keystore.json")
+	passphrase := filepath.Join(dir, "passphrase")
+	os.WriteFile(keystore, sealed, 0600)
+	// A trailing newline from an editor is ignored
+	os.WriteFile(passphrase, []byte("passphrase\n"), 0600)
+
+	p, err := New(config.KeyProviderConfig{Backend: "file", KeystorePath: keystore, KeystorePassphraseFile: passphrase})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: MagicLinkSecret, want: "magic"},
+		{name: DownloadURLSecret, wantErr: ErrSecretNotFound},
+		{name: ServiceEncryptKeys, wantErr: ErrSecretNotFound},
+	}
+	for _, tt := range tests {
+		got, err := p.Secret(context.Background(), tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("Secret(%s) = %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if value, err := Optional(context.Background(), p, ServiceEncryptKeys); value != "" || err != nil {
+		t.Fatalf("Optional() = %q, %v, want an empty value", value, err)
+	}
+}
+
+// fakeTransit answers Vault Transit decrypt calls for "vault:v1:<plaintext base64>".
+func fakeTransit(t *testing.T, token string, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		if r.URL.Path != "/v1/transit/decrypt/aspire" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Ciphertext string `json:"ciphertext"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]string{"plaintext": strings.TrimPrefix(body.Ciphertext, "vault:v1:")},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultTransitProvider(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		secret    string
+		want      string
+		wantErr   string
+		wantCalls int32
+	}{
+		// "bWFnaWM=" is "magic"
+		{name: "decrypts", token: "root", secret: MagicLinkSecret, want: "magic", wantCalls: 1},
+		{name: "not a transit ciphertext", token: "root", secret: DownloadURLSecret, wantErr: "not a vault transit ciphertext"},
+		{name: "missing", token: "root", secret: ServiceEncryptKeys, wantErr: ErrSecretNotFound.Error()},
+		{name: "bad token", token: "other", secret: MagicLinkSecret, wantErr: "permission denied", wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := fakeTransit(t, "root", &calls)
+
+			dir := t.TempDir()
+			tokenFile := filepath.Join(dir, "token")
+			secretsFile := filepath.Join(dir, "secrets.json")
+			os.WriteFile(tokenFile, []byte(tt.token+"\n"), 0600)
+			os.WriteFile(secretsFile, []byte(`{"MAGIC_LINK_SECRET_KEY": "vault:v1:bWFnaWM=", "DOWNLOAD_URL_SECRET_KEY": "plain"}`), 0600)
+
+			p, err := New(config.KeyProviderConfig{
+				Backend:          "vault",
+				Timeout:          5 * time.Second,
+				VaultAddress:     server.URL + "/",
+				VaultTokenFile:   tokenFile,
+				VaultMount:       "/transit/",
+				VaultKeyName:     "aspire",
+				VaultSecretsFile: secretsFile,
+			})
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			// The second lookup is served from the cache
+			for i := 0; i < 2; i++ {
+				got, err := p.Secret(context.Background(), tt.secret)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("Secret() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil || got != tt.want {
+					t.Fatalf("Secret() = %q, %v, want %q", got, err, tt.want)
+				}
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("vault was called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		cfg     config.KeyProviderConfig
+		want    string
+		wantErr bool
+	}{
+		{cfg: config.KeyProviderConfig{}, want: "env"},
+		{cfg: config.KeyProviderConfig{Backend: "env"}, want: "env"},
+		{cfg: config.KeyProviderConfig{Backend: "file"}, wantErr: true},
+		{cfg: config.KeyProviderConfig{Backend: "vault"}, wantErr: true},
+		{cfg: config.KeyProviderConfig{Backend: "kms"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		p, err := New(tt.cfg)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("New(%q) succeeded", tt.cfg.Backend)
+			}
+			continue
+		}
+		if err != nil || p.Name() != tt.want {
+			t.Errorf("New(%q) = %v, %v, want %s", tt.cfg.Backend, p, err, tt.want)
+		}
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	t.Setenv(MagicLinkSecret, "from-env")
+	t.Setenv(DownloadURLSecret, "")
+
+	p := NewEnvProvider()
+	if got, err := p.Secret(context.Background(), MagicLinkSecret); got != "from-env" || err != nil {
+		t.Fatalf("Secret() = %q, %v", got, err)
+	}
+	if _, err := p.Secret(context.Background(), DownloadURLSecret); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("Secret() of an empty variable error = %v, want ErrSecretNotFound", err)
+	}
+}
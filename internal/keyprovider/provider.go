@@ -0,0 +1,60 @@
+package keyprovider
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Names of the secrets aspire-auth needs. They match the environment
+// variable names used by the env backend.
+const (
+	AccountAccessTokenSecret  = "ACCOUNT_ACCESS_TOKEN_SECRET_KEY"
+	AccountRefreshTokenSecret = "ACCOUNT_REFRESH_TOKEN_SECRET_KEY"
+	ServiceAccessTokenSecret  = "SERVICE_ACCESS_TOKEN_SECRET_KEY"
+	ServiceRefreshTokenSecret = "SERVICE_REFRESH_TOKEN_SECRET_KEY"
+	// ServiceEncryptKeys is a key ring of "version:key" pairs, e.g. "1:old,2:new"
+	ServiceEncryptKeys = "SERVICE_ENCRYPT_KEYS"
+	// ServiceEncryptSecret is the legacy single key used by the old AES-CFB scheme
+	ServiceEncryptSecret = "SERVICE_ENCRYPT_SECRET_KEY"
+	// MagicLinkSecret signs emailed verification and sign-in links. When
+	// unset a key is derived from the account refresh token secret.
+	MagicLinkSecret = "MAGIC_LINK_SECRET_KEY"
+	// DownloadURLSecret signs short-lived download links. When unset a key
+	// is derived from the account refresh token secret.
+	DownloadURLSecret = "DOWNLOAD_URL_SECRET_KEY"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Provider resolves named master secrets.
+type Provider interface {
+	// Name identifies the backend in logs
+	Name() string
+	// Secret returns the named secret or ErrSecretNotFound
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// New builds the provider selected by cfg.Backend.
+func New(cfg config.KeyProviderConfig) (Provider, error) {
+	switch cfg.Backend {
+	case "", "env":
+		return NewEnvProvider(), nil
+	case "file":
+		return NewFileProvider(cfg.KeystorePath, cfg.KeystorePassphraseFile)
+	case "vault":
+		return NewVaultTransitProvider(cfg)
+	default:
+		return nil, fmt.Errorf("unknown key provider backend %q", cfg.Backend)
+	}
+}
+
+// Optional fetches a secret, treating a missing one as empty.
+func Optional(ctx context.Context, p Provider, name string) (string, error) {
+	value, err := p.Secret(ctx, name)
+	if errors.Is(err, ErrSecretNotFound) {
+		return "", nil
+	}
+	return value, err
+}
@@ -0,0 +1,166 @@
+package keyprovider
+
+import (
+	"aspire-auth/internal/config"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// VaultTransitProvider keeps secrets as Vault Transit ciphertexts
+// ("vault:v1:...") and decrypts them through the Transit HTTP API, so the
+// process only ever sees the plaintext in memory. Ciphertexts come from
+// VAULT_SECRETS_FILE (a JSON object of name -> ciphertext) or, failing
+// that, from an environment variable of the same name.
+type VaultTransitProvider struct {
+	address     string
+	token       string
+	namespace   string
+	mount       string
+	keyName     string
+	ciphertexts map[string]string
+	client      *http.Client
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func NewVaultTransitProvider(cfg config.KeyProviderConfig) (*VaultTransitProvider, error) {
+	if cfg.VaultTokenFile == "" {
+		return nil, fmt.Errorf("vault key provider requires VAULT_TOKEN_FILE")
+	}
+	token, err := os.ReadFile(cfg.VaultTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault token: %w", err)
+	}
+
+	ciphertexts := map[string]string{}
+	if cfg.VaultSecretsFile != "" {
+		data, err := os.ReadFile(cfg.VaultSecretsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vault secrets file: %w", err)
+		}
+		if err := json.Unmarshal(data, &ciphertexts); err != nil {
+			return nil, fmt.Errorf("invalid vault secrets file: %w", err)
+		}
+	}
+
+	return &VaultTransitProvider{
+		address:     strings.TrimRight(cfg.VaultAddress, "/"),
+		token:       strings.TrimSpace(string(token)),
+		namespace:   cfg.VaultNamespace,
+		mount:       strings.Trim(cfg.VaultMount, "/"),
+		keyName:     cfg.VaultKeyName,
+		ciphertexts: ciphertexts,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		cache:       map[string]string{},
+	}, nil
+}
+
+func (p *VaultTransitProvider) Name() string {
+	return "vault"
+}
+
+func (p *VaultTransitProvider) Secret(ctx context.Context, name string) (string, error) {
+	p.mu.Lock()
+	if value, ok := p.cache[name]; ok {
+		p.mu.Unlock()
+		return value, nil
+	}
+	p.mu.Unlock()
+
+	ciphertext, ok := p.ciphertexts[name]
+	if !ok {
+		ciphertext = os.Getenv(name)
+	}
+	if ciphertext == "" {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if !strings.HasPrefix(ciphertext, "vault:") {
+		return "", fmt.Errorf("secret %s is not a vault transit ciphertext", name)
+	}
+
+	plaintext, err := p.Decrypt(ctx, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+
+	p.mu.Lock()
+	p.cache[name] = plaintext
+	p.mu.Unlock()
+
+	return plaintext, nil
+}
+
+// Encrypt seals a plaintext with the Transit key. It is used by tooling to
+// produce the ciphertexts stored in VAULT_SECRETS_FILE.
+func (p *VaultTransitProvider) Encrypt(ctx context.Context, plaintext string) (string, error) {
+	var res struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	body := map[string]string{"plaintext": base64.StdEncoding.EncodeToString([]byte(plaintext))}
+	if err := p.call(ctx, "encrypt", body, &res); err != nil {
+		return "", err
+	}
+	return res.Data.Ciphertext, nil
+}
+
+func (p *VaultTransitProvider) Decrypt(ctx context.Context, ciphertext string) (string, error) {
+	var res struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := p.call(ctx, "decrypt", map[string]string{"ciphertext": ciphertext}, &res); err != nil {
+		return "", err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(res.Data.Plaintext)
+	if err != nil {
+		return "", fmt.Errorf("invalid plaintext encoding: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func (p *VaultTransitProvider) call(ctx context.Context, operation string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", p.address, p.mount, operation, url.PathEscape(p.keyName))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&vaultErr)
+		return fmt.Errorf("vault %s returned %d: %s", operation, resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,225 @@
+package lockout
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/helpers"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidUnlockToken = errors.New("invalid or expired unlock token")
+
+type scope string
+
+const (
+	scopeAccount scope = "account"
+	scopeIP      scope = "ip"
+)
+
+// Guard tracks failed credential checks per account (by email) and per
+// client IP in Redis. When Redis is unavailable it fails open so logins keep
+// working, matching how the rest of the server treats Redis.
+type Guard struct {
+	redis  *redis.Client
+	config *config.Config
+	mail   helpers.Mail
+}
+
+func NewGuard(redis *redis.Client, cfg *config.Config, mail helpers.Mail) *Guard {
+	return &Guard{redis: redis, config: cfg, mail: mail}
+}
+
+// Failure describes the state after a failed attempt was recorded.
+type Failure struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func failuresKey(s scope, id string) string { return fmt.Sprintf("lockout:failures:%s:%s", s, id) }
+func backoffKey(s scope, id string) string  { return fmt.Sprintf("lockout:backoff:%s:%s", s, id) }
+func lockedKey(s scope, id string) string   { return fmt.Sprintf("lockout:locked:%s:%s", s, id) }
+
+func unlockKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "lockout:unlock:" + hex.EncodeToString(sum[:])
+}
+
+// Check returns how long the caller has to wait before another attempt for
+// this email or IP is allowed. Zero means the attempt may proceed.
+func (g *Guard) Check(ctx context.Context, email, ip string) time.Duration {
+	if !g.config.Lockout.Enabled {
+		return 0
+	}
+
+	email = normalizeEmail(email)
+	pipe := g.redis.Pipeline()
+	ttls := []*redis.DurationCmd{
+		pipe.PTTL(ctx, lockedKey(scopeAccount, email)),
+		pipe.PTTL(ctx, backoffKey(scopeAccount, email)),
+		pipe.PTTL(ctx, lockedKey(scopeIP, ip)),
+		pipe.PTTL(ctx, backoffKey(scopeIP, ip)),
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Lockout check failed, allowing attempt: %v", err)
+		return 0
+	}
+
+	var wait time.Duration
+	for _, ttl := range ttls {
+		if d := ttl.Val(); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// RecordFailure counts a failed attempt. When notify is set and this failure
+// locks the account, the owner is emailed an unlock link.
+func (g *Guard) RecordFailure(ctx context.Context, email, ip string, notify bool) Failure {
+	if !g.config.Lockout.Enabled {
+		return Failure{}
+	}
+
+	email = normalizeEmail(email)
+	account, accountLocked := g.recordFailure(ctx, scopeAccount, email, g.config.Lockout.AccountMaxFailures)
+	ipFailure, _ := g.recordFailure(ctx, scopeIP, ip, g.config.Lockout.IPMaxFailures)
+
+	if accountLocked && notify {
+		go g.notifyLocked(email)
+	}
+
+	if ipFailure.RetryAfter > account.RetryAfter {
+		account.RetryAfter = ipFailure.RetryAfter
+	}
+	account.Locked = account.Locked || ipFailure.Locked
+	return account
+}
+
+// countScript increments a failure counter and starts its window on the
+// first failure, in one step so a counter is never left without a TTL.
+var countScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// recordFailure returns the resulting state and whether this call newly locked the subject
+func (g *Guard) recordFailure(ctx context.Context, s scope, id string, maxFailures int) (Failure, bool) {
+	cfg := g.config.Lockout
+
+	count, err := countScript.Run(ctx, g.redis, []string{failuresKey(s, id)}, cfg.FailureWindow.Milliseconds()).Int64()
+	if err != nil {
+		log.Printf("Lockout failure counter unavailable: %v", err)
+		return Failure{}, false
+	}
+
+	if maxFailures > 0 && count >= int64(maxFailures) {
+		newlyLocked, err := g.redis.SetNX(ctx, lockedKey(s, id), time.Now().Unix(), cfg.LockoutDuration).Result()
+		if err != nil {
+			log.Printf("Error setting lockout: %v", err)
+		}
+		if newlyLocked {
+			log.Printf("Lockout: %s locked for %s after %d failures", s, cfg.LockoutDuration, count)
+		}
+		return Failure{RetryAfter: cfg.LockoutDuration, Locked: true}, newlyLocked
+	}
+
+	delay := g.backoff(int(count))
+	if delay > 0 {
+		g.redis.Set(ctx, backoffKey(s, id), 1, delay)
+	}
+	return Failure{RetryAfter: delay}, false
+}
+
+// backoff doubles the enforced delay for every failure past the free attempts
+func (g *Guard) backoff(failures int) time.Duration {
+	cfg := g.config.Lockout
+	over := failures - cfg.FreeAttempts
+	if over <= 0 || cfg.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := cfg.BaseDelay
+	for i := 1; i < over; i++ {
+		delay *= 2
+		if delay >= cfg.MaxDelay {
+			return cfg.MaxDelay
+		}
+	}
+	return delay
+}
+
+// RecordSuccess clears the account's failure state. The IP counter is left
+// alone so an attacker cannot reset it by logging into their own account.
+func (g *Guard) RecordSuccess(ctx context.Context, email string) {
+	if !g.config.Lockout.Enabled {
+		return
+	}
+
+	email = normalizeEmail(email)
+	if err := g.redis.Del(ctx, failuresKey(scopeAccount, email), backoffKey(scopeAccount, email)).Err(); err != nil {
+		log.Printf("Error clearing lockout state: %v", err)
+	}
+}
+
+// Unlock consumes an emailed unlock token and clears the account lock.
+func (g *Guard) Unlock(ctx context.Context, token string) (string, error) {
+	email, err := g.redis.GetDel(ctx, unlockKey(token)).Result()
+	if err == redis.Nil {
+		return "", ErrInvalidUnlockToken
+	} else if err != nil {
+		return "", err
+	}
+
+	err = g.redis.Del(ctx,
+		lockedKey(scopeAccount, email),
+		failuresKey(scopeAccount, email),
+		backoffKey(scopeAccount, email),
+	).Err()
+	return email, err
+}
+
+func (g *Guard) createUnlockToken(ctx context.Context, email string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	if err := g.redis.Set(ctx, unlockKey(token), email, g.config.Lockout.UnlockTokenTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (g *Guard) notifyLocked(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, err := g.createUnlockToken(ctx, email)
+	if err != nil {
+		log.Printf("Error creating unlock token: %v", err)
+		return
+	}
+
+	unlockLink := fmt.Sprintf("%s/unlock/%s", strings.TrimRight(g.config.Server.PublicURL, "/"), token)
+	if err := helpers.SendAccountLockedEmail(ctx, g.mail, email, unlockLink, g.config.Lockout.LockoutDuration, g.config.Lockout.UnlockTokenTTL, g.config); err != nil {
+		log.Printf("Error sending lockout notification: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package lockout
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/mailer"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// captureQueue hands enqueued messages to the test.
+type captureQueue chan mailer.Message
+
+func (q captureQueue) Enqueue(ctx context.Context, msg mailer.Message) error {
+	q <- msg
+	return nil
+}
+
+var testLockout = config.LockoutConfig{
+	Enabled:            true,
+	FreeAttempts:       2,
+	AccountMaxFailures: 5,
+	IPMaxFailures:      8,
+	BaseDelay:          time.Second,
+	MaxDelay:           4 * time.Second,
+	FailureWindow:      15 * time.Minute,
+	LockoutDuration:    time.Hour,
+	UnlockTokenTTL:     24 * time.Hour,
+}
+
+func newTestGuard(t *testing.T, lockout config.LockoutConfig) (*Guard, *miniredis.Miniredis, captureQueue) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	templates, err := emailtemplate.New(config.EmailConfig{DefaultLocale: "en", BrandName: "Aspire Auth"})
+	if err != nil {
+		t.Fatalf("emailtemplate.New: %v", err)
+	}
+	queue := make(captureQueue, 1)
+	cfg := &config.Config{Lockout: lockout}
+	cfg.Server.PublicURL = "https://auth.example.com"
+	return NewGuard(client, cfg, helpers.Mail{Queue: queue, Templates: templates, Locale: "en"}), mr, queue
+}
+
+func TestBackoff(t *testing.T) {
+	g, _, _ := newTestGuard(t, testLockout)
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 4 * time.Second},
+		{40, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := g.backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		wantLocked bool
+		wantRetry  time.Duration
+	}{
+		{name: "free attempts", failures: 2, wantRetry: 0},
+		{name: "first delay", failures: 3, wantRetry: time.Second},
+		{name: "doubling", failures: 4, wantRetry: 2 * time.Second},
+		{name: "locked", failures: 5, wantLocked: true, wantRetry: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, _, _ := newTestGuard(t, testLockout)
+			ctx := context.Background()
+
+			var last Failure
+			for i := 0; i < tt.failures; i++ {
+				// Case and spacing do not give an attacker a fresh counter
+				email := []string{"User@Example.com", " user@example.com"}[i%2]
+				last = g.RecordFailure(ctx, email, "203.0.113.7", false)
+			}
+			if last.Locked != tt.wantLocked || last.RetryAfter != tt.wantRetry {
+				t.Fatalf("RecordFailure() = %+v, want locked %v retry %s", last, tt.wantLocked, tt.wantRetry)
+			}
+			if wait := g.Check(ctx, "user@example.com", "198.51.100.1"); wait > tt.wantRetry || (tt.wantRetry > 0 && wait <= 0) {
+				t.Fatalf("Check() = %s, want about %s", wait, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestFailureWindow(t *testing.T) {
+	g, mr, _ := newTestGuard(t, testLockout)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		g.RecordFailure(ctx, "user@example.com", "203.0.113.7", false)
+	}
+	key := failuresKey(scopeAccount, "user@example.com")
+	if ttl := mr.TTL(key); ttl <= 0 || ttl > testLockout.FailureWindow {
+		t.Fatalf("failure counter TTL = %s, want within the window", ttl)
+	}
+
+	// Failures spread wider than the window never add up to a lock
+	mr.FastForward(testLockout.FailureWindow + time.Second)
+	if failure := g.RecordFailure(ctx, "user@example.com", "203.0.113.7", false); failure.Locked || failure.RetryAfter != 0 {
+		t.Fatalf("RecordFailure() after the window = %+v, want a fresh count", failure)
+	}
+}
+
+func TestIPLockout(t *testing.T) {
+	g, _, _ := newTestGuard(t, testLockout)
+	ctx := context.Background()
+
+	// One IP spraying many accounts is locked out even though no single account is
+	var last Failure
+	for i := 0; i < testLockout.IPMaxFailures; i++ {
+		last = g.RecordFailure(ctx, string(rune('a'+i))+"@example.com", "203.0.113.7", false)
+	}
+	if !last.Locked {
+		t.Fatalf("RecordFailure() = %+v, want the IP locked", last)
+	}
+	if wait := g.Check(ctx, "fresh@example.com", "203.0.113.7"); wait <= 0 {
+		t.Fatal("Check() lets a locked IP try another account")
+	}
+	if wait := g.Check(ctx, "fresh@example.com", "198.51.100.1"); wait != 0 {
+		t.Fatalf("Check() from another IP = %s, want 0", wait)
+	}
+}
+
+func TestRecordSuccessKeepsIPCounter(t *testing.T) {
+	g, mr, _ := newTestGuard(t, testLockout)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		g.RecordFailure(ctx, "user@example.com", "203.0.113.7", false)
+	}
+	g.RecordSuccess(ctx, "USER@example.com")
+
+	if mr.Exists(failuresKey(scopeAccount, "user@example.com")) || mr.Exists(backoffKey(scopeAccount, "user@example.com")) {
+		t.Fatal("RecordSuccess() left the account's failure state")
+	}
+	if !mr.Exists(failuresKey(scopeIP, "203.0.113.7")) {
+		t.Fatal("RecordSuccess() reset the IP counter")
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	g, _, queue := newTestGuard(t, testLockout)
+	ctx := context.Background()
+
+	for i := 0; i < testLockout.AccountMaxFailures; i++ {
+		g.RecordFailure(ctx, "user@example.com", "203.0.113.7", true)
+	}
+
+	var msg mailer.Message
+	select {
+	case msg = <-queue:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no lockout notice was queued")
+	}
+	if msg.To != "user@example.com" {
+		t.Fatalf("lockout notice sent to %q", msg.To)
+	}
+	token := regexp.MustCompile(`https://auth\.example\.com/unlock/([A-Za-z0-9_-]+)`).FindStringSubmatch(msg.TextBody)
+	if token == nil {
+		t.Fatalf("lockout notice has no unlock link:\n%s", msg.TextBody)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "unknown token", token: "not-a-token", wantErr: ErrInvalidUnlockToken},
+		{name: "emailed token", token: token[1]},
+		{name: "token reused", token: token[1], wantErr: ErrInvalidUnlockToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := g.Unlock(ctx, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Unlock() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && email != "user@example.com" {
+				t.Fatalf("Unlock() = %q", email)
+			}
+		})
+	}
+
+	// Only the account lock is lifted; the IP keeps its count
+	if wait := g.Check(ctx, "user@example.com", "198.51.100.1"); wait != 0 {
+		t.Fatalf("Check() after unlock = %s, want 0", wait)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	disabled := testLockout
+	disabled.Enabled = false
+	g, mr, _ := newTestGuard(t, disabled)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		if failure := g.RecordFailure(ctx, "user@example.com", "203.0.113.7", true); failure != (Failure{}) {
+			t.Fatalf("RecordFailure() = %+v with lockout disabled", failure)
+		}
+	}
+	if wait := g.Check(ctx, "user@example.com", "203.0.113.7"); wait != 0 || len(mr.Keys()) != 0 {
+		t.Fatalf("Check() = %s with keys %v, want nothing tracked", wait, mr.Keys())
+	}
+}
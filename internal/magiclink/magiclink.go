@@ -0,0 +1,137 @@
+package magiclink
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Purpose namespaces links so a verification link can never sign anyone in.
+type Purpose string
+
+const (
+	PurposeVerify Purpose = "verify"
+	PurposeSignIn Purpose = "signin"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired link")
+
+// Signer produces the MAC of an encoded payload. JWTHelpers implements it
+// with a key from the key provider.
+type Signer interface {
+	SignMagicLink(payload []byte) []byte
+}
+
+// Claims are carried inside the link. ServiceID is set for service sign-in.
+type Claims struct {
+	Purpose   Purpose    `json:"p"`
+	AccountID uuid.UUID  `json:"a"`
+	ServiceID *uuid.UUID `json:"s,omitempty"`
+	Nonce     string     `json:"n"`
+	ExpiresAt int64      `json:"e"`
+}
+
+// Service issues and consumes links of the form <payload>.<signature>. The
+// signature makes links unforgeable without a Redis lookup; the nonce stored
+// in Redis makes each link single-use, and issuing a new link for the same
+// account and purpose invalidates the previous one.
+type Service struct {
+	redis  *redis.Client
+	signer Signer
+	config config.MagicLinkConfig
+}
+
+func NewService(redis *redis.Client, signer Signer, cfg config.MagicLinkConfig) *Service {
+	return &Service{redis: redis, signer: signer, config: cfg}
+}
+
+func nonceKey(purpose Purpose, accountID uuid.UUID, serviceID *uuid.UUID) string {
+	if serviceID != nil {
+		return fmt.Sprintf("magiclink:%s:%s:%s", purpose, accountID, serviceID)
+	}
+	return fmt.Sprintf("magiclink:%s:%s", purpose, accountID)
+}
+
+// consumeScript deletes the stored nonce only if it matches, so a link can
+// be used once and only while it is the latest one issued.
+var consumeScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Issue returns a new link token for accountID.
+func (s *Service) Issue(ctx context.Context, purpose Purpose, accountID uuid.UUID, serviceID *uuid.UUID) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	claims := Claims{
+		Purpose:   purpose,
+		AccountID: accountID,
+		ServiceID: serviceID,
+		Nonce:     base64.RawURLEncoding.EncodeToString(nonce),
+		ExpiresAt: time.Now().Add(s.config.TTL).Unix(),
+	}
+
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	signature := base64.RawURLEncoding.EncodeToString(s.signer.SignMagicLink([]byte(payload)))
+
+	if err := s.redis.Set(ctx, nonceKey(purpose, accountID, serviceID), claims.Nonce, s.config.TTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store link nonce: %w", err)
+	}
+
+	return payload + "." + signature, nil
+}
+
+// Consume validates token for purpose and marks it used.
+func (s *Service) Consume(ctx context.Context, purpose Purpose, token string) (*Claims, error) {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.signer.SignMagicLink([]byte(payload))) {
+		return nil, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Purpose != purpose || time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+
+	deleted, err := consumeScript.Run(ctx, s.redis, []string{nonceKey(purpose, claims.AccountID, claims.ServiceID)}, claims.Nonce).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume link: %w", err)
+	}
+	if deleted != 1 {
+		return nil, ErrInvalidToken
+	}
+
+	return &claims, nil
+}
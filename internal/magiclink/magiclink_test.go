@@ -0,0 +1,175 @@
+package magiclink
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// hmacSigner signs with a fixed key, like JWTHelpers does with its derived one.
+type hmacSigner []byte
+
+func (s hmacSigner) SignMagicLink(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func newTestService(t *testing.T, key string) (*Service, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewService(client, hmacSigner(key), config.MagicLinkConfig{TTL: 15 * time.Minute}), mr
+}
+
+// resign replaces the claims of token and signs them with key.
+func resign(t *testing.T, token, key string, edit func(*Claims)) string {
+	t.Helper()
+	payload, _, _ := strings.Cut(token, ".")
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatal(err)
+	}
+	edit(&claims)
+	raw, _ = json.Marshal(claims)
+	payload = base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(hmacSigner(key).SignMagicLink([]byte(payload)))
+}
+
+func TestConsume(t *testing.T) {
+	accountID := uuid.New()
+	serviceID := uuid.New()
+
+	tests := []struct {
+		name      string
+		serviceID *uuid.UUID
+		purpose   Purpose
+		// token turns the issued token into the one presented
+		token   func(t *testing.T, issued string) string
+		wantErr error
+	}{
+		{name: "valid", purpose: PurposeVerify, token: func(t *testing.T, issued string) string { return issued }},
+		{name: "valid for a service", serviceID: &serviceID, purpose: PurposeSignIn, token: func(t *testing.T, issued string) string { return issued }},
+		{name: "other purpose", purpose: PurposeSignIn, token: func(t *testing.T, issued string) string { return issued }, wantErr: ErrInvalidToken},
+		{name: "no signature", purpose: PurposeVerify, token: func(t *testing.T, issued string) string {
+			payload, _, _ := strings.Cut(issued, ".")
+			return payload
+		}, wantErr: ErrInvalidToken},
+		{name: "bad signature encoding", purpose: PurposeVerify, token: func(t *testing.T, issued string) string {
+			return issued + "!"
+		}, wantErr: ErrInvalidToken},
+		{name: "tampered payload", purpose: PurposeVerify, token: func(t *testing.T, issued string) string {
+			forged := resign(t, issued, "other-key", func(c *Claims) { c.AccountID = uuid.New() })
+			payload, _, _ := strings.Cut(forged, ".")
+			_, signature, _ := strings.Cut(issued, ".")
+			return payload + "." + signature
+		}, wantErr: ErrInvalidToken},
+		{name: "signed with another key", purpose: PurposeVerify, token: func(t *testing.T, issued string) string {
+			return resign(t, issued, "other-key", func(*Claims) {})
+		}, wantErr: ErrInvalidToken},
+		{name: "expired", purpose: PurposeVerify, token: func(t *testing.T, issued string) string {
+			return resign(t, issued, "test-key", func(c *Claims) { c.ExpiresAt = time.Now().Add(-time.Minute).Unix() })
+		}, wantErr: ErrInvalidToken},
+		{name: "wrong nonce", purpose: PurposeVerify, token: func(t *testing.T, issued string) string {
+			return resign(t, issued, "test-key", func(c *Claims) { c.Nonce = "guessed" })
+		}, wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t, "test-key")
+			ctx := context.Background()
+
+			issuedPurpose := PurposeVerify
+			if tt.serviceID != nil {
+				issuedPurpose = PurposeSignIn
+			}
+			issued, err := s.Issue(ctx, issuedPurpose, accountID, tt.serviceID)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+
+			claims, err := s.Consume(ctx, tt.purpose, tt.token(t, issued))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Consume() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Consume() error = %v", err)
+			}
+			if claims.AccountID != accountID || claims.Purpose != tt.purpose {
+				t.Fatalf("Consume() claims = %+v", claims)
+			}
+			if (claims.ServiceID == nil) != (tt.serviceID == nil) || (tt.serviceID != nil && *claims.ServiceID != *tt.serviceID) {
+				t.Fatalf("Consume() service = %v, want %v", claims.ServiceID, tt.serviceID)
+			}
+		})
+	}
+}
+
+func TestConsumeSingleUse(t *testing.T) {
+	s, mr := newTestService(t, "test-key")
+	ctx := context.Background()
+	accountID := uuid.New()
+
+	first, err := s.Issue(ctx, PurposeSignIn, accountID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Issue(ctx, PurposeSignIn, accountID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Consume(ctx, PurposeSignIn, first); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("superseded link: error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := s.Consume(ctx, PurposeSignIn, second); err != nil {
+		t.Fatalf("latest link: error = %v", err)
+	}
+	if _, err := s.Consume(ctx, PurposeSignIn, second); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("replayed link: error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	// A verification link lives under its own key, so signing in does not
+	// invalidate it
+	verify, err := s.Issue(ctx, PurposeVerify, accountID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Issue(ctx, PurposeSignIn, accountID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Consume(ctx, PurposeVerify, verify); err != nil {
+		t.Fatalf("verify link after sign-in link: error = %v", err)
+	}
+
+	// Once the nonce expires in Redis the link is dead even if the claims
+	// have not caught up
+	stale, err := s.Issue(ctx, PurposeSignIn, accountID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(16 * time.Minute)
+	if _, err := s.Consume(ctx, PurposeSignIn, stale); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expired nonce: error = %v, want %v", err, ErrInvalidToken)
+	}
+}
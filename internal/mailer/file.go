@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer is for development: it writes each message as an .eml file to
+// dir, or only logs the recipient and subject when dir is empty.
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+func NewFileMailer(from, dir string) (*FileMailer, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("creating email directory: %w", err)
+		}
+	}
+	return &FileMailer{from: from, dir: dir}, nil
+}
+
+func (m *FileMailer) Name() string {
+	if m.dir == "" {
+		return "log"
+	}
+	return "file"
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if m.dir == "" {
+		log.Printf("Email to %s: %s", msg.To, msg.Subject)
+		return nil
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s_%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), recipient)
+
+	file, err := os.OpenFile(filepath.Join(m.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := buildMessage(m.from, msg).WriteTo(file); err != nil {
+		return err
+	}
+	log.Printf("Email to %s written to %s", msg.To, name)
+	return nil
+}
@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"aspire-auth/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPMailer hands messages to a transactional email API that accepts a JSON
+// body of from, to, subject, html and text, authenticated with a bearer key.
+type HTTPMailer struct {
+	from   string
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func NewHTTPMailer(cfg config.EmailConfig) (*HTTPMailer, error) {
+	if cfg.HTTPURL == "" {
+		return nil, errors.New("EMAIL_HTTP_URL is required for the http email provider")
+	}
+	return &HTTPMailer{
+		from:   cfg.From,
+		url:    cfg.HTTPURL,
+		apiKey: cfg.HTTPAPIKey,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (m *HTTPMailer) Name() string { return "http" }
+
+type httpMessage struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text,omitempty"`
+}
+
+func (m *HTTPMailer) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(httpMessage{
+		From:    m.from,
+		To:      msg.To,
+		Subject: msg.Subject,
+		HTML:    msg.HTMLBody,
+		Text:    msg.TextBody,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("email API returned %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
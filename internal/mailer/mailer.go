@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"fmt"
+)
+
+// Message is a fully rendered email. TextBody is optional.
+type Message struct {
+	To       string
+	Subject  string
+	HTMLBody string
+	TextBody string
+}
+
+// Mailer delivers a single message. Implementations must be safe for
+// concurrent use.
+type Mailer interface {
+	// Name identifies the provider in logs
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// New builds the mailer selected by cfg.Provider.
+func New(cfg config.EmailConfig) (Mailer, error) {
+	switch cfg.Provider {
+	case "", "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		return NewFileMailer(cfg.From, cfg.FileDir)
+	case "http":
+		return NewHTTPMailer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,168 @@
+package mailer
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/worker"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// claimLease is how long a claimed message is hidden from other senders. A
+// sender that dies mid-batch leaves its messages to be picked up afterwards.
+const claimLease = 5 * time.Minute
+
+// maxRetryDelay caps the exponential backoff between attempts.
+const maxRetryDelay = 6 * time.Hour
+
+// cleanupInterval is how often sent and failed messages past retention are removed.
+const cleanupInterval = time.Hour
+
+// Queue accepts messages for delivery.
+type Queue interface {
+	Enqueue(ctx context.Context, msg Message) error
+}
+
+// Secrets seals message bodies while they wait in the outbox.
+type Secrets interface {
+	EncryptServiceSecretKey(plaintext string) (string, error)
+	DecryptServiceSecretKey(encrypted string) (string, error)
+}
+
+// Outbox stores messages in the OUTBOX_EMAILS table and delivers them from
+// a background loop, so callers never wait on the mail provider.
+//
+// Bodies carry sign-in links and codes, so they are stored encrypted and
+// blanked once the message is sent. Finished rows are removed after the
+// retention period.
+type Outbox struct {
+	db      *gorm.DB
+	mailer  Mailer
+	secrets Secrets
+	config  config.EmailConfig
+}
+
+func NewOutbox(db *gorm.DB, mailer Mailer, secrets Secrets, cfg config.EmailConfig) *Outbox {
+	return &Outbox{db: db, mailer: mailer, secrets: secrets, config: cfg}
+}
+
+// WithTx returns an outbox that enqueues inside tx, so the message is only
+// sent if tx commits.
+func (o *Outbox) WithTx(tx *gorm.DB) *Outbox {
+	scoped := *o
+	scoped.db = tx
+	return &scoped
+}
+
+func (o *Outbox) Enqueue(ctx context.Context, msg Message) error {
+	htmlBody, err := o.secrets.EncryptServiceSecretKey(msg.HTMLBody)
+	if err != nil {
+		return fmt.Errorf("encrypting email body: %w", err)
+	}
+	textBody := ""
+	if msg.TextBody != "" {
+		if textBody, err = o.secrets.EncryptServiceSecretKey(msg.TextBody); err != nil {
+			return fmt.Errorf("encrypting email body: %w", err)
+		}
+	}
+
+	return o.db.WithContext(ctx).Create(&models.OutboxEmail{
+		Recipient:     msg.To,
+		Subject:       msg.Subject,
+		HTMLBody:      htmlBody,
+		TextBody:      textBody,
+		Status:        models.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// Run delivers pending messages until ctx is cancelled.
+func (o *Outbox) Run(ctx context.Context) {
+	log.Printf("Email outbox sender started using %s provider", o.mailer.Name())
+
+	worker.Loop{
+		Name:            "Email outbox",
+		Interval:        o.config.PollInterval,
+		BatchSize:       o.config.BatchSize,
+		Process:         o.dispatch,
+		Cleanup:         o.cleanup,
+		CleanupInterval: cleanupInterval,
+	}.Run(ctx)
+}
+
+// dispatch claims one batch of due messages and attempts each of them.
+func (o *Outbox) dispatch(ctx context.Context) (int, error) {
+	batch, err := worker.ClaimDue[models.OutboxEmail](ctx, o.db, models.OutboxPending, o.config.BatchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range batch {
+		o.deliver(ctx, &batch[i])
+	}
+	return len(batch), nil
+}
+
+func (o *Outbox) deliver(ctx context.Context, email *models.OutboxEmail) {
+	sendCtx, cancel := context.WithTimeout(ctx, o.config.Timeout)
+	defer cancel()
+
+	msg, err := o.open(email)
+	if err == nil {
+		err = o.mailer.Send(sendCtx, msg)
+	}
+
+	attempts := email.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":   attempts,
+		"updated_at": time.Now(),
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = models.OutboxSent
+		updates["sent_at"] = time.Now()
+		updates["last_error"] = ""
+		updates["html_body"] = ""
+		updates["text_body"] = ""
+	case attempts >= o.config.MaxAttempts:
+		log.Printf("Giving up on email %s to %s after %d attempts: %v", email.ID, email.Recipient, attempts, err)
+		updates["status"] = models.OutboxFailed
+		updates["last_error"] = err.Error()
+	default:
+		log.Printf("Email %s to %s failed (attempt %d): %v", email.ID, email.Recipient, attempts, err)
+		updates["next_attempt_at"] = time.Now().Add(worker.RetryDelay(o.config.RetryBaseDelay, maxRetryDelay, attempts))
+		updates["last_error"] = err.Error()
+	}
+
+	if err := o.db.Model(&models.OutboxEmail{}).Where("id = ?", email.ID).Updates(updates).Error; err != nil {
+		log.Printf("Error updating outbox email %s: %v", email.ID, err)
+	}
+}
+
+// open decrypts a stored message for sending.
+func (o *Outbox) open(email *models.OutboxEmail) (Message, error) {
+	msg := Message{To: email.Recipient, Subject: email.Subject}
+
+	var err error
+	if msg.HTMLBody, err = o.secrets.DecryptServiceSecretKey(email.HTMLBody); err != nil {
+		return Message{}, fmt.Errorf("decrypting email body: %w", err)
+	}
+	if email.TextBody != "" {
+		if msg.TextBody, err = o.secrets.DecryptServiceSecretKey(email.TextBody); err != nil {
+			return Message{}, fmt.Errorf("decrypting email body: %w", err)
+		}
+	}
+	return msg, nil
+}
+
+// cleanup removes sent and failed messages older than the retention period.
+func (o *Outbox) cleanup(ctx context.Context) error {
+	return o.db.WithContext(ctx).
+		Where("status <> ? AND created_at < ?", models.OutboxPending, time.Now().Add(-o.config.Retention)).
+		Delete(&models.OutboxEmail{}).Error
+}
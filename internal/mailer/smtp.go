@@ -0,0 +1,187 @@
+package mailer
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+// SMTPMailer sends through an SMTP relay. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it. Certificates are
+// verified against Host unless InsecureSkipVerify is set.
+//
+// Each message gets its own connection, bounded by the send context: the
+// dial, the SMTP conversation and the upload all stop at its deadline.
+type SMTPMailer struct {
+	from      string
+	host      string
+	ssl       bool
+	addr      string
+	username  string
+	password  string
+	tlsConfig *tls.Config
+}
+
+func NewSMTPMailer(cfg config.EmailConfig) *SMTPMailer {
+	return &SMTPMailer{
+		from:     cfg.From,
+		host:     cfg.Host,
+		ssl:      cfg.Port == 465,
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		username: cfg.Username,
+		password: cfg.Password,
+		tlsConfig: &tls.Config{
+			ServerName:         cfg.Host,
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		},
+	}
+}
+
+func (m *SMTPMailer) Name() string { return "smtp" }
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	// Cancellation without a deadline still has to unblock the conversation
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if m.ssl {
+		conn = tls.Client(conn, m.tlsConfig)
+	}
+
+	err = m.send(conn, msg)
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return fmt.Errorf("%w: %v", ctxErr, err)
+	}
+	return err
+}
+
+func (m *SMTPMailer) send(conn net.Conn, msg Message) error {
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if !m.ssl {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(m.tlsConfig); err != nil {
+				return err
+			}
+		}
+	}
+
+	if m.username != "" {
+		if ok, mechanisms := client.Extension("AUTH"); ok {
+			if err := client.Auth(m.auth(mechanisms)); err != nil {
+				return err
+			}
+		}
+	}
+
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := buildMessage(m.from, msg).WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// auth picks a mechanism the server offers, preferring CRAM-MD5 and using
+// LOGIN only for servers that do not support PLAIN.
+func (m *SMTPMailer) auth(mechanisms string) smtp.Auth {
+	switch {
+	case strings.Contains(mechanisms, "CRAM-MD5"):
+		return smtp.CRAMMD5Auth(m.username, m.password)
+	case strings.Contains(mechanisms, "LOGIN") && !strings.Contains(mechanisms, "PLAIN"):
+		return &loginAuth{username: m.username, password: m.password, host: m.host}
+	default:
+		return smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp does not provide.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" && server.Name != "::1" {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch strings.ToLower(strings.TrimSuffix(string(fromServer), ":")) {
+	case "username":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
+
+func buildMessage(from string, msg Message) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Subject)
+	if msg.TextBody != "" {
+		m.SetBody("text/plain", msg.TextBody)
+		m.AddAlternative("text/html", msg.HTMLBody)
+	} else {
+		m.SetBody("text/html", msg.HTMLBody)
+	}
+	return m
+}
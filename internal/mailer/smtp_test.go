@@ -0,0 +1,125 @@
+package mailer
+
+import (
+	"aspire-auth/internal/config"
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one connection and runs serve on it.
+func fakeSMTP(t *testing.T, serve func(conn net.Conn)) config.EmailConfig {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serve(conn)
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return config.EmailConfig{From: "Aspire <noreply@example.com>", Host: host, Port: portNumber}
+}
+
+// relay answers a minimal SMTP conversation and reports the DATA it received.
+func relay(received chan<- string) func(net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 fake ESMTP")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					received <- data.String()
+					reply("250 queued")
+					continue
+				}
+				data.WriteString(line)
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250 fake")
+			case cmd == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		serve   func(received chan<- string) func(net.Conn)
+		timeout time.Duration
+		wantErr error
+	}{
+		{name: "delivers", serve: relay, timeout: 5 * time.Second},
+		{
+			name: "stalled server",
+			serve: func(chan<- string) func(net.Conn) {
+				// Accept the connection but never send the greeting
+				return func(conn net.Conn) { time.Sleep(2 * time.Second) }
+			},
+			timeout: 100 * time.Millisecond,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan string, 1)
+			m := NewSMTPMailer(fakeSMTP(t, tt.serve(received)))
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := m.Send(ctx, Message{To: "user@example.com", Subject: "Hello", HTMLBody: "<p>Hi</p>", TextBody: "Hi"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Send() error = %v, want %v", err, tt.wantErr)
+				}
+				if elapsed := time.Since(start); elapsed > time.Second {
+					t.Fatalf("Send() took %s, want it bounded by the context", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			body := <-received
+			if !strings.Contains(body, "Subject: Hello") || !strings.Contains(body, "<p>Hi</p>") {
+				t.Fatalf("unexpected message:\n%s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package mfa
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrInvalidChallenge = errors.New("invalid or expired MFA challenge")
+
+// Challenge is a sign-in waiting for its second step. ServiceID is set when
+// the sign-in is to a service rather than to the account itself.
+type Challenge struct {
+	AccountID uuid.UUID  `json:"account_id"`
+	ServiceID *uuid.UUID `json:"service_id,omitempty"`
+}
+
+// Store tracks sign-ins whose password has been accepted but whose second
+// step is still outstanding. Only a hash of each challenge token is kept.
+type Store struct {
+	redis  *redis.Client
+	config config.MFAConfig
+}
+
+func NewStore(redis *redis.Client, cfg config.MFAConfig) *Store {
+	return &Store{redis: redis, config: cfg}
+}
+
+func challengeKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "mfa:challenge:" + hex.EncodeToString(sum[:])
+}
+
+// CreateChallenge returns a token that lets the holder finish signing in to
+// accountID, or to serviceID as accountID when it is set, by entering the
+// code sent to it.
+func (s *Store) CreateChallenge(ctx context.Context, accountID uuid.UUID, serviceID *uuid.UUID) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	payload, err := json.Marshal(Challenge{AccountID: accountID, ServiceID: serviceID})
+	if err != nil {
+		return "", err
+	}
+	if err := s.redis.Set(ctx, challengeKey(token), payload, s.config.ChallengeTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Challenge returns the sign-in a token was issued for without consuming it,
+// so a mistyped code can be retried within the OTP attempt limit.
+func (s *Store) Challenge(ctx context.Context, token string) (*Challenge, error) {
+	payload, err := s.redis.Get(ctx, challengeKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidChallenge
+	} else if err != nil {
+		return nil, err
+	}
+
+	var challenge Challenge
+	if err := json.Unmarshal(payload, &challenge); err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	return &challenge, nil
+}
+
+// Complete deletes the challenge. It reports false when another request
+// already completed it, so a token yields at most one session.
+func (s *Store) Complete(ctx context.Context, token string) (bool, error) {
+	deleted, err := s.redis.Del(ctx, challengeKey(token)).Result()
+	return deleted == 1, err
+}
@@ -0,0 +1,91 @@
+package mfa
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewStore(client, config.MFAConfig{ChallengeTTL: 5 * time.Minute}), mr
+}
+
+func TestChallenge(t *testing.T) {
+	serviceID := uuid.New()
+	tests := []struct {
+		name      string
+		serviceID *uuid.UUID
+	}{
+		{"account sign-in", nil},
+		{"service sign-in", &serviceID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			ctx := context.Background()
+			accountID := uuid.New()
+
+			token, err := s.CreateChallenge(ctx, accountID, tt.serviceID)
+			if err != nil {
+				t.Fatalf("CreateChallenge: %v", err)
+			}
+
+			// Looking a challenge up leaves it in place for a retry
+			for i := 0; i < 2; i++ {
+				challenge, err := s.Challenge(ctx, token)
+				if err != nil {
+					t.Fatalf("Challenge: %v", err)
+				}
+				if challenge.AccountID != accountID {
+					t.Errorf("AccountID = %s, want %s", challenge.AccountID, accountID)
+				}
+				if (challenge.ServiceID == nil) != (tt.serviceID == nil) || (tt.serviceID != nil && *challenge.ServiceID != *tt.serviceID) {
+					t.Errorf("ServiceID = %v, want %v", challenge.ServiceID, tt.serviceID)
+				}
+			}
+
+			if completed, err := s.Complete(ctx, token); err != nil || !completed {
+				t.Fatalf("Complete = %v, %v", completed, err)
+			}
+			if completed, _ := s.Complete(ctx, token); completed {
+				t.Error("challenge completed twice")
+			}
+			if _, err := s.Challenge(ctx, token); !errors.Is(err, ErrInvalidChallenge) {
+				t.Errorf("completed challenge: Challenge = %v, want %v", err, ErrInvalidChallenge)
+			}
+		})
+	}
+}
+
+func TestChallengeExpiresAndIsHashed(t *testing.T) {
+	s, mr := newTestStore(t)
+	ctx := context.Background()
+
+	token, err := s.CreateChallenge(ctx, uuid.New(), nil)
+	if err != nil {
+		t.Fatalf("CreateChallenge: %v", err)
+	}
+	for _, key := range mr.Keys() {
+		if key == "mfa:challenge:"+token {
+			t.Fatal("challenge token is stored in plaintext")
+		}
+	}
+	if _, err := s.Challenge(ctx, "unknown"); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("unknown token: Challenge = %v, want %v", err, ErrInvalidChallenge)
+	}
+
+	mr.FastForward(5 * time.Minute)
+	if _, err := s.Challenge(ctx, token); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("expired token: Challenge = %v, want %v", err, ErrInvalidChallenge)
+	}
+}
@@ -0,0 +1,97 @@
+package mfa
+
+import (
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrCodeExhausted means the code behind a challenge expired or was guessed
+// wrong too often. The challenge is discarded; the sign-in starts over.
+var ErrCodeExhausted = errors.New("sign-in code expired or exhausted")
+
+// Service runs the second step of a sign-in: it sends a code along with a
+// challenge token and later checks the code posted back with the token.
+// Its errors are plain; the handlers decide how each is answered.
+type Service struct {
+	store *Store
+	codes *otp.Service
+	db    *gorm.DB
+}
+
+func NewService(store *Store, codes *otp.Service, db *gorm.DB) *Service {
+	return &Service{store: store, codes: codes, db: db}
+}
+
+// Start generates a sign-in code for account, sends it through sender to
+// the addresses in delivery and returns the challenge token it has to be
+// posted back with. Sign-ins to a service pass its ID. A code requested too
+// soon after the last one fails with *otp.RateLimitError.
+func (s *Service) Start(ctx context.Context, account *models.Account, serviceID *uuid.UUID, sender otp.OTPSender, delivery otp.Delivery) (string, error) {
+	code, err := s.codes.Generate(ctx, otp.PurposeMFA, account.ID.String())
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.store.CreateChallenge(ctx, account.ID, serviceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	delivery.Purpose = otp.PurposeMFA
+	delivery.Code = code
+	if err := sender.Send(ctx, delivery); err != nil {
+		return "", fmt.Errorf("failed to send sign-in code by %s: %w", sender.Channel(), err)
+	}
+	return token, nil
+}
+
+// Finish checks code against the challenge behind token and completes it.
+// forService says which kind of sign-in is being finished; a challenge for
+// the other kind fails with ErrInvalidChallenge as if it did not exist, so
+// an account challenge can never yield a service session or the reverse.
+//
+// A wrong code fails with otp.ErrInvalidCode and can be retried within the
+// attempt limit; ErrCodeExhausted ends the challenge. Both return the
+// account and challenge so the failure can be attributed.
+func (s *Service) Finish(ctx context.Context, token, code string, forService bool) (*models.Account, *Challenge, error) {
+	challenge, err := s.store.Challenge(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if (challenge.ServiceID != nil) != forService {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	var account models.Account
+	if err := s.db.WithContext(ctx).Where("id = ?", challenge.AccountID).First(&account).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidChallenge
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.codes.Verify(ctx, otp.PurposeMFA, account.ID.String(), code)
+	switch {
+	case errors.Is(err, otp.ErrTooManyAttempts), errors.Is(err, otp.ErrExpired):
+		// The code is gone, so the challenge is useless
+		s.store.Complete(ctx, token)
+		return &account, challenge, ErrCodeExhausted
+	case err != nil:
+		return &account, challenge, err
+	}
+
+	completed, err := s.store.Complete(ctx, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to complete challenge: %w", err)
+	}
+	if !completed {
+		// Another request finished the challenge first
+		return nil, nil, ErrInvalidChallenge
+	}
+	return &account, challenge, nil
+}
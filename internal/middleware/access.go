@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"aspire-auth/internal/access"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequireServiceRole admits accounts holding at least min on the service in
+// the :id path parameter and leaves the service in c.Locals("service") and
+// the role in c.Locals("serviceRole"). It must follow AccountAuthMiddleware.
+// Accounts with no access at all get the same 404 as for a missing service.
+func (h *Middleware) RequireServiceRole(min models.OrganizationRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+		accountID, err := uuid.Parse(authToken.UserID)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token")
+		}
+
+		serviceID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return utils.SendError(c, fiber.StatusNotFound, "Service not found or not authorized")
+		}
+
+		var service models.Service
+		if err := h.DB.Where("id = ?", serviceID).First(&service).Error; err != nil {
+			return utils.SendError(c, fiber.StatusNotFound, "Service not found or not authorized")
+		}
+
+		role, err := access.ServiceRole(h.DB, &service, accountID)
+		if err != nil {
+			log.Printf("Error resolving service role: %v", err)
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error checking service access")
+		}
+		if role == "" {
+			return utils.SendError(c, fiber.StatusNotFound, "Service not found or not authorized")
+		}
+		if !role.AtLeast(min) {
+			h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceAccessDenied, "insufficient_role").
+				WithAccount(accountID).WithService(service.ID).WithSubject(c.Method()+" "+c.Path()).
+				WithMetadata(map[string]interface{}{"role": role, "required": min}))
+			return utils.SendError(c, fiber.StatusForbidden, "Your role does not allow this action")
+		}
+
+		c.Locals("service", &service)
+		c.Locals("serviceRole", role)
+		return c.Next()
+	}
+}
+
+// RequireOrganizationRole admits members holding at least min in the
+// organization in the :id path parameter and leaves it in
+// c.Locals("organization") and the role in c.Locals("organizationRole"). It
+// must follow AccountAuthMiddleware.
+func (h *Middleware) RequireOrganizationRole(min models.OrganizationRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+		accountID, err := uuid.Parse(authToken.UserID)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token")
+		}
+
+		organizationID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return utils.SendError(c, fiber.StatusNotFound, "Organization not found")
+		}
+
+		role, err := access.OrganizationRole(h.DB, organizationID, accountID)
+		if err != nil {
+			log.Printf("Error resolving organization role: %v", err)
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error checking organization access")
+		}
+		if role == "" {
+			return utils.SendError(c, fiber.StatusNotFound, "Organization not found")
+		}
+
+		var organization models.Organization
+		if err := h.DB.Where("id = ?", organizationID).First(&organization).Error; err != nil {
+			return utils.SendError(c, fiber.StatusNotFound, "Organization not found")
+		}
+
+		if !role.AtLeast(min) {
+			h.Audit.Record(c.Context(), audit.Failure(c, audit.EventOrganizationAccessDenied, "insufficient_role").
+				WithAccount(accountID).WithSubject(organizationID.String()).
+				WithMetadata(map[string]interface{}{"role": role, "required": min, "route": c.Method() + " " + c.Path()}))
+			return utils.SendError(c, fiber.StatusForbidden, "Your role does not allow this action")
+		}
+
+		c.Locals("organization", &organization)
+		c.Locals("organizationRole", role)
+		return c.Next()
+	}
+}
@@ -21,7 +21,6 @@ func InitMiddleware(container *container.Container) *Middleware {
 }
 
 func (h *Middleware) AccountAuthMiddleware(c *fiber.Ctx) error {
-	fmt.Println("ACCOUNT AUTH MIDDLEWARE CALLED for path:", c.Path())
 	authorization := c.Get("Authorization")
 	if authorization == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(response.APIResponse{
@@ -31,12 +30,10 @@ func (h *Middleware) AccountAuthMiddleware(c *fiber.Ctx) error {
 	}
 
 	token := h.Container.JWT.ExtractToken(authorization)
-	fmt.Printf("ACCOUNT Middleware - Received token: %s\n", token) // Debug log
 
 	authToken := &models.AccountAuthorizationToken{}
 
 	if err := h.Container.JWT.ParseAccountAccessToken(token, authToken); err != nil {
-		fmt.Printf("Token parsing error: %v\n", err) // Debug log
 		return c.Status(fiber.StatusUnauthorized).JSON(response.APIResponse{
 			Success: false,
 			Message: fmt.Sprintf("Invalid or expired token: %v", err),
@@ -44,7 +41,6 @@ func (h *Middleware) AccountAuthMiddleware(c *fiber.Ctx) error {
 	}
 
 	if err := authToken.Valid(); err != nil {
-		fmt.Printf("Token validation error: %v\n", err) // Debug log
 		return c.Status(fiber.StatusUnauthorized).JSON(response.APIResponse{
 			Success: false,
 			Message: "Token validation failed",
@@ -56,7 +52,6 @@ func (h *Middleware) AccountAuthMiddleware(c *fiber.Ctx) error {
 }
 
 func (h *Middleware) ServiceAuthMiddleware(c *fiber.Ctx) error {
-	fmt.Println("SERVICE AUTH MIDDLEWARE CALLED for path:", c.Path())
 	authorization := c.Get("Authorization")
 	if authorization == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(response.APIResponse{
@@ -68,8 +63,6 @@ func (h *Middleware) ServiceAuthMiddleware(c *fiber.Ctx) error {
 	// Make sure we remove the Bearer prefix if present
 	token := h.Container.JWT.ExtractToken(authorization)
 
-	fmt.Printf("SERVICE Middleware - Received token: %s\n", token) // Debug log
-
 	// Extract token parts
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
@@ -87,11 +80,8 @@ func (h *Middleware) ServiceAuthMiddleware(c *fiber.Ctx) error {
 	var serviceSecret string
 
 	if err == nil {
-		// Log the claims and check token type
-		fmt.Printf("Token claims: %v\n", rawClaims)
-
+		// Check the token type
 		if _, exists := rawClaims["service_id"]; !exists {
-			log.Printf("Warning: This appears to be an account token, not a service token")
 			return c.Status(fiber.StatusUnauthorized).JSON(response.APIResponse{
 				Success: false,
 				Message: "Invalid token type: expected service token",
@@ -101,23 +91,26 @@ func (h *Middleware) ServiceAuthMiddleware(c *fiber.Ctx) error {
 				// Get the service's secret from the database
 				var service models.Service
 				if err := h.Container.DB.Where("id = ?", serviceID).First(&service).Error; err != nil {
-					fmt.Printf("Error finding service: %v\n", err)
 					return c.Status(fiber.StatusUnauthorized).JSON(response.APIResponse{
 						Success: false,
 						Message: "Invalid service ID in token",
 					})
+				} else if service.DisabledAt != nil {
+					return c.Status(fiber.StatusForbidden).JSON(response.APIResponse{
+						Success: false,
+						Message: "This service has been disabled",
+					})
 				} else {
 					// Decrypt the service secret
 					decryptedSecret, err := h.Container.JWT.DecryptServiceSecretKey(service.SecretKey)
 					if err != nil {
-						fmt.Printf("Error decrypting service secret: %v\n", err)
+						log.Printf("Error decrypting service secret: %v", err)
 						return c.Status(fiber.StatusInternalServerError).JSON(response.APIResponse{
 							Success: false,
 							Message: "Error processing service authentication",
 						})
 					} else {
 						serviceSecret = decryptedSecret
-						fmt.Printf("Successfully retrieved service secret for service ID: %s\n", serviceID)
 					}
 				}
 			}
@@ -129,9 +122,6 @@ func (h *Middleware) ServiceAuthMiddleware(c *fiber.Ctx) error {
 	// If we have the service secret, validate with it
 	if serviceSecret != "" {
 		if err := h.Container.JWT.ParseServiceAccessTokenWithSecret(token, authToken, serviceSecret); err == nil {
-			// Successfully validated with service secret
-			fmt.Printf("Token validated with service-specific secret\n")
-
 			// Validate token expiration
 			if err := authToken.Valid(); err != nil {
 				if strings.Contains(err.Error(), "token is expired") {
@@ -149,8 +139,6 @@ func (h *Middleware) ServiceAuthMiddleware(c *fiber.Ctx) error {
 			c.Locals("auth", authToken)
 			return c.Next()
 		} else {
-			fmt.Printf("Token validation with service-specific secret failed: %v\n", err)
-
 			// Provide specific error messages
 			if strings.Contains(err.Error(), "token is expired") {
 				return c.Status(fiber.StatusUnauthorized).JSON(response.APIResponse{
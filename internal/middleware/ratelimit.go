@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/ratelimit"
+	"aspire-auth/internal/utils"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// rateLimitSubject picks identifiers out of public request bodies that have
+// no auth token yet
+type rateLimitSubject struct {
+	AccountID string `json:"account_id"`
+	Email     string `json:"email"`
+	ServiceID string `json:"service_id"`
+}
+
+// RateLimit enforces the named policies from config.RateLimit.Policies in
+// order. Policies whose subject cannot be determined for a request (e.g. an
+// email policy on a request without an email) are skipped. The most restrictive result is reported in the standard
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+func (h *Middleware) RateLimit(policies ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !h.Config.RateLimit.Enabled {
+			return c.Next()
+		}
+
+		var tightest *ratelimit.Result
+		var body *rateLimitSubject
+
+		for _, name := range policies {
+			policy, ok := h.Config.RateLimit.Policies[name]
+			if !ok {
+				log.Printf("Rate limit policy %q is not configured", name)
+				continue
+			}
+
+			subject := rateLimitSubjectKey(c, policy.Scope, &body)
+			if subject == "" {
+				continue
+			}
+
+			key := fmt.Sprintf("%s:%s:%s", name, policy.Scope, subject)
+			result, err := h.RateLimiter.Allow(c.Context(), key, policy.Limit, policy.Window)
+			if err != nil {
+				log.Printf("Rate limit check failed for %s: %v", name, err)
+				continue
+			}
+
+			if !result.Allowed {
+				setRateLimitHeaders(c, result)
+				return utils.SendTooManyRequests(c, result.Reset, "Too many requests. Please try again later.")
+			}
+
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = &result
+			}
+		}
+
+		if tightest != nil {
+			setRateLimitHeaders(c, *tightest)
+		}
+		return c.Next()
+	}
+}
+
+func rateLimitSubjectKey(c *fiber.Ctx, scope config.RateLimitScope, body **rateLimitSubject) string {
+	switch scope {
+	case config.RateLimitByIP:
+		return c.IP()
+
+	case config.RateLimitByAccount:
+		switch auth := c.Locals("auth").(type) {
+		case *models.AccountAuthorizationToken:
+			return auth.UserID
+		case *models.ServiceAuthorizationToken:
+			return auth.UserID
+		}
+		// Only the routes that act on an account_id take it from the body;
+		// routes that look the account up by email use an email policy
+		return parseRateLimitSubject(c, body).AccountID
+
+	case config.RateLimitByEmail:
+		return utils.NormalizeEmail(parseRateLimitSubject(c, body).Email)
+
+	case config.RateLimitByService:
+		if auth, ok := c.Locals("auth").(*models.ServiceAuthorizationToken); ok {
+			return auth.ServiceID
+		}
+		return parseRateLimitSubject(c, body).ServiceID
+	}
+
+	return ""
+}
+
+func parseRateLimitSubject(c *fiber.Ctx, body **rateLimitSubject) *rateLimitSubject {
+	if *body == nil {
+		*body = &rateLimitSubject{}
+		if len(c.Body()) > 0 {
+			// A malformed body is left for the handler to reject
+			_ = c.BodyParser(*body)
+		}
+	}
+	return *body
+}
+
+func setRateLimitHeaders(c *fiber.Ctx, result ratelimit.Result) {
+	c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/container"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/ratelimit"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitSubjectKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope config.RateLimitScope
+		auth  interface{}
+		body  string
+		want  string
+	}{
+		{name: "ip", scope: config.RateLimitByIP, body: `{"email":"a@example.com"}`, want: "0.0.0.0"},
+		{name: "account from the account token", scope: config.RateLimitByAccount, auth: &models.AccountAuthorizationToken{UserID: "acct"}, body: `{"account_id":"other"}`, want: "acct"},
+		{name: "account from the service token", scope: config.RateLimitByAccount, auth: &models.ServiceAuthorizationToken{UserID: "acct", ServiceID: "svc"}, want: "acct"},
+		{name: "account from the body", scope: config.RateLimitByAccount, body: `{"account_id":"acct"}`, want: "acct"},
+		{name: "account ignores the email", scope: config.RateLimitByAccount, body: `{"email":"a@example.com"}`, want: ""},
+		{name: "email is normalised", scope: config.RateLimitByEmail, body: `{"email":" Bob@Example.COM "}`, want: "bob@example.com"},
+		{name: "email ignores a client account_id", scope: config.RateLimitByEmail, body: `{"email":"bob@example.com","account_id":"random"}`, want: "bob@example.com"},
+		{name: "email missing", scope: config.RateLimitByEmail, body: `{"account_id":"acct"}`, want: ""},
+		{name: "malformed body", scope: config.RateLimitByEmail, body: `{"email":`, want: ""},
+		{name: "service from the token", scope: config.RateLimitByService, auth: &models.ServiceAuthorizationToken{UserID: "acct", ServiceID: "svc"}, body: `{"service_id":"other"}`, want: "svc"},
+		{name: "service from the body", scope: config.RateLimitByService, body: `{"service_id":"svc"}`, want: "svc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Post("/", func(c *fiber.Ctx) error {
+				if tt.auth != nil {
+					c.Locals("auth", tt.auth)
+				}
+				var body *rateLimitSubject
+				return c.SendString(rateLimitSubjectKey(c, tt.scope, &body))
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			if string(got) != tt.want {
+				t.Fatalf("subject = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	m := &Middleware{&container.Container{
+		Config: &config.Config{RateLimit: config.RateLimitConfig{
+			Enabled: true,
+			Policies: map[string]config.RateLimitPolicy{
+				"forgot_password_email": {Scope: config.RateLimitByEmail, Limit: 2, Window: time.Minute},
+			},
+		}},
+		RateLimiter: ratelimit.NewMemoryLimiter(),
+	}}
+	app := fiber.New()
+	app.Post("/forgot-password", m.RateLimit("forgot_password_email"), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusAccepted)
+	})
+
+	// Varying the account_id must not buy a fresh window
+	bodies := []string{
+		`{"email":"bob@example.com","account_id":"1"}`,
+		`{"email":"Bob@example.com","account_id":"2"}`,
+		`{"email":"bob@example.com","account_id":"3"}`,
+	}
+	wants := []int{fiber.StatusAccepted, fiber.StatusAccepted, fiber.StatusTooManyRequests}
+	for i, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/forgot-password", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != wants[i] {
+			t.Fatalf("request %d: status %d, want %d", i+1, resp.StatusCode, wants[i])
+		}
+		if resp.Header.Get("RateLimit-Limit") != "2" {
+			t.Fatalf("request %d: RateLimit-Limit = %q", i+1, resp.Header.Get("RateLimit-Limit"))
+		}
+	}
+}
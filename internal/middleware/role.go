@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequireRole admits accounts holding role. It must follow
+// AccountAuthMiddleware. The token's claim is only a first filter: the role
+// is re-read from the database so a demoted or disabled account loses access
+// immediately rather than when its access token expires.
+func (h *Middleware) RequireRole(role models.RoleType) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+		accountID, err := uuid.Parse(authToken.UserID)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token")
+		}
+
+		var account models.Account
+		found := authToken.RoleType == role &&
+			h.DB.Select("id", "role_type", "disabled_at").Where("id = ?", accountID).First(&account).Error == nil
+		if !found || account.RoleType != role || account.DisabledAt != nil {
+			h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAdminAccessDenied, "insufficient_role").
+				WithAccount(accountID).WithSubject(c.Method()+" "+c.Path()))
+			return utils.SendError(c, fiber.StatusForbidden, "Forbidden")
+		}
+
+		return c.Next()
+	}
+}
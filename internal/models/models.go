@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -33,25 +34,87 @@ type Account struct {
 	RoleType       RoleType    `gorm:"type:text;default:'USER'" json:"role_type"`
 	IsVerified     bool        `gorm:"default:false" json:"is_verified"`
 	Avatar         *string     `gorm:"type:text" json:"avatar,omitempty"`
-	CreatedAt      time.Time   `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
-	UpdatedAt      time.Time   `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+	Locale         *string     `gorm:"type:text" json:"locale,omitempty"`
+	PhoneNumber    *string     `gorm:"type:text;uniqueIndex" json:"phone_number,omitempty"`
+	PhoneVerified  bool        `gorm:"default:false" json:"phone_verified"`
+	OTPChannel     string      `gorm:"type:text;default:'email'" json:"otp_channel"`
+	MFAEnabled     bool        `gorm:"default:false" json:"mfa_enabled"`
+	// DeletionScheduledAt is when a requested deletion will be carried out;
+	// services it still owns then are deleted with it
+	DeletionScheduledAt *time.Time `gorm:"type:timestamp;index" json:"deletion_scheduled_at,omitempty"`
+	// DisabledAt is set while a platform admin has disabled the account
+	DisabledAt *time.Time `gorm:"type:timestamp" json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
 }
 
+// Service is managed by its owner, or, once it belongs to an organization,
+// by that organization's members according to their roles. OwnerID then
+// only records the accountable account and grants nothing by itself.
 type Service struct {
-	ID                 uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	OwnerID            uuid.UUID `gorm:"type:uuid" json:"owner_id"`
-	ServiceName        string    `gorm:"type:text;not null" json:"service_name"`
-	ServiceLogo        *string   `gorm:"type:text" json:"service_logo,omitempty"`
-	SecretKey          string    `gorm:"type:uuid;not null"`
-	ServiceDescription *string   `gorm:"type:text" json:"service_description,omitempty"`
-	CreatedAt          time.Time `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
-	UpdatedAt          time.Time `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+	ID                 uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OwnerID            uuid.UUID  `gorm:"type:uuid" json:"owner_id"`
+	OrganizationID     *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	ServiceName        string     `gorm:"type:text;not null" json:"service_name"`
+	ServiceLogo        *string    `gorm:"type:text" json:"service_logo,omitempty"`
+	SecretKey          string     `gorm:"type:text;not null" json:"-"`
+	ServiceDescription *string    `gorm:"type:text" json:"service_description,omitempty"`
+	BrandColor         *string    `gorm:"type:text" json:"brand_color,omitempty"`
+	// DisabledAt is set while a platform admin has disabled the service
+	DisabledAt *time.Time `gorm:"type:timestamp" json:"disabled_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
 
 	// Add relationships
 	Owner Account        `gorm:"foreignKey:OwnerID"`
 	Users []ServicesUser `gorm:"foreignKey:ServiceID"`
 }
 
+type OrganizationRole string
+
+const (
+	OrganizationOwner     OrganizationRole = "OWNER"
+	OrganizationAdmin     OrganizationRole = "ADMIN"
+	OrganizationDeveloper OrganizationRole = "DEVELOPER"
+	OrganizationViewer    OrganizationRole = "VIEWER"
+)
+
+var organizationRoleRank = map[OrganizationRole]int{
+	OrganizationViewer:    1,
+	OrganizationDeveloper: 2,
+	OrganizationAdmin:     3,
+	OrganizationOwner:     4,
+}
+
+// Valid reports whether r is one of the known roles.
+func (r OrganizationRole) Valid() bool {
+	return organizationRoleRank[r] > 0
+}
+
+// AtLeast reports whether r grants everything min does. The empty role, no
+// membership at all, grants nothing.
+func (r OrganizationRole) AtLeast(min OrganizationRole) bool {
+	return organizationRoleRank[r] > 0 && organizationRoleRank[r] >= organizationRoleRank[min]
+}
+
+// Organization groups services so that they are managed by a team rather
+// than by whoever created them.
+type Organization struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name      string    `gorm:"type:text;not null" json:"name"`
+	CreatedAt time.Time `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
+type OrganizationMember struct {
+	ID             uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OrganizationID uuid.UUID        `gorm:"type:uuid;not null" json:"organization_id"`
+	AccountID      uuid.UUID        `gorm:"type:uuid;not null" json:"account_id"`
+	Role           OrganizationRole `gorm:"type:text;not null" json:"role"`
+	CreatedAt      time.Time        `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt      time.Time        `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
 type ServicesUser struct {
 	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	ServiceID  uuid.UUID `gorm:"type:uuid" json:"service_id"`
@@ -88,6 +151,164 @@ type ServiceRefreshToken struct {
 	UpdatedAt    time.Time `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
 }
 
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "SUCCESS"
+	AuditFailure AuditOutcome = "FAILURE"
+)
+
+// AuditEvent is an append-only record of a security-relevant action. Reason
+// carries the detailed cause that public responses deliberately hide.
+type AuditEvent struct {
+	ID        uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	EventType string       `gorm:"type:text;not null;index" json:"event_type"`
+	Outcome   AuditOutcome `gorm:"type:text;not null" json:"outcome"`
+	AccountID *uuid.UUID   `gorm:"type:uuid;index" json:"account_id,omitempty"`
+	ServiceID *uuid.UUID   `gorm:"type:uuid;index" json:"service_id,omitempty"`
+	Subject   string       `gorm:"type:text" json:"subject,omitempty"`
+	Reason    string       `gorm:"type:text" json:"reason,omitempty"`
+	IPAddress string       `gorm:"type:text" json:"ip_address,omitempty"`
+	UserAgent string       `gorm:"type:text" json:"user_agent,omitempty"`
+	Metadata  *string      `gorm:"type:jsonb" json:"metadata,omitempty"`
+	CreatedAt time.Time    `gorm:"type:timestamp;default:current_timestamp;index" json:"created_at"`
+}
+
+// MarshalJSON writes Metadata as the JSON object it holds rather than as a
+// string containing it.
+func (e AuditEvent) MarshalJSON() ([]byte, error) {
+	type plain AuditEvent
+	var metadata json.RawMessage
+	if e.Metadata != nil {
+		metadata = json.RawMessage(*e.Metadata)
+	}
+	return json.Marshal(struct {
+		plain
+		Metadata json.RawMessage `json:"metadata,omitempty"`
+	}{plain(e), metadata})
+}
+
+type OutboxEmailStatus string
+
+const (
+	OutboxPending OutboxEmailStatus = "PENDING"
+	OutboxSent    OutboxEmailStatus = "SENT"
+	OutboxFailed  OutboxEmailStatus = "FAILED"
+)
+
+// OutboxEmail is a rendered message waiting for the background sender.
+// Writing it in the same transaction as the change that triggered it means
+// a mail outage can no longer roll that change back.
+type OutboxEmail struct {
+	ID            uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Recipient     string            `gorm:"type:text;not null" json:"recipient"`
+	Subject       string            `gorm:"type:text;not null" json:"subject"`
+	HTMLBody      string            `gorm:"type:text;not null" json:"-"`
+	TextBody      string            `gorm:"type:text" json:"-"`
+	Status        OutboxEmailStatus `gorm:"type:text;not null;default:PENDING" json:"status"`
+	Attempts      int               `gorm:"not null;default:0" json:"attempts"`
+	LastError     string            `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time         `gorm:"type:timestamp;not null" json:"next_attempt_at"`
+	SentAt        *time.Time        `gorm:"type:timestamp" json:"sent_at,omitempty"`
+	CreatedAt     time.Time         `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt     time.Time         `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
+type DomainEventStatus string
+
+const (
+	DomainEventPending   DomainEventStatus = "PENDING"
+	DomainEventProcessed DomainEventStatus = "PROCESSED"
+	DomainEventFailed    DomainEventStatus = "FAILED"
+)
+
+// DomainEvent is an entry in the event outbox. Handlers write it in the
+// transaction that made the change; the bus then hands it to every
+// subscriber, remembering in Completed which of them have succeeded so a
+// retry only repeats the ones that failed.
+type DomainEvent struct {
+	ID            uuid.UUID         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	EventType     string            `gorm:"type:text;not null" json:"event_type"`
+	Payload       string            `gorm:"type:jsonb;not null" json:"-"`
+	Audit         *string           `gorm:"type:jsonb" json:"-"`
+	Status        DomainEventStatus `gorm:"type:text;not null;default:PENDING" json:"status"`
+	Completed     string            `gorm:"type:text;not null;default:''" json:"completed"`
+	Attempts      int               `gorm:"not null;default:0" json:"attempts"`
+	LastError     string            `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time         `gorm:"type:timestamp;not null" json:"next_attempt_at"`
+	ProcessedAt   *time.Time        `gorm:"type:timestamp" json:"processed_at,omitempty"`
+	CreatedAt     time.Time         `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt     time.Time         `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
+type AccountExportStatus string
+
+const (
+	ExportPending    AccountExportStatus = "PENDING"
+	ExportProcessing AccountExportStatus = "PROCESSING"
+	ExportReady      AccountExportStatus = "READY"
+	ExportFailed     AccountExportStatus = "FAILED"
+)
+
+// AccountExport is a personal data archive requested by an account holder.
+// The background worker stores it under StorageKey; it is removed at ExpiresAt.
+type AccountExport struct {
+	ID          uuid.UUID           `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AccountID   uuid.UUID           `gorm:"type:uuid;not null;index" json:"account_id"`
+	Status      AccountExportStatus `gorm:"type:text;not null;default:PENDING" json:"status"`
+	StorageKey  *string             `gorm:"type:text" json:"-"`
+	SizeBytes   int64               `gorm:"not null;default:0" json:"size_bytes"`
+	LastError   string              `gorm:"type:text" json:"-"`
+	CompletedAt *time.Time          `gorm:"type:timestamp" json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time          `gorm:"type:timestamp" json:"expires_at,omitempty"`
+	CreatedAt   time.Time           `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt   time.Time           `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
+// Webhook subscribes a service to user events. Events is a comma-separated
+// list from webhook.Catalog. Secret signs deliveries and is stored encrypted
+// like service secrets. FailingSince marks the first failure since the last
+// success; an endpoint failing for long enough gets DisabledAt set.
+type Webhook struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	ServiceID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"service_id"`
+	URL            string     `gorm:"type:text;not null" json:"url"`
+	Events         string     `gorm:"type:text;not null" json:"-"`
+	Secret         string     `gorm:"type:text;not null" json:"-"`
+	FailingSince   *time.Time `gorm:"type:timestamp" json:"failing_since,omitempty"`
+	DisabledAt     *time.Time `gorm:"type:timestamp" json:"disabled_at,omitempty"`
+	DisabledReason string     `gorm:"type:text" json:"disabled_reason,omitempty"`
+	CreatedAt      time.Time  `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDelivered WebhookDeliveryStatus = "DELIVERED"
+	WebhookFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+// WebhookDelivery is one event queued for one webhook, and afterwards its
+// entry in the delivery log. Redeliveries are new rows sharing EventID, so
+// receivers can recognise duplicates.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	WebhookID      uuid.UUID             `gorm:"type:uuid;not null;index" json:"webhook_id"`
+	EventID        uuid.UUID             `gorm:"type:uuid;not null" json:"event_id"`
+	EventType      string                `gorm:"type:text;not null" json:"event_type"`
+	Payload        string                `gorm:"type:jsonb;not null" json:"-"`
+	Status         WebhookDeliveryStatus `gorm:"type:text;not null;default:PENDING" json:"status"`
+	Attempts       int                   `gorm:"not null;default:0" json:"attempts"`
+	ResponseStatus int                   `gorm:"not null;default:0" json:"response_status,omitempty"`
+	LastError      string                `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt  time.Time             `gorm:"type:timestamp;not null" json:"next_attempt_at"`
+	DeliveredAt    *time.Time            `gorm:"type:timestamp" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time             `gorm:"type:timestamp;default:current_timestamp" json:"created_at"`
+	UpdatedAt      time.Time             `gorm:"type:timestamp;default:current_timestamp" json:"updated_at"`
+}
+
 type AccountAuthorizationToken struct {
 	baseClaims
 	UserID    string   `json:"user_id"`
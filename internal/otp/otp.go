@@ -0,0 +1,297 @@
+package otp
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Purpose namespaces codes so a code issued for one flow can never be used in another.
+type Purpose string
+
+const (
+	PurposeAccountVerification Purpose = "account_verification"
+	PurposePasswordReset       Purpose = "password_reset"
+	PurposeEmailChange         Purpose = "email_change"
+	PurposePhoneVerification   Purpose = "phone_verification"
+	PurposeMFA                 Purpose = "mfa"
+)
+
+var (
+	ErrInvalidCode     = errors.New("invalid code")
+	ErrExpired         = errors.New("code expired or not found")
+	ErrTooManyAttempts = errors.New("too many attempts")
+)
+
+// RateLimitError is returned by Generate when a new code may not be sent yet.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("code requested too often, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// Service issues and verifies one-time codes. Only a salted HMAC of each code
+// is stored in Redis, together with a counter of verification attempts.
+type Service struct {
+	redis  *redis.Client
+	config config.OTPConfig
+}
+
+func NewService(redis *redis.Client, cfg config.OTPConfig) *Service {
+	return &Service{redis: redis, config: cfg}
+}
+
+func codeKey(purpose Purpose, subject string) string {
+	return fmt.Sprintf("otp:%s:%s", purpose, subject)
+}
+
+func sendsKey(purpose Purpose, subject string) string {
+	return fmt.Sprintf("otp:sends:%s:%s", purpose, subject)
+}
+
+func cooldownKey(purpose Purpose, subject string) string {
+	return fmt.Sprintf("otp:cooldown:%s:%s", purpose, subject)
+}
+
+// Generate creates a fresh code for subject, replacing any previous one.
+// It returns a *RateLimitError when the subject asked for codes too often.
+func (s *Service) Generate(ctx context.Context, purpose Purpose, subject string) (string, error) {
+	if err := s.ThrottleSend(ctx, purpose, subject); err != nil {
+		return "", err
+	}
+
+	code, err := randomCode(s.config.Length)
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := codeKey(purpose, subject)
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key,
+		"hash", hashCode(salt, purpose, subject, code),
+		"salt", hex.EncodeToString(salt),
+		"attempts", 0,
+	)
+	pipe.Expire(ctx, key, s.config.TTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to store code: %w", err)
+	}
+
+	return code, nil
+}
+
+// RecordChannel notes which channel the current code for subject was sent
+// over, so VerifyChannel can report it.
+func (s *Service) RecordChannel(ctx context.Context, purpose Purpose, subject string, channel Channel) error {
+	err := recordChannelScript.Run(ctx, s.redis, []string{codeKey(purpose, subject)}, string(channel)).Err()
+	if err == redis.Nil {
+		return ErrExpired
+	}
+	return err
+}
+
+// attemptScript counts a verification attempt and returns the attempts so
+// far with the stored hash, salt and channel, or nil when there is no code.
+// It only touches a code that still exists, so an attempt racing the code's
+// expiry cannot leave behind a counter that never expires.
+var attemptScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return false
+end
+local attempts = redis.call("HINCRBY", KEYS[1], "attempts", 1)
+local stored = redis.call("HMGET", KEYS[1], "hash", "salt", "channel")
+return {tostring(attempts), stored[1] or "", stored[2] or "", stored[3] or ""}
+`)
+
+// recordChannelScript sets the channel of a code that still exists.
+var recordChannelScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return false
+end
+return redis.call("HSET", KEYS[1], "channel", ARGV[1])
+`)
+
+// Verify checks code against the stored hash. A successful check consumes
+// the code; exceeding MaxAttempts discards it so a new one must be requested.
+func (s *Service) Verify(ctx context.Context, purpose Purpose, subject, code string) error {
+	_, err := s.VerifyChannel(ctx, purpose, subject, code)
+	return err
+}
+
+// VerifyChannel is Verify that also returns the channel recorded for the
+// code, or ChannelEmail when none was recorded.
+func (s *Service) VerifyChannel(ctx context.Context, purpose Purpose, subject, code string) (Channel, error) {
+	channel, hash, err := s.check(ctx, purpose, subject, code)
+	if err != nil {
+		return "", err
+	}
+	if err := s.consume(ctx, purpose, subject, hash); err != nil {
+		return "", err
+	}
+	return channel, nil
+}
+
+// Check is Verify without consuming a correct code, for callers that may
+// still turn the request down and let the user retry with the same code.
+// Wrong guesses count towards MaxAttempts all the same. A caller that goes
+// on to act on the code must Consume it first.
+func (s *Service) Check(ctx context.Context, purpose Purpose, subject, code string) error {
+	_, _, err := s.check(ctx, purpose, subject, code)
+	return err
+}
+
+// Consume deletes a code that passed Check. Of several requests holding the
+// same code only one succeeds; the others, and any request whose code was
+// replaced in the meantime, get ErrExpired.
+func (s *Service) Consume(ctx context.Context, purpose Purpose, subject, code string) error {
+	encoded, err := s.redis.HGet(ctx, codeKey(purpose, subject), "salt").Result()
+	if err == redis.Nil {
+		return ErrExpired
+	} else if err != nil {
+		return fmt.Errorf("failed to load code: %w", err)
+	}
+	salt, err := hex.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("corrupt stored code: %w", err)
+	}
+	return s.consume(ctx, purpose, subject, hashCode(salt, purpose, subject, code))
+}
+
+// consumeScript deletes a code only while it is the one with the given
+// hash, reporting whether it did.
+var consumeScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "hash") == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (s *Service) consume(ctx context.Context, purpose Purpose, subject, hash string) error {
+	deleted, err := consumeScript.Run(ctx, s.redis, []string{codeKey(purpose, subject)}, hash).Int()
+	if err != nil {
+		return fmt.Errorf("failed to consume code: %w", err)
+	}
+	if deleted != 1 {
+		return ErrExpired
+	}
+	return nil
+}
+
+func (s *Service) check(ctx context.Context, purpose Purpose, subject, code string) (Channel, string, error) {
+	key := codeKey(purpose, subject)
+
+	// Count the attempt before comparing so concurrent guesses cannot exceed the limit
+	result, err := attemptScript.Run(ctx, s.redis, []string{key}).StringSlice()
+	if err == redis.Nil {
+		return "", "", ErrExpired
+	} else if err != nil {
+		return "", "", fmt.Errorf("failed to count attempt: %w", err)
+	}
+	stored := map[string]string{"hash": result[1], "salt": result[2], "channel": result[3]}
+	attempts, err := strconv.ParseInt(result[0], 10, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("corrupt stored code: %w", err)
+	}
+	if attempts > int64(s.config.MaxAttempts) {
+		s.redis.Del(ctx, key)
+		return "", "", ErrTooManyAttempts
+	}
+
+	salt, err := hex.DecodeString(stored["salt"])
+	if err != nil {
+		return "", "", fmt.Errorf("corrupt stored code: %w", err)
+	}
+
+	expected := stored["hash"]
+	actual := hashCode(salt, purpose, subject, code)
+	if !hmac.Equal([]byte(expected), []byte(actual)) {
+		if attempts == int64(s.config.MaxAttempts) {
+			s.redis.Del(ctx, key)
+			return "", "", ErrTooManyAttempts
+		}
+		return "", "", ErrInvalidCode
+	}
+
+	if channel := Channel(stored["channel"]); channel != "" {
+		return channel, expected, nil
+	}
+	return ChannelEmail, expected, nil
+}
+
+// Invalidate discards any outstanding code for subject.
+func (s *Service) Invalidate(ctx context.Context, purpose Purpose, subject string) error {
+	return s.redis.Del(ctx, codeKey(purpose, subject)).Err()
+}
+
+// sendScript counts a send and starts the window with the first one, so the
+// counter cannot be left behind without an expiry.
+var sendScript = redis.NewScript(`
+local sends = redis.call("INCR", KEYS[1])
+if sends == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return sends
+`)
+
+// ThrottleSend applies the resend cooldown and send cap without issuing a
+// code. Callers use it for unknown subjects so the rate limit behaves the
+// same whether or not the subject exists.
+func (s *Service) ThrottleSend(ctx context.Context, purpose Purpose, subject string) error {
+	if s.config.ResendCooldown > 0 {
+		ok, err := s.redis.SetNX(ctx, cooldownKey(purpose, subject), 1, s.config.ResendCooldown).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check resend cooldown: %w", err)
+		}
+		if !ok {
+			ttl, _ := s.redis.PTTL(ctx, cooldownKey(purpose, subject)).Result()
+			return &RateLimitError{RetryAfter: ttl}
+		}
+	}
+
+	if s.config.MaxSends > 0 {
+		sends, err := sendScript.Run(ctx, s.redis, []string{sendsKey(purpose, subject)}, s.config.SendWindow.Milliseconds()).Int64()
+		if err != nil {
+			return fmt.Errorf("failed to count sends: %w", err)
+		}
+		if sends > int64(s.config.MaxSends) {
+			ttl, _ := s.redis.PTTL(ctx, sendsKey(purpose, subject)).Result()
+			return &RateLimitError{RetryAfter: ttl}
+		}
+	}
+
+	return nil
+}
+
+// randomCode returns a uniformly distributed numeric code of the given length
+func randomCode(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+func hashCode(salt []byte, purpose Purpose, subject, code string) string {
+	mac := hmac.New(sha256.New, salt)
+	fmt.Fprintf(mac, "%s\x00%s\x00%s", purpose, subject, code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
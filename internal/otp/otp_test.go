@@ -0,0 +1,320 @@
+package otp
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestService(t *testing.T, cfg config.OTPConfig) (*Service, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewService(client, cfg), mr
+}
+
+var testConfig = config.OTPConfig{
+	Length:      6,
+	TTL:         10 * time.Minute,
+	MaxAttempts: 3,
+}
+
+func TestGenerateStoresOnlyHash(t *testing.T) {
+	s, mr := newTestService(t, testConfig)
+	code, err := s.Generate(context.Background(), PurposePasswordReset, "subject")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(code) != testConfig.Length || strings.Trim(code, "0123456789") != "" {
+		t.Fatalf("code %q is not %d digits", code, testConfig.Length)
+	}
+
+	key := codeKey(PurposePasswordReset, "subject")
+	fields, err := mr.HKeys(key)
+	if err != nil {
+		t.Fatalf("HKeys: %v", err)
+	}
+	for _, field := range fields {
+		if strings.Contains(mr.HGet(key, field), code) {
+			t.Errorf("field %q stores the code in plaintext", field)
+		}
+	}
+	if ttl := mr.TTL(key); ttl != testConfig.TTL {
+		t.Errorf("TTL = %s, want %s", ttl, testConfig.TTL)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		guesses []string // "" stands for the right code
+		want    []error
+	}{
+		{"right code", []string{""}, []error{nil}},
+		{"right code is single use", []string{"", ""}, []error{nil, ErrExpired}},
+		{"wrong then right", []string{"x", ""}, []error{ErrInvalidCode, nil}},
+		{"last attempt discards the code", []string{"x", "x", "x", ""}, []error{ErrInvalidCode, ErrInvalidCode, ErrTooManyAttempts, ErrExpired}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t, testConfig)
+			ctx := context.Background()
+			code, err := s.Generate(ctx, PurposeMFA, "subject")
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			for i, guess := range tt.guesses {
+				if guess == "" {
+					guess = code
+				}
+				if err := s.Verify(ctx, PurposeMFA, "subject", guess); !errors.Is(err, tt.want[i]) {
+					t.Fatalf("attempt %d: Verify = %v, want %v", i+1, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyIsBoundToPurposeAndSubject(t *testing.T) {
+	s, _ := newTestService(t, testConfig)
+	ctx := context.Background()
+	code, err := s.Generate(ctx, PurposePasswordReset, "subject")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if err := s.Verify(ctx, PurposeMFA, "subject", code); !errors.Is(err, ErrExpired) {
+		t.Errorf("other purpose: Verify = %v, want %v", err, ErrExpired)
+	}
+	if err := s.Verify(ctx, PurposePasswordReset, "other", code); !errors.Is(err, ErrExpired) {
+		t.Errorf("other subject: Verify = %v, want %v", err, ErrExpired)
+	}
+	if err := s.Verify(ctx, PurposePasswordReset, "subject", code); err != nil {
+		t.Errorf("Verify = %v, want nil", err)
+	}
+}
+
+func TestVerifyAfterExpiry(t *testing.T) {
+	s, mr := newTestService(t, testConfig)
+	ctx := context.Background()
+	code, err := s.Generate(ctx, PurposeMFA, "subject")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	mr.FastForward(testConfig.TTL)
+	if err := s.Verify(ctx, PurposeMFA, "subject", code); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Verify = %v, want %v", err, ErrExpired)
+	}
+	if err := s.RecordChannel(ctx, PurposeMFA, "subject", ChannelSMS); !errors.Is(err, ErrExpired) {
+		t.Fatalf("RecordChannel = %v, want %v", err, ErrExpired)
+	}
+	if mr.Exists(codeKey(PurposeMFA, "subject")) {
+		t.Error("expired code was recreated without a TTL")
+	}
+}
+
+func TestVerifyChannel(t *testing.T) {
+	s, _ := newTestService(t, testConfig)
+	ctx := context.Background()
+
+	code, _ := s.Generate(ctx, PurposeAccountVerification, "subject")
+	if channel, err := s.VerifyChannel(ctx, PurposeAccountVerification, "subject", code); err != nil || channel != ChannelEmail {
+		t.Errorf("no channel recorded: got %q, %v; want %q", channel, err, ChannelEmail)
+	}
+
+	code, _ = s.Generate(ctx, PurposeAccountVerification, "subject")
+	if err := s.RecordChannel(ctx, PurposeAccountVerification, "subject", ChannelSMS); err != nil {
+		t.Fatalf("RecordChannel: %v", err)
+	}
+	if channel, err := s.VerifyChannel(ctx, PurposeAccountVerification, "subject", code); err != nil || channel != ChannelSMS {
+		t.Errorf("sms recorded: got %q, %v; want %q", channel, err, ChannelSMS)
+	}
+}
+
+func TestThrottleSend(t *testing.T) {
+	s, mr := newTestService(t, config.OTPConfig{
+		Length:         6,
+		TTL:            10 * time.Minute,
+		MaxAttempts:    3,
+		ResendCooldown: time.Minute,
+		MaxSends:       2,
+		SendWindow:     time.Hour,
+	})
+	ctx := context.Background()
+
+	if _, err := s.Generate(ctx, PurposeMFA, "subject"); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+
+	var limited *RateLimitError
+	if _, err := s.Generate(ctx, PurposeMFA, "subject"); !errors.As(err, &limited) {
+		t.Fatalf("send within cooldown = %v, want RateLimitError", err)
+	}
+	if limited.RetryAfter <= 0 || limited.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %s, want within the cooldown", limited.RetryAfter)
+	}
+
+	mr.FastForward(time.Minute)
+	if _, err := s.Generate(ctx, PurposeMFA, "subject"); err != nil {
+		t.Fatalf("send after cooldown: %v", err)
+	}
+
+	mr.FastForward(time.Minute)
+	if _, err := s.Generate(ctx, PurposeMFA, "subject"); !errors.As(err, &limited) {
+		t.Fatalf("send over the cap = %v, want RateLimitError", err)
+	}
+
+	if err := s.ThrottleSend(ctx, PurposeMFA, "other"); err != nil {
+		t.Errorf("other subject throttled: %v", err)
+	}
+}
+
+func TestCheckKeepsCode(t *testing.T) {
+	s, _ := newTestService(t, testConfig)
+	ctx := context.Background()
+	code, err := s.Generate(ctx, PurposePasswordReset, "subject")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if err := s.Check(ctx, PurposePasswordReset, "subject", code); err != nil {
+		t.Fatalf("Check = %v, want nil", err)
+	}
+	if err := s.Check(ctx, PurposePasswordReset, "subject", "x"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("wrong code: Check = %v, want %v", err, ErrInvalidCode)
+	}
+	if err := s.Check(ctx, PurposePasswordReset, "subject", code); err != nil {
+		t.Fatalf("Check on the last attempt = %v, want nil", err)
+	}
+	// Correct checks count as attempts too, so a kept code cannot be
+	// checked forever
+	if err := s.Check(ctx, PurposePasswordReset, "subject", code); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("Check past the limit = %v, want %v", err, ErrTooManyAttempts)
+	}
+}
+
+func TestSendWindowStartsWithFirstSend(t *testing.T) {
+	s, mr := newTestService(t, config.OTPConfig{MaxSends: 2, SendWindow: time.Hour})
+	ctx := context.Background()
+	key := sendsKey(PurposeMFA, "subject")
+
+	tests := []struct {
+		name    string
+		wantErr bool
+		wantTTL time.Duration
+	}{
+		{name: "first send starts the window", wantTTL: time.Hour},
+		{name: "second send keeps it", wantTTL: time.Hour - time.Minute},
+		{name: "over the cap", wantErr: true, wantTTL: time.Hour - 2*time.Minute},
+	}
+
+	for _, tt := range tests {
+		err := s.ThrottleSend(ctx, PurposeMFA, "subject")
+		var limited *RateLimitError
+		if tt.wantErr != errors.As(err, &limited) {
+			t.Fatalf("%s: ThrottleSend = %v", tt.name, err)
+		}
+		if ttl := mr.TTL(key); ttl != tt.wantTTL {
+			t.Fatalf("%s: TTL = %s, want %s", tt.name, ttl, tt.wantTTL)
+		}
+		mr.FastForward(time.Minute)
+	}
+
+	// The window ends and the count starts over
+	mr.FastForward(time.Hour)
+	if err := s.ThrottleSend(ctx, PurposeMFA, "subject"); err != nil {
+		t.Fatalf("after the window: ThrottleSend = %v", err)
+	}
+}
+
+func TestConcurrentVerifyConsumesOnce(t *testing.T) {
+	s, _ := newTestService(t, config.OTPConfig{Length: 6, TTL: time.Minute, MaxAttempts: 20})
+	ctx := context.Background()
+	code, err := s.Generate(ctx, PurposeMFA, "subject")
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Verify(ctx, PurposeMFA, "subject", code); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrExpired) {
+				t.Errorf("Verify = %v, want nil or %v", err, ErrExpired)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("%d requests verified the code, want 1", succeeded)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare runs between Check and Consume and returns the code to consume
+		prepare func(t *testing.T, s *Service, code string) string
+		wantErr error
+	}{
+		{name: "checked code", prepare: func(t *testing.T, s *Service, code string) string { return code }},
+		{name: "already consumed", prepare: func(t *testing.T, s *Service, code string) string {
+			if err := s.Consume(context.Background(), PurposePasswordReset, "subject", code); err != nil {
+				t.Fatalf("first Consume: %v", err)
+			}
+			return code
+		}, wantErr: ErrExpired},
+		{name: "replaced by a new code", prepare: func(t *testing.T, s *Service, code string) string {
+			if _, err := s.Generate(context.Background(), PurposePasswordReset, "subject"); err != nil {
+				t.Fatal(err)
+			}
+			return code
+		}, wantErr: ErrExpired},
+		{name: "invalidated", prepare: func(t *testing.T, s *Service, code string) string {
+			s.Invalidate(context.Background(), PurposePasswordReset, "subject")
+			return code
+		}, wantErr: ErrExpired},
+		{name: "other code", prepare: func(t *testing.T, s *Service, code string) string { return code + "0" }, wantErr: ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t, testConfig)
+			ctx := context.Background()
+			code, err := s.Generate(ctx, PurposePasswordReset, "subject")
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if err := s.Check(ctx, PurposePasswordReset, "subject", code); err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+
+			err = s.Consume(ctx, PurposePasswordReset, "subject", tt.prepare(t, s, code))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Consume = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if err := s.Check(ctx, PurposePasswordReset, "subject", code); !errors.Is(err, ErrExpired) {
+					t.Fatalf("Check after Consume = %v, want %v", err, ErrExpired)
+				}
+			}
+		})
+	}
+}
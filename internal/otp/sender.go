@@ -0,0 +1,50 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Channel is how a code reaches its owner.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+var (
+	ErrUnknownChannel = errors.New("unknown OTP channel")
+	ErrNoPhoneNumber  = errors.New("no phone number to send the code to")
+)
+
+// ParseChannel accepts "email" or "sms". An empty value yields fallback.
+func ParseChannel(value string, fallback Channel) (Channel, error) {
+	switch Channel(value) {
+	case "":
+		return fallback, nil
+	case ChannelEmail, ChannelSMS:
+		return Channel(value), nil
+	default:
+		return "", ErrUnknownChannel
+	}
+}
+
+// Delivery is one code to send. Each channel uses the address it needs;
+// Link is an optional one-click alternative only email can carry.
+type Delivery struct {
+	Purpose Purpose
+	Code    string
+	TTL     time.Duration
+	Email   string
+	Phone   string
+	Link    string
+}
+
+// OTPSender delivers codes over a single channel. Senders are built per
+// request so they can carry the recipient's language and branding.
+type OTPSender interface {
+	Channel() Channel
+	Send(ctx context.Context, delivery Delivery) error
+}
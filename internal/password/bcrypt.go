@@ -0,0 +1,42 @@
+package password
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxBytes is the longest password bcrypt accepts. It would only
+// read this much, so x/crypto rejects anything longer.
+const bcryptMaxBytes = 72
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func hashBcrypt(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func verifyBcrypt(password, encoded string) (int, error) {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return 0, ErrUnsupportedHash
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return cost, ErrMismatch
+	}
+	if err != nil {
+		return cost, ErrUnsupportedHash
+	}
+	return cost, nil
+}
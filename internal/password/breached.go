@@ -0,0 +1,66 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BreachedChecker looks passwords up in an offline copy of the Have I Been
+// Pwned range files. Only the file for the hash prefix is read, so the data
+// set never has to fit in memory.
+type BreachedChecker struct {
+	dir      string
+	minCount int
+}
+
+func NewBreachedChecker(dir string, minCount int) (*BreachedChecker, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if minCount < 1 {
+		minCount = 1
+	}
+	return &BreachedChecker{dir: dir, minCount: minCount}, nil
+}
+
+// IsBreached reports whether password appears at least minCount times. A
+// missing range file means no known breach for that prefix.
+func (b *BreachedChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, found := strings.Cut(line, ":")
+		if !found || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return false, fmt.Errorf("malformed entry in %s.txt: %q", prefix, line)
+		}
+		return n >= b.minCount, nil
+	}
+	return false, scanner.Err()
+}
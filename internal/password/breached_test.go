@@ -0,0 +1,86 @@
+package password
+
+import (
+	"aspire-auth/internal/config"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRange adds password to an HIBP-style range file with count sightings.
+func writeRange(t *testing.T, dir, password string, count string) {
+	t.Helper()
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	file := filepath.Join(dir, hash[:5]+".txt")
+
+	existing, _ := os.ReadFile(file)
+	// Real range files mix other suffixes in and may use lowercase and CRLF
+	line := "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n" + strings.ToLower(hash[5:]) + ":" + count + "\r\n"
+	if err := os.WriteFile(file, append(existing, line...), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBreachedChecker(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "seen-once", "1")
+	writeRange(t, dir, "seen-often", "5000")
+	writeRange(t, dir, "corrupt", "many")
+
+	tests := []struct {
+		name     string
+		minCount int
+		password string
+		want     bool
+		wantErr  bool
+	}{
+		{name: "breached", minCount: 1, password: "seen-often", want: true},
+		{name: "below threshold", minCount: 10, password: "seen-once", want: false},
+		{name: "at threshold", minCount: 1, password: "seen-once", want: true},
+		{name: "zero threshold means one", minCount: 0, password: "seen-once", want: true},
+		{name: "no range file", minCount: 1, password: "never-seen-anywhere", want: false},
+		{name: "malformed count", minCount: 1, password: "corrupt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, err := NewBreachedChecker(dir, tt.minCount)
+			if err != nil {
+				t.Fatalf("NewBreachedChecker: %v", err)
+			}
+			got, err := checker.IsBreached(tt.password)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("IsBreached(%q) = %v, %v, want %v (error %v)", tt.password, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyBreachedCheck(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "Leaked-Pass-42", "3")
+
+	tests := []struct {
+		name string
+		dir  string
+		want bool
+	}{
+		{name: "enabled", dir: dir, want: true},
+		// A bad directory disables the check rather than failing startup
+		{name: "missing directory", dir: filepath.Join(dir, "missing"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy(config.PasswordPolicyConfig{MinLength: 8, BreachedHashesDir: tt.dir, BreachedMinCount: 1})
+			codes := violationCodes(p.Check("Leaked-Pass-42", UserInfo{}))
+			if got := len(codes) == 1 && codes[0] == ViolationBreached; got != tt.want {
+				t.Fatalf("Check() = %v, want breached %v", codes, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package password
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/models"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatch        = errors.New("password does not match")
+	ErrUnsupportedHash = errors.New("unsupported password hash format")
+)
+
+// Hasher creates password hashes with the configured algorithm and verifies
+// hashes produced by any supported algorithm. Each hash carries its own
+// algorithm and parameters, so the configuration can be raised at any time.
+type Hasher struct {
+	config config.PasswordConfig
+
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+func NewHasher(cfg config.PasswordConfig) *Hasher {
+	switch cfg.Algorithm {
+	case AlgorithmArgon2id, AlgorithmBcrypt:
+	default:
+		log.Printf("Unknown password hash algorithm %q, using %s", cfg.Algorithm, AlgorithmArgon2id)
+		cfg.Algorithm = AlgorithmArgon2id
+	}
+	return &Hasher{config: cfg}
+}
+
+// MaxBytes is the longest password the configured algorithm can hash, or
+// zero when there is no limit. The policy rejects longer passwords so they
+// never reach Hash.
+func (h *Hasher) MaxBytes() int {
+	if h.config.Algorithm == AlgorithmBcrypt {
+		return bcryptMaxBytes
+	}
+	return 0
+}
+
+// Hash encodes password with the configured algorithm.
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.config.Algorithm == AlgorithmBcrypt {
+		return hashBcrypt(password, h.config.BcryptCost)
+	}
+	return hashArgon2id(password, argon2ParamsFromConfig(h.config))
+}
+
+// Verify checks password against encoded. It returns ErrMismatch for a wrong
+// password; on success needsRehash reports whether encoded uses another
+// algorithm or weaker parameters than the current configuration.
+func (h *Hasher) Verify(password, encoded string) (needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, err := verifyArgon2id(password, encoded)
+		if err != nil {
+			return false, err
+		}
+		if h.config.Algorithm != AlgorithmArgon2id {
+			// A password chosen under argon2id may be too long for bcrypt
+			return len(password) <= bcryptMaxBytes, nil
+		}
+		return params.weakerThan(argon2ParamsFromConfig(h.config)), nil
+
+	case isBcrypt(encoded):
+		cost, err := verifyBcrypt(password, encoded)
+		if err != nil {
+			return false, err
+		}
+		if h.config.Algorithm != AlgorithmBcrypt {
+			return true, nil
+		}
+		return cost < h.config.BcryptCost, nil
+	}
+
+	return false, ErrUnsupportedHash
+}
+
+// DummyVerify spends the same time as a real verification so requests for
+// unknown accounts cannot be told apart by response time.
+func (h *Hasher) DummyVerify(password string) {
+	h.dummyOnce.Do(func() {
+		var err error
+		h.dummyHash, err = h.Hash("aspire-auth-dummy-password")
+		if err != nil {
+			log.Printf("Error generating dummy password hash: %v", err)
+		}
+	})
+	h.Verify(password, h.dummyHash)
+}
+
+// Upgrade re-hashes password with the current configuration and stores it
+// for account. The update only applies while the stored hash is unchanged,
+// so a concurrent password change is never overwritten.
+func (h *Hasher) Upgrade(db *gorm.DB, account *models.Account, password string) error {
+	hashed, err := h.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	result := db.Model(&models.Account{}).
+		Where("id = ? AND hashed_password = ?", account.ID, account.HashedPassword).
+		Update("hashed_password", hashed)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 1 {
+		account.HashedPassword = hashed
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package password
+
+import (
+	"aspire-auth/internal/config"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast; only their relative strength matters.
+var (
+	testArgon2 = config.PasswordConfig{Algorithm: AlgorithmArgon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}
+	testBcrypt = config.PasswordConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}
+)
+
+func mustHash(t *testing.T, cfg config.PasswordConfig, password string) string {
+	t.Helper()
+	encoded, err := NewHasher(cfg).Hash(password)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	return encoded
+}
+
+func TestHasherRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config config.PasswordConfig
+		prefix string
+	}{
+		{name: "argon2id", config: testArgon2, prefix: "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{name: "bcrypt", config: testBcrypt, prefix: "$2a$04$"},
+		{name: "unknown algorithm falls back to argon2id", config: config.PasswordConfig{Algorithm: "md5", Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}, prefix: "$argon2id$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHasher(tt.config)
+			encoded, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if !strings.HasPrefix(encoded, tt.prefix) {
+				t.Fatalf("Hash = %q, want prefix %q", encoded, tt.prefix)
+			}
+			if again, _ := h.Hash("correct horse"); again == encoded {
+				t.Fatal("Hash is not salted")
+			}
+
+			if needsRehash, err := h.Verify("correct horse", encoded); err != nil || needsRehash {
+				t.Fatalf("Verify(right) = %v, %v", needsRehash, err)
+			}
+			if _, err := h.Verify("wrong horse", encoded); !errors.Is(err, ErrMismatch) {
+				t.Fatalf("Verify(wrong) = %v, want %v", err, ErrMismatch)
+			}
+		})
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	stronger := testArgon2
+	stronger.Argon2Memory *= 2
+	moreIterations := testArgon2
+	moreIterations.Argon2Iterations++
+	costlier := testBcrypt
+	costlier.BcryptCost++
+	long := strings.Repeat("a", 100)
+
+	tests := []struct {
+		name     string
+		hashed   config.PasswordConfig
+		current  config.PasswordConfig
+		password string
+		want     bool
+	}{
+		{name: "same argon2id parameters", hashed: testArgon2, current: testArgon2, want: false},
+		{name: "more argon2id memory", hashed: testArgon2, current: stronger, want: true},
+		{name: "more argon2id iterations", hashed: testArgon2, current: moreIterations, want: true},
+		{name: "stronger stored hash is kept", hashed: stronger, current: testArgon2, want: false},
+		{name: "same bcrypt cost", hashed: testBcrypt, current: testBcrypt, want: false},
+		{name: "higher bcrypt cost", hashed: testBcrypt, current: costlier, want: true},
+		{name: "bcrypt to argon2id", hashed: testBcrypt, current: testArgon2, want: true},
+		{name: "argon2id to bcrypt", hashed: testArgon2, current: testBcrypt, want: true},
+		{name: "argon2id to bcrypt too long for bcrypt", hashed: testArgon2, current: testBcrypt, password: long, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			password := tt.password
+			if password == "" {
+				password = "correct horse"
+			}
+			needsRehash, err := NewHasher(tt.current).Verify(password, mustHash(t, tt.hashed, password))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if needsRehash != tt.want {
+				t.Fatalf("needsRehash = %v, want %v", needsRehash, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasherRejectsMalformedHashes(t *testing.T) {
+	valid := mustHash(t, testArgon2, "correct horse")
+	parts := strings.Split(valid, "$")
+	withPart := func(i int, value string) string {
+		changed := append([]string(nil), parts...)
+		changed[i] = value
+		return strings.Join(changed, "$")
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "empty", encoded: ""},
+		{name: "plaintext", encoded: "correct horse"},
+		{name: "unsupported algorithm", encoded: "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5"},
+		{name: "argon2i", encoded: withPart(1, "argon2i")},
+		{name: "missing field", encoded: strings.Join(parts[:5], "$")},
+		{name: "other argon2 version", encoded: withPart(2, "v=16")},
+		{name: "unparsable parameters", encoded: withPart(3, "m=lots,t=1,p=1")},
+		{name: "zero memory", encoded: withPart(3, "m=0,t=1,p=1")},
+		{name: "zero iterations", encoded: withPart(3, "m=1024,t=0,p=1")},
+		{name: "bad salt encoding", encoded: withPart(4, "not base64!")},
+		{name: "bad key encoding", encoded: withPart(5, "not base64!")},
+		{name: "empty key", encoded: withPart(5, "")},
+		{name: "bcrypt without cost", encoded: "$2a$xx$abcdefghijklmnopqrstuv"},
+		{name: "truncated bcrypt", encoded: mustHash(t, testBcrypt, "correct horse")[:30]},
+	}
+
+	h := NewHasher(testArgon2)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := h.Verify("correct horse", tt.encoded); !errors.Is(err, ErrUnsupportedHash) {
+				t.Fatalf("Verify(%q) = %v, want %v", tt.encoded, err, ErrUnsupportedHash)
+			}
+		})
+	}
+}
+
+func TestHasherMaxBytes(t *testing.T) {
+	if got := NewHasher(testArgon2).MaxBytes(); got != 0 {
+		t.Fatalf("argon2id MaxBytes = %d, want no limit", got)
+	}
+	h := NewHasher(testBcrypt)
+	if got := h.MaxBytes(); got != 72 {
+		t.Fatalf("bcrypt MaxBytes = %d, want 72", got)
+	}
+	if _, err := h.Hash(strings.Repeat("a", 72)); err != nil {
+		t.Fatalf("Hash at the limit: %v", err)
+	}
+	if _, err := h.Hash(strings.Repeat("a", 73)); err == nil {
+		t.Fatal("Hash over the limit succeeded")
+	}
+}
@@ -0,0 +1,199 @@
+package password
+
+import (
+	"aspire-auth/internal/config"
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Violation codes returned to clients alongside a human readable message.
+const (
+	ViolationTooShort       = "too_short"
+	ViolationTooLong        = "too_long"
+	ViolationMissingUpper   = "missing_uppercase"
+	ViolationMissingLower   = "missing_lowercase"
+	ViolationMissingDigit   = "missing_digit"
+	ViolationMissingSymbol  = "missing_symbol"
+	ViolationPersonalInfo   = "contains_personal_info"
+	ViolationBlocklisted    = "too_common"
+	ViolationBreached       = "breached"
+	ViolationSameAsPrevious = "same_as_current"
+)
+
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// UserInfo is what the password must not resemble. Empty fields are ignored.
+type UserInfo struct {
+	Username  string
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+// commonPasswords is always blocked, whatever the configuration adds.
+var commonPasswords = []string{
+	"password", "passw0rd", "p@ssword", "p@ssw0rd", "123456", "12345678",
+	"123456789", "1234567890", "qwerty", "qwertyuiop", "abc123", "111111",
+	"iloveyou", "letmein", "welcome", "admin", "monkey", "dragon", "football",
+	"baseball", "sunshine", "princess", "trustno1", "changeme", "aspire",
+}
+
+// Policy checks candidate passwords against the configured rules.
+type Policy struct {
+	config    config.PasswordPolicyConfig
+	blocklist map[string]struct{}
+	breached  *BreachedChecker
+}
+
+// NewPolicy builds the blocklist and breached-password checker. Files that
+// cannot be read are logged and skipped so a bad path does not keep the
+// server from starting.
+func NewPolicy(cfg config.PasswordPolicyConfig) *Policy {
+	// Every character takes at least a byte, so the hasher's limit caps the length
+	if cfg.MaxBytes > 0 && (cfg.MaxLength <= 0 || cfg.MaxLength > cfg.MaxBytes) {
+		cfg.MaxLength = cfg.MaxBytes
+	}
+
+	p := &Policy{
+		config:    cfg,
+		blocklist: make(map[string]struct{}),
+	}
+
+	for _, entry := range commonPasswords {
+		p.blocklist[normalize(entry)] = struct{}{}
+	}
+	for _, entry := range cfg.Blocklist {
+		p.blocklist[normalize(entry)] = struct{}{}
+	}
+	if cfg.BlocklistFile != "" {
+		if err := p.loadBlocklistFile(cfg.BlocklistFile); err != nil {
+			log.Printf("Warning: could not load password blocklist: %v", err)
+		}
+	}
+
+	if cfg.BreachedHashesDir != "" {
+		checker, err := NewBreachedChecker(cfg.BreachedHashesDir, cfg.BreachedMinCount)
+		if err != nil {
+			log.Printf("Warning: breached password check disabled: %v", err)
+		} else {
+			p.breached = checker
+		}
+	}
+
+	return p
+}
+
+func (p *Policy) loadBlocklistFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if entry := strings.TrimSpace(scanner.Text()); entry != "" && !strings.HasPrefix(entry, "#") {
+			p.blocklist[normalize(entry)] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
+
+// Check returns every rule the password breaks; an empty result means the
+// password is acceptable.
+func (p *Policy) Check(password string, user UserInfo) []Violation {
+	var violations []Violation
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		add(ViolationTooShort, "Password must be at least %d characters long", p.config.MinLength)
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		add(ViolationTooLong, "Password must be at most %d characters long", p.config.MaxLength)
+	} else if p.config.MaxBytes > 0 && len(password) > p.config.MaxBytes {
+		// Only reachable with multi-byte characters, since MaxLength is capped
+		add(ViolationTooLong, "Password is too long; use at most %d characters, fewer with accented letters or symbols", p.config.MaxBytes)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.config.RequireUpper && !hasUpper {
+		add(ViolationMissingUpper, "Password must contain an uppercase letter")
+	}
+	if p.config.RequireLower && !hasLower {
+		add(ViolationMissingLower, "Password must contain a lowercase letter")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		add(ViolationMissingDigit, "Password must contain a digit")
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		add(ViolationMissingSymbol, "Password must contain a symbol")
+	}
+
+	if p.config.RejectPersonalInfo && containsPersonalInfo(password, user) {
+		add(ViolationPersonalInfo, "Password must not contain your username, email or name")
+	}
+
+	if _, blocked := p.blocklist[normalize(password)]; blocked {
+		add(ViolationBlocklisted, "Password is too common")
+	}
+
+	if p.breached != nil {
+		breached, err := p.breached.IsBreached(password)
+		if err != nil {
+			log.Printf("Breached password check failed: %v", err)
+		} else if breached {
+			add(ViolationBreached, "Password has appeared in a data breach and cannot be used")
+		}
+	}
+
+	return violations
+}
+
+// normalize lowercases and strips the digits and symbols people commonly
+// append, so "Password123!" matches the "password" blocklist entry.
+func normalize(password string) string {
+	return strings.TrimRightFunc(strings.ToLower(password), func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+}
+
+func containsPersonalInfo(password string, user UserInfo) bool {
+	lowered := strings.ToLower(password)
+
+	localPart := user.Email
+	if at := strings.LastIndex(localPart, "@"); at >= 0 {
+		localPart = localPart[:at]
+	}
+
+	for _, value := range []string{user.Username, localPart, user.FirstName, user.LastName} {
+		value = strings.ToLower(strings.TrimSpace(value))
+		// Very short values such as initials would reject too many passwords
+		if utf8.RuneCountInString(value) >= 3 && strings.Contains(lowered, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package password
+
+import (
+	"aspire-auth/internal/config"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func violationCodes(violations []Violation) []string {
+	codes := make([]string, len(violations))
+	for i, v := range violations {
+		codes[i] = v.Code
+	}
+	return codes
+}
+
+func TestPolicyCheck(t *testing.T) {
+	blocklistFile := filepath.Join(t.TempDir(), "blocklist.txt")
+	os.WriteFile(blocklistFile, []byte("# company words\nAcmeCorp\n\n"), 0600)
+
+	strict := NewPolicy(config.PasswordPolicyConfig{
+		MinLength:          10,
+		MaxLength:          64,
+		RequireUpper:       true,
+		RequireLower:       true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		RejectPersonalInfo: true,
+		Blocklist:          []string{"Hunter"},
+		BlocklistFile:      blocklistFile,
+	})
+	user := UserInfo{Username: "jdoe", Email: "jane.doe@example.com", FirstName: "Jane", LastName: "Li"}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{name: "acceptable", password: "Correct-Horse-42", want: nil},
+		{name: "too short", password: "Ab1!", want: []string{ViolationTooShort}},
+		{name: "too long", password: "Aa1!" + strings.Repeat("a", 61), want: []string{ViolationTooLong}},
+		{name: "length counts characters not bytes", password: "Ünïcödé-Pässwörd-1", want: nil},
+		{name: "missing classes", password: "alllowercaseletters", want: []string{ViolationMissingUpper, ViolationMissingDigit, ViolationMissingSymbol}},
+		{name: "username", password: "Xx-jdoe-2024-Yy", want: []string{ViolationPersonalInfo}},
+		{name: "email local part", password: "JANE.DOE-is-42!", want: []string{ViolationPersonalInfo}},
+		{name: "first name any case", password: "iamJANE-1234!", want: []string{ViolationPersonalInfo}},
+		{name: "short names are ignored", password: "Lions-Roar-42!", want: nil},
+		{name: "built-in common password", password: "Password123!", want: []string{ViolationBlocklisted}},
+		{name: "configured entry", password: "Hunter2024!!", want: []string{ViolationBlocklisted}},
+		{name: "blocked word followed by letters", password: "acmecorp-99!A", want: nil},
+		{name: "file entry with suffix", password: "ACMECORP1234!", want: []string{ViolationMissingLower, ViolationBlocklisted}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violationCodes(strict.Check(tt.password, user)); !slices.Equal(got, tt.want) {
+				t.Fatalf("Check(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyPersonalInfoOnlyWhenEnabled(t *testing.T) {
+	user := UserInfo{Username: "jdoe"}
+	tests := []struct {
+		name    string
+		enabled bool
+		want    []string
+	}{
+		{name: "enabled", enabled: true, want: []string{ViolationPersonalInfo}},
+		{name: "disabled", enabled: false, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPolicy(config.PasswordPolicyConfig{MinLength: 8, RejectPersonalInfo: tt.enabled})
+			if got := violationCodes(p.Check("xx-jdoe-xx", user)); !slices.Equal(got, tt.want) {
+				t.Fatalf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Password", "password"},
+		{"Password123!", "password"},
+		{"p@ssw0rd", "p@ssw0rd"},
+		{"123456", ""},
+		{"pass word!", "pass word"},
+	}
+	for _, tt := range tests {
+		if got := normalize(tt.in); got != tt.want {
+			t.Errorf("normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyMaxBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.PasswordPolicyConfig
+		password string
+		want     []string
+	}{
+		{name: "ascii at the limit", config: config.PasswordPolicyConfig{MaxLength: 128, MaxBytes: 72}, password: strings.Repeat("a", 72), want: nil},
+		{name: "length is capped to the byte limit", config: config.PasswordPolicyConfig{MaxLength: 128, MaxBytes: 72}, password: strings.Repeat("a", 73), want: []string{ViolationTooLong}},
+		{name: "no configured length", config: config.PasswordPolicyConfig{MaxBytes: 72}, password: strings.Repeat("a", 73), want: []string{ViolationTooLong}},
+		{name: "multi-byte characters", config: config.PasswordPolicyConfig{MaxLength: 128, MaxBytes: 72}, password: strings.Repeat("ä", 40), want: []string{ViolationTooLong}},
+		{name: "lower configured length kept", config: config.PasswordPolicyConfig{MaxLength: 20, MaxBytes: 72}, password: strings.Repeat("a", 21), want: []string{ViolationTooLong}},
+		{name: "no byte limit", config: config.PasswordPolicyConfig{MaxLength: 128}, password: strings.Repeat("ä", 100), want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violationCodes(NewPolicy(tt.config).Check(tt.password, UserInfo{})); !slices.Equal(got, tt.want) {
+				t.Fatalf("Check = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryLimiter is a per-process sliding-window-log limiter. It is only as
+// accurate as a single replica's view and is meant as a fallback.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*memoryWindow
+}
+
+type memoryWindow struct {
+	hits   []time.Time
+	window time.Duration
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	l := &MemoryLimiter{windows: map[string]*memoryWindow{}}
+	go l.janitor(time.Minute)
+	return l
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok {
+		w = &memoryWindow{window: window}
+		l.windows[key] = w
+	}
+	w.window = window
+	w.prune(now)
+
+	allowed := len(w.hits) < limit
+	if allowed {
+		w.hits = append(w.hits, now)
+	}
+
+	reset := window
+	if len(w.hits) > 0 {
+		reset = window - now.Sub(w.hits[0])
+	}
+
+	remaining := limit - len(w.hits)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}, nil
+}
+
+func (w *memoryWindow) prune(now time.Time) {
+	cutoff := now.Add(-w.window)
+	i := 0
+	for i < len(w.hits) && !w.hits[i].After(cutoff) {
+		i++
+	}
+	w.hits = w.hits[i:]
+}
+
+// janitor drops windows that no longer hold any hits
+func (l *MemoryLimiter) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		l.sweep(now)
+	}
+}
+
+func (l *MemoryLimiter) sweep(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, w := range l.windows {
+		w.prune(now)
+		if len(w.hits) == 0 {
+			delete(l.windows, key)
+		}
+	}
+}
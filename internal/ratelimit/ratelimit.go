@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Result reports the state of a sliding window after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the oldest counted request leaves the window
+	Reset time.Duration
+}
+
+// Limiter counts requests for a key within a sliding window.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// primaryRetryInterval is how long the fallback is used before the primary is tried again
+const primaryRetryInterval = 10 * time.Second
+
+// FallbackLimiter uses the primary limiter and switches to the fallback
+// whenever the primary returns an error, e.g. when Redis is unreachable.
+// While degraded the primary is only probed every primaryRetryInterval so
+// requests do not each wait for a Redis timeout.
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	degraded atomic.Bool
+	retryAt  atomic.Int64
+}
+
+func NewFallbackLimiter(primary, fallback Limiter) *FallbackLimiter {
+	return &FallbackLimiter{primary: primary, fallback: fallback}
+}
+
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	if l.degraded.Load() && time.Now().UnixNano() < l.retryAt.Load() {
+		return l.fallback.Allow(ctx, key, limit, window)
+	}
+
+	result, err := l.primary.Allow(ctx, key, limit, window)
+	if err == nil {
+		if l.degraded.CompareAndSwap(true, false) {
+			log.Println("Rate limiter: primary store recovered")
+		}
+		return result, nil
+	}
+
+	l.retryAt.Store(time.Now().Add(primaryRetryInterval).UnixNano())
+	if l.degraded.CompareAndSwap(false, true) {
+		log.Printf("Rate limiter: primary store failed, using in-memory fallback: %v", err)
+	}
+	return l.fallback.Allow(ctx, key, limit, window)
+}
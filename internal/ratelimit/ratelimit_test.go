@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testLimiters runs each test against both implementations, which must
+// agree on the sliding window.
+func testLimiters(t *testing.T) map[string]Limiter {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return map[string]Limiter{
+		"redis":  NewRedisLimiter(client),
+		"memory": &MemoryLimiter{windows: map[string]*memoryWindow{}},
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	const window = 200 * time.Millisecond
+
+	for name, limiter := range testLimiters(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for i, want := range []struct {
+				allowed   bool
+				remaining int
+			}{{true, 2}, {true, 1}, {true, 0}, {false, 0}} {
+				result, err := limiter.Allow(ctx, "key", 3, window)
+				if err != nil {
+					t.Fatalf("request %d: %v", i+1, err)
+				}
+				if result.Allowed != want.allowed || result.Remaining != want.remaining || result.Limit != 3 {
+					t.Fatalf("request %d = %+v, want allowed %v, remaining %d", i+1, result, want.allowed, want.remaining)
+				}
+				if result.Reset <= 0 || result.Reset > window {
+					t.Fatalf("request %d: reset = %s, want within the window", i+1, result.Reset)
+				}
+			}
+
+			// Other keys have their own window
+			if result, err := limiter.Allow(ctx, "other", 3, window); err != nil || !result.Allowed {
+				t.Fatalf("other key = %+v, %v", result, err)
+			}
+
+			// A rejected request is not counted, so the window clears on time
+			time.Sleep(window + 50*time.Millisecond)
+			result, err := limiter.Allow(ctx, "key", 3, window)
+			if err != nil || !result.Allowed || result.Remaining != 2 {
+				t.Fatalf("after the window = %+v, %v", result, err)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	l := &MemoryLimiter{windows: map[string]*memoryWindow{}}
+	ctx := context.Background()
+
+	l.Allow(ctx, "short", 5, time.Second)
+	l.Allow(ctx, "long", 5, time.Hour)
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  []string
+	}{
+		{name: "all current", after: 0, want: []string{"short", "long"}},
+		{name: "short window expired", after: 2 * time.Second, want: []string{"long"}},
+		{name: "all expired", after: 2 * time.Hour, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l.sweep(time.Now().Add(tt.after))
+			if len(l.windows) != len(tt.want) {
+				t.Fatalf("%d windows left, want %v", len(l.windows), tt.want)
+			}
+			for _, key := range tt.want {
+				if _, ok := l.windows[key]; !ok {
+					t.Fatalf("window %q was dropped", key)
+				}
+			}
+		})
+	}
+}
+
+// stubLimiter allows everything unless err is set, and counts its calls.
+type stubLimiter struct {
+	err   error
+	calls int
+}
+
+func (l *stubLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	l.calls++
+	if l.err != nil {
+		return Result{}, l.err
+	}
+	return Result{Allowed: true, Limit: limit, Remaining: limit - 1}, nil
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	primary := &stubLimiter{}
+	fallback := &stubLimiter{}
+	l := NewFallbackLimiter(primary, fallback)
+	ctx := context.Background()
+
+	steps := []struct {
+		name string
+		// before runs ahead of the request
+		before       func()
+		wantPrimary  int
+		wantFallback int
+	}{
+		{name: "healthy primary", wantPrimary: 1},
+		{name: "primary fails", before: func() { primary.err = errors.New("connection refused") }, wantPrimary: 2, wantFallback: 1},
+		{name: "degraded skips the primary", wantPrimary: 2, wantFallback: 2},
+		{name: "recovered primary not probed before the retry interval", before: func() { primary.err = nil }, wantPrimary: 2, wantFallback: 3},
+		{name: "probe after the retry interval switches back", before: func() { l.retryAt.Store(time.Now().Add(-time.Second).UnixNano()) }, wantPrimary: 3, wantFallback: 3},
+		{name: "stays on the primary", wantPrimary: 4, wantFallback: 3},
+	}
+
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+		result, err := l.Allow(ctx, "key", 10, time.Minute)
+		if err != nil || !result.Allowed {
+			t.Fatalf("%s: Allow = %+v, %v", step.name, result, err)
+		}
+		if primary.calls != step.wantPrimary || fallback.calls != step.wantFallback {
+			t.Fatalf("%s: primary calls %d, fallback calls %d, want %d and %d", step.name, primary.calls, fallback.calls, step.wantPrimary, step.wantFallback)
+		}
+	}
+}
@@ -7,22 +7,77 @@ import (
 type CreateAccountRequest struct {
 	Username    string `json:"username" validate:"required,min=3,max=50"`
 	Email       string `json:"email" validate:"required,email"`
-	Password    string `json:"password" validate:"required,min=8"`
+	Password    string `json:"password" validate:"required"`
 	FirstName   string `json:"first_name" validate:"required"`
 	LastName    string `json:"last_name" validate:"required"`
 	DateOfBirth string `json:"date_of_birth,omitempty"`
 	Gender      string `json:"gender,omitempty"`
 	Avatar      string `json:"avatar,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	// OTPChannel is where the verification code is sent: "email" (default) or "sms"
+	OTPChannel string `json:"otp_channel,omitempty"`
 }
 
+// UpdateAccountRequest cannot change the email; that goes through the
+// verified change-email flow
 type UpdateAccountRequest struct {
 	Username    string    `json:"username"`
-	Email       string    `json:"email"`
 	FirstName   string    `json:"first_name"`
 	LastName    string    `json:"last_name"`
 	DateOfBirth time.Time `json:"date_of_birth"`
 	Gender      string    `json:"gender"`
-	Avatar      string    `json:"avatar"`
+	// Avatar is rejected; avatars are uploaded through PUT /account/avatar
+	Avatar string `json:"avatar"`
+	Locale string `json:"locale"`
+}
+
+// UpdateAvatarRequest carries an avatar as base64, optionally as a data: URL.
+// Multipart uploads send the file in an "avatar" field instead.
+type UpdateAvatarRequest struct {
+	Avatar string `json:"avatar" validate:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// DeleteAccountRequest schedules the account for deletion. Owned services are
+// offered to the account registered to TransferServicesTo, if given; those
+// it has not accepted by the purge are deleted.
+type DeleteAccountRequest struct {
+	CurrentPassword    string `json:"current_password" validate:"required"`
+	TransferServicesTo string `json:"transfer_services_to,omitempty" validate:"omitempty,email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email   string `json:"email" validate:"required,email"`
+	Channel string `json:"channel,omitempty"`
+}
+
+type ResetPasswordRequest struct {
+	Email       string `json:"email" validate:"required,email"`
+	OTP         string `json:"otp" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" validate:"required,email"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	OTP string `json:"otp" validate:"required"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ServiceMagicLinkRequest struct {
+	ServiceID string `json:"service_id" validate:"required"`
+	Email     string `json:"email" validate:"required,email"`
 }
 
 type LoginRequest struct {
@@ -39,13 +94,55 @@ type CreateServiceRequest struct {
 	ServiceName        string  `json:"service_name" validate:"required"`
 	ServiceDescription *string `json:"service_description,omitempty"`
 	ServiceLogo        *string `json:"service_logo,omitempty"`
-	SecretKey          string  `json:"secret_key" validate:"required"`
+	BrandColor         *string `json:"brand_color,omitempty"`
+	// SecretKey is optional; when omitted a secret is generated server-side.
+	SecretKey string `json:"secret_key,omitempty"`
+	// OrganizationID places the service in an organization the caller
+	// administers; without it the service is personal.
+	OrganizationID string `json:"organization_id,omitempty"`
+}
+
+// MoveServiceRequest moves a service into an organization, or back to its
+// owner's personal services when OrganizationID is empty.
+type MoveServiceRequest struct {
+	OrganizationID string `json:"organization_id"`
+}
+
+// TransferServiceRequest nominates the account that should become the
+// service's owner once it accepts.
+type TransferServiceRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// AddOrganizationMemberRequest adds an existing, verified account by email.
+type AddOrganizationMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required"`
+}
+
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" validate:"required"`
 }
 
 type UpdateServiceRequest struct {
 	ServiceName        string  `json:"service_name"`
 	ServiceDescription *string `json:"service_description,omitempty"`
 	ServiceLogo        *string `json:"service_logo,omitempty"`
+	BrandColor         *string `json:"brand_color,omitempty"`
+}
+
+// UpdateLogoRequest carries a service logo as base64, optionally as a data: URL.
+// Multipart uploads send the file in a "logo" field instead.
+type UpdateLogoRequest struct {
+	Logo string `json:"logo" validate:"required"`
 }
 
 type SignupToServiceRequest struct {
@@ -54,14 +151,30 @@ type SignupToServiceRequest struct {
 	Password  string `json:"password" validate:"required"`
 }
 
-type ServiceUsersListRequest struct {
-	ServiceID string `json:"service_id" validate:"required"`
-	Page      int    `json:"page" default:"1"`
-	Limit     int    `json:"limit" default:"10"`
-}
-
 type ResendOTPRequest struct {
 	AccountID string `json:"account_id" validate:"required"`
+	Channel   string `json:"channel,omitempty"`
+}
+
+type UpdatePhoneRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required"`
+}
+
+type VerifyPhoneRequest struct {
+	OTP string `json:"otp" validate:"required"`
+}
+
+// UpdateMFARequest changes how codes are delivered and whether sign-in needs
+// one. Both fields are optional; the current password is always required.
+type UpdateMFARequest struct {
+	Enabled         *bool  `json:"enabled,omitempty"`
+	Channel         string `json:"channel,omitempty"`
+	CurrentPassword string `json:"current_password" validate:"required"`
+}
+
+type MFAVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	OTP            string `json:"otp" validate:"required"`
 }
 
 type RefreshTokenRequest struct {
@@ -73,3 +186,24 @@ type LoginServiceRequest struct {
 	Email     string `json:"email" validate:"required"`
 	Password  string `json:"password" validate:"required"`
 }
+
+// AdminDisableRequest optionally records why an admin disabled an account
+// or service; the reason is kept in the audit log.
+type AdminDisableRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CreateWebhookRequest subscribes URL to one or more events from
+// webhook.Catalog.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required"`
+	Events []string `json:"events" validate:"required"`
+}
+
+// UpdateWebhookRequest changes only the fields given. Setting Enabled to
+// true re-enables a webhook that was disabled for failing.
+type UpdateWebhookRequest struct {
+	URL     *string  `json:"url,omitempty"`
+	Events  []string `json:"events,omitempty"`
+	Enabled *bool    `json:"enabled,omitempty"`
+}
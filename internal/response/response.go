@@ -2,6 +2,8 @@ package response
 
 import (
 	"aspire-auth/internal/models"
+	"aspire-auth/internal/password"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,11 +14,61 @@ type APIResponse struct {
 	Message string `json:"message"`
 }
 
+// PasswordPolicyErrorResponse lists every rule a rejected password breaks
+type PasswordPolicyErrorResponse struct {
+	APIResponse
+	Violations []password.Violation `json:"violations"`
+}
+
 type LoginResponse struct {
 	APIResponse
 	ExpiresAt int64 `json:"expires_at"`
 }
 
+// MFAChallengeResponse is returned instead of a session when the password
+// was correct but the account requires a second step. The code is sent over
+// Channel and must be posted with ChallengeToken to /signin/mfa, or to
+// /service/login/mfa when signing in to a service.
+type MFAChallengeResponse struct {
+	APIResponse
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	Channel        string `json:"channel"`
+	ExpiresAt      int64  `json:"expires_at"`
+}
+
+// AccountExportResponse describes a personal data export. DownloadURL is
+// only set once the archive is ready and stops working at DownloadExpiresAt.
+type AccountExportResponse struct {
+	APIResponse
+	Export            models.AccountExport `json:"export"`
+	DownloadURL       string               `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time           `json:"download_expires_at,omitempty"`
+}
+
+// AvatarResponse names the stored avatar and links each of its sizes.
+type AvatarResponse struct {
+	APIResponse
+	Avatar   string            `json:"avatar"`
+	Variants map[string]string `json:"variants"`
+}
+
+// LogoResponse gives the ServiceLogo value of an uploaded logo and links
+// each of its sizes.
+type LogoResponse struct {
+	APIResponse
+	Logo     string            `json:"logo"`
+	Variants map[string]string `json:"variants"`
+}
+
+// EmailRevertResponse describes the change a revert link undoes, so it can
+// be confirmed before anything is restored.
+type EmailRevertResponse struct {
+	APIResponse
+	OldEmail string `json:"old_email"`
+	NewEmail string `json:"new_email"`
+}
+
 type CreateAccountResponse struct {
 	APIResponse
 	AccountID string `json:"account_id"`
@@ -29,6 +81,22 @@ type ServiceResponse struct {
 	Description *string `json:"description,omitempty"`
 	Logo        *string `json:"logo,omitempty"`
 	UsersCount  int64   `json:"users_count"`
+	// OrganizationID is set for services managed by an organization, and
+	// Role is what the caller may do with the service
+	OrganizationID *uuid.UUID              `json:"organization_id,omitempty"`
+	Role           models.OrganizationRole `json:"role,omitempty"`
+}
+
+type CreateServiceResponse struct {
+	APIResponse
+	ServiceID uuid.UUID `json:"service_id"`
+	SecretKey string    `json:"secret_key,omitempty"`
+}
+
+type ServiceSecretResponse struct {
+	APIResponse
+	ServiceID uuid.UUID `json:"service_id"`
+	SecretKey string    `json:"secret_key"`
 }
 
 type ServiceListResponse struct {
@@ -37,6 +105,22 @@ type ServiceListResponse struct {
 	Total    int64             `json:"total"`
 }
 
+// ServiceTransfer is an ownership transfer waiting for ToEmail to accept it.
+// ServiceName and FromEmail are filled in when the recipient reviews it.
+type ServiceTransfer struct {
+	ServiceID   uuid.UUID `json:"service_id"`
+	ServiceName string    `json:"service_name,omitempty"`
+	FromEmail   string    `json:"from_email,omitempty"`
+	ToID        uuid.UUID `json:"to_id"`
+	ToEmail     string    `json:"to_email"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type ServiceTransferResponse struct {
+	APIResponse
+	Transfer ServiceTransfer `json:"transfer"`
+}
+
 type ServiceUserResponse struct {
 	APIResponse
 	ID         string    `json:"id"`
@@ -64,19 +148,155 @@ type SignUpServiceResponse struct {
 }
 
 type AccountResponse struct {
-	Username    string             `json:"username"`
-	Email       string             `json:"email"`
-	FirstName   string             `json:"first_name"`
-	LastName    string             `json:"last_name"`
-	DateOfBirth *time.Time         `json:"date_of_birth,omitempty"`
-	Gender      *models.GenderType `json:"gender,omitempty"`
-	RoleType    models.RoleType    `json:"role_type"`
-	Avatar      *string            `gorm:"type:text" json:"avatar,omitempty"`
-	CreatedAt   time.Time          `json:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at"`
+	Username      string             `json:"username"`
+	Email         string             `json:"email"`
+	FirstName     string             `json:"first_name"`
+	LastName      string             `json:"last_name"`
+	DateOfBirth   *time.Time         `json:"date_of_birth,omitempty"`
+	Gender        *models.GenderType `json:"gender,omitempty"`
+	RoleType      models.RoleType    `json:"role_type"`
+	Avatar        *string            `gorm:"type:text" json:"avatar,omitempty"`
+	Locale        *string            `json:"locale,omitempty"`
+	PhoneNumber   *string            `json:"phone_number,omitempty"`
+	PhoneVerified bool               `json:"phone_verified"`
+	OTPChannel    string             `json:"otp_channel"`
+	MFAEnabled    bool               `json:"mfa_enabled"`
+	// DeletionScheduledAt is set while the account is waiting to be purged
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 }
 
 type GetAccountDetailsResponse struct {
 	APIResponse
 	Account AccountResponse `json:"account"`
 }
+
+type AdminAccountListResponse struct {
+	APIResponse
+	Accounts []models.Account `json:"accounts"`
+	Total    int64            `json:"total"`
+	Page     int              `json:"page"`
+	Limit    int              `json:"limit"`
+}
+
+// AdminMembership is a service an account has joined.
+type AdminMembership struct {
+	ServiceID   uuid.UUID `json:"service_id"`
+	ServiceName string    `json:"service_name"`
+	IsVerified  bool      `json:"is_verified"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// AdminServiceSummary describes a service without its secret.
+type AdminServiceSummary struct {
+	ID          uuid.UUID `json:"id"`
+	ServiceName string    `json:"service_name"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	OwnerEmail  string    `json:"owner_email"`
+	// OrganizationID is set when an organization manages the service
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	UsersCount     int64      `json:"users_count"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type AdminAccountResponse struct {
+	APIResponse
+	Account         models.Account        `json:"account"`
+	Memberships     []AdminMembership     `json:"memberships"`
+	OwnedServices   []AdminServiceSummary `json:"owned_services"`
+	AccountSessions int64                 `json:"account_sessions"`
+	ServiceSessions int64                 `json:"service_sessions"`
+}
+
+type AdminServiceListResponse struct {
+	APIResponse
+	Services []AdminServiceSummary `json:"services"`
+	Total    int64                 `json:"total"`
+	Page     int                   `json:"page"`
+	Limit    int                   `json:"limit"`
+}
+
+type AuditEventListResponse struct {
+	APIResponse
+	Events []models.AuditEvent `json:"events"`
+	Total  int64               `json:"total"`
+	Page   int                 `json:"page"`
+	Limit  int                 `json:"limit"`
+}
+
+// WebhookDetails is a webhook with its subscriptions as a list.
+type WebhookDetails struct {
+	models.Webhook
+	Events []string `json:"events"`
+}
+
+// WebhookResponse carries the signing secret only when the webhook is
+// created; it cannot be read back afterwards.
+type WebhookResponse struct {
+	APIResponse
+	Webhook WebhookDetails `json:"webhook"`
+	Secret  string         `json:"secret,omitempty"`
+}
+
+type WebhookListResponse struct {
+	APIResponse
+	Webhooks []WebhookDetails `json:"webhooks"`
+	Catalog  []string         `json:"catalog"`
+}
+
+type WebhookDeliveryDetails struct {
+	models.WebhookDelivery
+	Payload json.RawMessage `json:"payload"`
+}
+
+type WebhookDeliveryListResponse struct {
+	APIResponse
+	Deliveries []WebhookDeliveryDetails `json:"deliveries"`
+	Total      int64                    `json:"total"`
+	Page       int                      `json:"page"`
+	Limit      int                      `json:"limit"`
+}
+
+type WebhookDeliveryResponse struct {
+	APIResponse
+	Delivery WebhookDeliveryDetails `json:"delivery"`
+}
+
+// OrganizationSummary is an organization with the caller's role in it.
+type OrganizationSummary struct {
+	models.Organization
+	Role models.OrganizationRole `json:"role"`
+}
+
+type OrganizationListResponse struct {
+	APIResponse
+	Organizations []OrganizationSummary `json:"organizations"`
+}
+
+type OrganizationMemberDetails struct {
+	models.OrganizationMember
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// OrganizationService describes a service managed by an organization.
+type OrganizationService struct {
+	ID          uuid.UUID `json:"id"`
+	ServiceName string    `json:"service_name"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type OrganizationResponse struct {
+	APIResponse
+	Organization OrganizationSummary         `json:"organization"`
+	Members      []OrganizationMemberDetails `json:"members,omitempty"`
+	Services     []OrganizationService       `json:"services,omitempty"`
+}
+
+type OrganizationMemberResponse struct {
+	APIResponse
+	Member OrganizationMemberDetails `json:"member"`
+}
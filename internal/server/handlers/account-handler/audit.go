@@ -0,0 +1,43 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ListAuditEvents returns the signed-in account's own security events,
+// newest first. It accepts the filters of audit.ParseQuery except
+// account_id, which is always the caller.
+func (h *AccountHandler) ListAuditEvents(c *fiber.Ctx) error {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+	accountID := uuid.MustParse(authToken.UserID)
+
+	query, err := audit.ParseQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	query.AccountID = &accountID
+
+	events, total, err := audit.Search(c.Context(), h.DB, query)
+	if err != nil {
+		log.Printf("Error fetching audit events: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching audit events")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.AuditEventListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Audit events fetched successfully",
+		},
+		Events: events,
+		Total:  total,
+		Page:   query.Page,
+		Limit:  query.Limit,
+	})
+}
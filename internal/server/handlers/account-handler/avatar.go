@@ -0,0 +1,182 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/imaging"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// UpdateAvatar replaces the account's avatar. The image is sent either as a
+// multipart "avatar" file or as base64 in a JSON body, and is stored in
+// every size in helpers.AvatarVariants. The previous avatar is deleted.
+func (h *AccountHandler) UpdateAvatar(c *fiber.Ctx) error {
+	data, rejected := h.readAvatarUpload(c)
+	if rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+
+	processed, err := imaging.Process(data, helpers.AvatarOptions(h.Config.Avatar))
+	if err != nil {
+		return h.avatarError(c, err)
+	}
+
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	filename, err := helpers.SaveAvatar(c.Context(), h.Storage, processed)
+	if err != nil {
+		log.Printf("Error saving avatar: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error saving avatar")
+	}
+
+	previous := account.Avatar
+	err = h.saveProfile(c, account.ID, audit.Success(c, audit.EventAvatarChange).WithAccount(account.ID), func(tx *gorm.DB) error {
+		return tx.Model(&account).Update("avatar", filename).Error
+	})
+	if err != nil {
+		if err := helpers.DeleteAvatar(c.Context(), h.Storage, filename); err != nil {
+			log.Printf("Failed to delete avatar file after error: %v", err)
+		}
+		return utils.HandleDBError(c, err, "Error updating avatar")
+	}
+
+	h.removeAvatar(c.Context(), previous)
+
+	return c.Status(fiber.StatusOK).JSON(response.AvatarResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Avatar updated successfully",
+		},
+		Avatar:   filename,
+		Variants: h.avatarURLs(filename),
+	})
+}
+
+// DeleteAvatar removes the account's avatar.
+func (h *AccountHandler) DeleteAvatar(c *fiber.Ctx) error {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+	if account.Avatar == nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Account has no avatar")
+	}
+
+	previous := account.Avatar
+	err := h.saveProfile(c, account.ID, audit.Success(c, audit.EventAvatarChange).WithAccount(account.ID).WithSubject("removed"), func(tx *gorm.DB) error {
+		return tx.Model(&account).Update("avatar", nil).Error
+	})
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error removing avatar")
+	}
+
+	h.removeAvatar(c.Context(), previous)
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Avatar removed", nil)
+}
+
+// readAvatarUpload returns the raw image bytes of a multipart or base64
+// upload, or the reason the request was rejected.
+func (h *AccountHandler) readAvatarUpload(c *fiber.Ctx) ([]byte, *fiber.Error) {
+	maxBytes := h.Config.Avatar.MaxBytes
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		header, err := c.FormFile("avatar")
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Avatar file is required")
+		}
+		if header.Size > maxBytes {
+			return nil, h.avatarRejection(imaging.ErrTooLarge)
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Avatar file could not be read")
+		}
+		defer file.Close()
+
+		// Read one byte past the limit so an inaccurate header cannot slip through
+		data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Avatar file could not be read")
+		}
+		return data, nil
+	}
+
+	var req request.UpdateAvatarRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.Avatar == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Avatar is required")
+	}
+	data, err := helpers.DecodeBase64Image(req.Avatar)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Avatar must be base64 encoded")
+	}
+	return data, nil
+}
+
+// avatarError writes the response for an image that failed processing.
+func (h *AccountHandler) avatarError(c *fiber.Ctx, err error) error {
+	rejected := h.avatarRejection(err)
+	return utils.SendError(c, rejected.Code, rejected.Message)
+}
+
+// avatarRejection explains why an image was rejected.
+func (h *AccountHandler) avatarRejection(err error) *fiber.Error {
+	cfg := h.Config.Avatar
+	switch {
+	case errors.Is(err, imaging.ErrTooLarge):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("Avatar must be at most %d KB", cfg.MaxBytes/1024))
+	case errors.Is(err, imaging.ErrUnsupportedType):
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "Avatar must be a JPEG, PNG or GIF image")
+	case errors.Is(err, imaging.ErrDimensions):
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Avatar must be between %d and %d pixels on each side", cfg.MinDimension, cfg.MaxDimension))
+	case errors.Is(err, imaging.ErrInvalidImage):
+		return fiber.NewError(fiber.StatusBadRequest, "Avatar image is corrupt or incomplete")
+	default:
+		log.Printf("Error processing avatar: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Error processing avatar")
+	}
+}
+
+// removeAvatar deletes a replaced avatar. Failure only leaves an orphaned
+// file, so it is logged rather than returned.
+func (h *AccountHandler) removeAvatar(ctx context.Context, previous *string) {
+	if previous == nil || *previous == "" {
+		return
+	}
+	if err := helpers.DeleteAvatar(ctx, h.Storage, *previous); err != nil {
+		log.Printf("Failed to delete previous avatar: %v", err)
+	}
+}
+
+func (h *AccountHandler) avatarURLs(filename string) map[string]string {
+	base := fmt.Sprintf("%s/images/avatars/%s", strings.TrimRight(h.Config.Server.PublicURL, "/"), url.PathEscape(filename))
+	urls := make(map[string]string, len(helpers.AvatarVariants))
+	for _, variant := range helpers.AvatarVariants {
+		urls[variant.Name] = base + "?size=" + variant.Name
+	}
+	return urls
+}
@@ -0,0 +1,181 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailchange"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// errEmailChangedConcurrently means the email was no longer the one the
+// change was confirmed against.
+var errEmailChangedConcurrently = errors.New("email changed concurrently")
+
+// RequestEmailChange starts an email change by sending a code to the new
+// address. The account keeps its current email until ConfirmEmailChange.
+func (h *AccountHandler) RequestEmailChange(c *fiber.Ctx) error {
+	var req request.ChangeEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	req.NewEmail = strings.TrimSpace(req.NewEmail)
+	if req.NewEmail == "" || req.CurrentPassword == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "New email and current password are required")
+	}
+
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	// A stolen session alone must not be enough to move the account
+	if _, err := h.Passwords.Verify(req.CurrentPassword, account.HashedPassword); err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventEmailChangeRequest, "invalid_password").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Current password is incorrect")
+	}
+
+	if strings.EqualFold(req.NewEmail, account.Email) {
+		return utils.SendError(c, fiber.StatusBadRequest, "New email is the same as the current email")
+	}
+
+	if taken, err := h.emailTaken(req.NewEmail, account); err != nil {
+		return utils.HandleDBError(c, err, "Error checking email")
+	} else if taken {
+		return utils.SendError(c, fiber.StatusConflict, "Email is already in use")
+	}
+
+	code, err := h.OTP.Generate(c.Context(), otp.PurposeEmailChange, account.ID.String())
+	var limited *otp.RateLimitError
+	if errors.As(err, &limited) {
+		return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+	} else if err != nil {
+		log.Printf("OTP error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating confirmation code")
+	}
+
+	if err := h.EmailChange.SetPending(c.Context(), account.ID, req.NewEmail); err != nil {
+		log.Printf("Error storing pending email change: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error starting email change")
+	}
+
+	if err := helpers.SendEmailChangeCodeEmail(c.Context(), h.MailFor(c, &account, emailtemplate.Brand{}), req.NewEmail, code, h.Config.OTP.TTL, h.Config); err != nil {
+		log.Printf("Email error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error sending confirmation email")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventEmailChangeRequest).WithAccount(account.ID).WithSubject(req.NewEmail))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "A confirmation code has been sent to the new email address", nil)
+}
+
+// ConfirmEmailChange swaps in the pending address once its code is entered
+// and sends the previous address a link to undo the change.
+func (h *AccountHandler) ConfirmEmailChange(c *fiber.Ctx) error {
+	var req request.ConfirmEmailChangeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.OTP == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Confirmation code is required")
+	}
+
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	newEmail, err := h.EmailChange.Pending(c.Context(), account.ID)
+	if errors.Is(err, emailchange.ErrNoPendingChange) {
+		return utils.SendError(c, fiber.StatusBadRequest, "No pending email change. Please request a new code.")
+	} else if err != nil {
+		log.Printf("Error loading pending email change: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error confirming email change")
+	}
+
+	err = h.OTP.Verify(c.Context(), otp.PurposeEmailChange, account.ID.String(), req.OTP)
+	switch {
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		h.EmailChange.ClearPending(c.Context(), account.ID)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventEmailChange, "too_many_attempts").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusTooManyRequests, "Too many incorrect attempts. Please request a new code.")
+	case errors.Is(err, otp.ErrInvalidCode), errors.Is(err, otp.ErrExpired):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventEmailChange, "invalid_code").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired confirmation code")
+	case err != nil:
+		log.Printf("OTP verification error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error verifying confirmation code")
+	}
+
+	// The address may have been registered since the code was sent
+	if taken, err := h.emailTaken(newEmail, account); err != nil {
+		return utils.HandleDBError(c, err, "Error checking email")
+	} else if taken {
+		h.EmailChange.ClearPending(c.Context(), account.ID)
+		return utils.SendError(c, fiber.StatusConflict, "Email is already in use")
+	}
+
+	oldEmail := account.Email
+	changed := audit.Success(c, audit.EventEmailChange).WithAccount(account.ID).WithSubject(newEmail)
+	err = h.saveProfile(c, account.ID, changed, func(tx *gorm.DB) error {
+		result := tx.Model(&models.Account{}).
+			Where("id = ? AND email = ?", account.ID, oldEmail).
+			Update("email", newEmail)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errEmailChangedConcurrently
+		}
+		return result.Error
+	})
+	if errors.Is(err, errEmailChangedConcurrently) {
+		return utils.SendError(c, fiber.StatusConflict, "Email was changed by another request")
+	} else if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return utils.SendError(c, fiber.StatusConflict, "Email is already in use")
+		}
+		return utils.HandleDBError(c, err, "Error updating email")
+	}
+
+	h.EmailChange.ClearPending(c.Context(), account.ID)
+
+	h.notifyPreviousEmail(c, &account, emailchange.Change{AccountID: account.ID, OldEmail: oldEmail, NewEmail: newEmail})
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Email address updated", nil)
+}
+
+// notifyPreviousEmail tells the old address about the change and sends the
+// revert link. The change has already been committed, so failures are only
+// logged, and the notice goes out without a link if no token could be made.
+func (h *AccountHandler) notifyPreviousEmail(c *fiber.Ctx, account *models.Account, change emailchange.Change) {
+	var revertLink string
+	if token, err := h.EmailChange.CreateRevertToken(c.Context(), change); err != nil {
+		log.Printf("Error creating email revert token: %v", err)
+	} else {
+		revertLink = fmt.Sprintf("%s/revert-email/%s", strings.TrimRight(h.Config.Server.PublicURL, "/"), token)
+	}
+
+	if err := helpers.SendEmailChangedEmail(c.Context(), h.MailFor(c, account, emailtemplate.Brand{}), change.OldEmail, change.NewEmail, revertLink, h.Config.EmailChange.RevertTTL, h.Config); err != nil {
+		log.Printf("Error sending email change notification: %v", err)
+	}
+}
+
+func (h *AccountHandler) emailTaken(email string, account models.Account) (bool, error) {
+	var count int64
+	err := h.DB.Model(&models.Account{}).
+		Where("LOWER(email) = LOWER(?) AND id <> ?", email, account.ID).
+		Count(&count).Error
+	return count > 0, err
+}
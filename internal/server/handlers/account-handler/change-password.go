@@ -0,0 +1,78 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/password"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ChangePassword replaces the signed-in account's password after checking the
+// current one. Every refresh token is revoked so other sessions must sign in
+// again.
+func (h *AccountHandler) ChangePassword(c *fiber.Ctx) error {
+	var req request.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Current and new password are required")
+	}
+
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	if _, err := h.Passwords.Verify(req.CurrentPassword, account.HashedPassword); err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventPasswordChange, "invalid_password").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Current password is incorrect")
+	}
+
+	violations := h.PasswordPolicy.Check(req.NewPassword, password.UserInfo{
+		Username:  account.Username,
+		Email:     account.Email,
+		FirstName: account.FirstName,
+		LastName:  account.LastName,
+	})
+	if req.NewPassword == req.CurrentPassword {
+		violations = append(violations, password.Violation{
+			Code:    password.ViolationSameAsPrevious,
+			Message: "New password must be different from the current password",
+		})
+	}
+	if len(violations) > 0 {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventPasswordChange, "policy_violation").WithAccount(account.ID))
+		return utils.SendPasswordViolations(c, violations)
+	}
+
+	hashed, err := h.Passwords.Hash(req.NewPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating password")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).Update("hashed_password", hashed).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.ID).Delete(&models.AccountRefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", account.ID).Delete(&models.ServiceRefreshToken{}).Error
+	})
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error updating password")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventPasswordChange).WithAccount(account.ID))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Password changed. Please sign in again on your other devices.", nil)
+}
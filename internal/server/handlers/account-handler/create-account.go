@@ -1,17 +1,24 @@
 package account
 
 import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/events"
 	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/imaging"
 	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/password"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
-	"fmt"
+	"aspire-auth/internal/sms"
+	"aspire-auth/internal/utils"
 	"log"
-	"math/rand"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 // CreateAccount godoc
@@ -23,6 +30,7 @@ import (
 // @Param account body request.CreateAccountRequest true "Account information"
 // @Success 201 {object} response.CreateAccountResponse
 // @Failure 400 {object} response.APIResponse
+// @Failure 409 {object} response.APIResponse
 // @Failure 500 {object} response.APIResponse
 // @Router /account [post]
 func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
@@ -36,16 +44,13 @@ func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
 	var req request.CreateAccountRequest
 
 	if err := c.BodyParser(&req); err != nil {
-		log.Printf("Error parsing request body: %v\nBody: %s", err, string(c.Body()))
+		log.Printf("Error parsing request body: %v", err)
 		return c.Status(400).JSON(response.APIResponse{
 			Success: false,
 			Message: "Invalid JSON format in request body",
 		})
 	}
 
-	// Debug log
-	log.Printf("Received request: %+v", req)
-
 	// Validate required fields
 	if req.Username == "" || req.Email == "" || req.Password == "" ||
 		req.FirstName == "" || req.LastName == "" {
@@ -55,18 +60,41 @@ func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
 		})
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	var phoneNumber *string
+	if req.PhoneNumber != "" {
+		if !sms.ValidNumber(req.PhoneNumber) {
+			tx.Rollback()
+			return utils.SendError(c, fiber.StatusBadRequest, "Phone number must be in E.164 format, e.g. +14155550123")
+		}
+		phoneNumber = &req.PhoneNumber
+	}
+
+	otpChannel, err := otp.ParseChannel(req.OTPChannel, otp.ChannelEmail)
 	if err != nil {
-		return c.Status(500).JSON(response.APIResponse{
-			Success: false,
-			Message: "Error hashing password",
-		})
+		tx.Rollback()
+		return utils.SendError(c, fiber.StatusBadRequest, "OTP channel must be email or sms")
+	}
+	if otpChannel == otp.ChannelSMS && phoneNumber == nil {
+		tx.Rollback()
+		return utils.SendError(c, fiber.StatusBadRequest, "A phone number is required to receive codes by SMS")
+	}
+
+	violations := h.PasswordPolicy.Check(req.Password, password.UserInfo{
+		Username:  req.Username,
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+	})
+	if len(violations) > 0 {
+		tx.Rollback()
+		return utils.SendPasswordViolations(c, violations)
 	}
 
 	var dateOfBirth *time.Time
 	if req.DateOfBirth != "" {
 		parsedDate, err := time.Parse("2006-01-02", req.DateOfBirth)
 		if err != nil {
+			tx.Rollback()
 			return c.Status(400).JSON(response.APIResponse{
 				Success: false,
 				Message: "Invalid date format. Use YYYY-MM-DD",
@@ -75,6 +103,17 @@ func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
 		dateOfBirth = &parsedDate
 	}
 
+	// Stored only when chosen; otherwise each email follows Accept-Language
+	var locale *string
+	if req.Locale != "" {
+		if !h.Templates.SupportsLocale(req.Locale) {
+			tx.Rollback()
+			return utils.SendError(c, fiber.StatusBadRequest, "Unsupported locale")
+		}
+		resolved := h.Templates.ResolveLocale(&req.Locale, "")
+		locale = &resolved
+	}
+
 	var gender *models.GenderType
 	if req.Gender != "" {
 		g := models.GenderType(req.Gender)
@@ -82,47 +121,129 @@ func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
 	}
 
 	var avatarFilename *string
-	var avatarPath string
-
-	// Handle avatar base64
-	if c.Get("Avatar") != "" { // Check if Avatar key exists in the request
-		if req.Avatar != "" { // Check if Avatar value is not empty
-			filename, err := helpers.SaveBase64File(req.Avatar, "images/avatars", ".png")
-			if err != nil {
-				tx.Rollback()
-				log.Printf("Error saving avatar: %v", err)
-				return c.Status(500).JSON(response.APIResponse{
-					Success: false,
-					Message: "Error saving avatar",
-				})
-			}
-			avatarFilename = &filename
-			avatarPath = fmt.Sprintf("images/avatars/%s", filename)
-
-			defer func() {
-				if tx.Error != nil {
-					if err := helpers.DeleteFile(avatarPath); err != nil {
-						log.Printf("Failed to delete avatar file after error: %v", err)
-					}
+	committed := false
+
+	// Everything up to the duplicate checks runs for every signup, so a
+	// duplicate does the same work as a new account. The avatar is removed
+	// again unless the account is created.
+	if req.Avatar != "" {
+		data, err := helpers.DecodeBase64Image(req.Avatar)
+		if err != nil {
+			tx.Rollback()
+			return utils.SendError(c, fiber.StatusBadRequest, "Avatar must be base64 encoded")
+		}
+		processed, err := imaging.Process(data, helpers.AvatarOptions(h.Config.Avatar))
+		if err != nil {
+			tx.Rollback()
+			return h.avatarError(c, err)
+		}
+		filename, err := helpers.SaveAvatar(c.Context(), h.Storage, processed)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error saving avatar: %v", err)
+			return c.Status(500).JSON(response.APIResponse{
+				Success: false,
+				Message: "Error saving avatar",
+			})
+		}
+		avatarFilename = &filename
+
+		defer func() {
+			if !committed {
+				if err := helpers.DeleteAvatar(c.Context(), h.Storage, filename); err != nil {
+					log.Printf("Failed to delete avatar file after error: %v", err)
 				}
-			}()
+			}
+		}()
+	}
+
+	hashedPassword, err := h.Passwords.Hash(req.Password)
+	if err != nil {
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error hashing password",
+		})
+	}
+
+	// Duplicate checks run after hashing so both outcomes take similar time.
+	// A taken username is reported as such: usernames are public handles the
+	// person has to change to sign up at all, so this is an accepted leak. It
+	// says nothing about which email the username belongs to.
+	var usernameTaken int64
+	if err := h.DB.Model(&models.Account{}).Where("username = ?", req.Username).Count(&usernameTaken).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Database error: %v", err)
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error creating account",
+		})
+	}
+	if usernameTaken > 0 {
+		tx.Rollback()
+		return c.Status(409).JSON(response.APIResponse{
+			Success: false,
+			Message: "Username is already taken",
+		})
+	}
+
+	var emailTaken int64
+	if err := h.DB.Model(&models.Account{}).Where("email = ?", req.Email).Count(&emailTaken).Error; err != nil {
+		tx.Rollback()
+		log.Printf("Database error: %v", err)
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error creating account",
+		})
+	}
+	if emailTaken > 0 {
+		tx.Rollback()
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountSignup, "duplicate_email").WithSubject(req.Email))
+		return h.duplicateSignup(c)
+	}
+
+	if phoneNumber != nil {
+		var phoneTaken int64
+		if err := h.DB.Model(&models.Account{}).Where("phone_number = ?", *phoneNumber).Count(&phoneTaken).Error; err != nil {
+			tx.Rollback()
+			log.Printf("Database error: %v", err)
+			return c.Status(500).JSON(response.APIResponse{
+				Success: false,
+				Message: "Error creating account",
+			})
+		}
+		if phoneTaken > 0 {
+			tx.Rollback()
+			h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountSignup, "duplicate_phone").WithSubject(req.Email))
+			if !h.Config.Security.UniformAuthResponses {
+				return utils.SendError(c, fiber.StatusConflict, "Phone number is already in use")
+			}
+			return h.duplicateSignup(c)
 		}
 	}
 
 	account := models.Account{
 		Username:       req.Username,
 		Email:          req.Email,
-		HashedPassword: string(hashedPassword),
+		HashedPassword: hashedPassword,
 		FirstName:      req.FirstName,
 		LastName:       req.LastName,
 		DateOfBirth:    dateOfBirth,
 		Gender:         gender,
 		Avatar:         avatarFilename,
+		Locale:         locale,
+		PhoneNumber:    phoneNumber,
+		OTPChannel:     string(otpChannel),
 		RoleType:       models.RoleUser,
 	}
 
+	writeStarted := time.Now()
 	if err := tx.Create(&account).Error; err != nil {
 		tx.Rollback()
+		// Lost a race with a concurrent signup for the same email
+		if strings.Contains(err.Error(), "duplicate key") {
+			h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountSignup, "duplicate_key").WithSubject(req.Email))
+			return h.duplicateSignup(c)
+		}
 		log.Printf("Database error: %v", err)
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
@@ -130,27 +251,23 @@ func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate OTP
-	otp := fmt.Sprintf("%06d", rand.Intn(999999))
-
-	// Store OTP in Redis
-	err = h.Redis.Set(c.Context(), fmt.Sprintf("otp:%s", account.ID.String()), otp, 15*time.Minute).Err()
+	// The verification code is generated and sent by a subscriber once the
+	// account is committed, so a failed signup leaves nothing behind
+	signedUp := audit.Success(c, audit.EventAccountSignup).WithAccount(account.ID)
+	err = events.Publish(c.Context(), tx, events.Event{
+		Type: events.AccountCreated,
+		Payload: events.AccountCreatedPayload{
+			AccountID: account.ID,
+			Locale:    h.MailFor(c, &account, emailtemplate.Brand{}).Locale,
+		},
+		Audit: &signedUp,
+	})
 	if err != nil {
 		tx.Rollback()
-		log.Printf("Redis error: %v", err)
-		return c.Status(500).JSON(response.APIResponse{
-			Success: false,
-			Message: "Error in verification system. Please try again.",
-		})
-	}
-
-	// Send verification email
-	if err := helpers.SendVerificationEmail(account.Email, otp, h.Config); err != nil {
-		tx.Rollback()
-		log.Printf("Email error: %v", err)
+		log.Printf("Event publish error: %v", err)
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
-			Message: "Error sending verification email. Please try again.",
+			Message: "Error creating account",
 		})
 	}
 
@@ -163,12 +280,39 @@ func (h *AccountHandler) CreateAccount(c *fiber.Ctx) error {
 			Message: "Error completing account creation. Please try again.",
 		})
 	}
+	committed = true
+	h.signupWrite.Observe(time.Since(writeStarted))
 
 	return c.Status(201).JSON(response.CreateAccountResponse{
 		APIResponse: response.APIResponse{
 			Success: true,
-			Message: "Account created successfully. Please check your email for verification.",
+			Message: signupSuccessMessage,
 		},
 		AccountID: account.ID.String(),
 	})
 }
+
+const signupSuccessMessage = "Account created successfully. Please check your email or phone for the verification code."
+
+// duplicateSignup answers a signup for an existing email. In uniform mode it
+// mirrors a successful signup with a throwaway account ID, which behaves like
+// any unknown account on /verify and /resend-otp, after waiting as long as
+// writing a new account usually takes.
+func (h *AccountHandler) duplicateSignup(c *fiber.Ctx) error {
+	if !h.Config.Security.UniformAuthResponses {
+		return c.Status(409).JSON(response.APIResponse{
+			Success: false,
+			Message: "An account with this email already exists",
+		})
+	}
+
+	h.signupWrite.Wait(c.Context())
+
+	return c.Status(201).JSON(response.CreateAccountResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: signupSuccessMessage,
+		},
+		AccountID: uuid.New().String(),
+	})
+}
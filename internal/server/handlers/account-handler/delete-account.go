@@ -1,19 +1,155 @@
 package account
 
 import (
+	"aspire-auth/internal/access"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
 	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/transfer"
 	"aspire-auth/internal/utils"
+	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// DeleteAccount schedules the account for deletion once the grace period is
+// over. Every session is signed out straight away; signing in again and
+// calling CancelDeletion keeps the account. The purge job does the rest.
+//
+// Personal services can be offered to another account, which has to accept
+// each one from the emailed link like any other transfer; services nobody
+// has accepted by the time of the purge are deleted with the account.
 func (h *AccountHandler) DeleteAccount(c *fiber.Ctx) error {
+	var req request.DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.CurrentPassword == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Current password is required")
+	}
+
 	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
 
-	// Delete the account
-	if err := h.DB.Delete(&models.Account{}, "id = ?", authToken.UserID).Error; err != nil {
-		return utils.HandleError(c, err)
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+	if account.DeletionScheduledAt != nil {
+		return utils.SendError(c, fiber.StatusConflict, "Account is already scheduled for deletion")
+	}
+
+	if _, err := h.Passwords.Verify(req.CurrentPassword, account.HashedPassword); err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountDeletionRequest, "invalid_password").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Current password is incorrect")
+	}
+
+	// Every unusable address gets the same answer, so this cannot be used
+	// to find out which emails are registered
+	var recipient *models.Account
+	if transferEmail := utils.NormalizeEmail(req.TransferServicesTo); transferEmail != "" {
+		var target models.Account
+		if err := h.DB.Where("LOWER(email) = ?", transferEmail).First(&target).Error; err != nil ||
+			target.ID == account.ID || !access.CanOwnServices(&target) {
+			return utils.SendError(c, fiber.StatusBadRequest, "Services can only be offered to another verified, active account")
+		}
+		recipient = &target
+	}
+
+	deleteAt := time.Now().Add(h.Config.Deletion.GracePeriod)
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).Update("deletion_scheduled_at", deleteAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.ID).Delete(&models.AccountRefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.ID).Delete(&models.ServiceRefreshToken{}).Error; err != nil {
+			return err
+		}
+
+		var offeredTo string
+		if recipient != nil {
+			offeredTo = recipient.Email
+		}
+		mail := h.MailFor(c, &account, emailtemplate.Brand{})
+		mail.Queue = h.Outbox.WithTx(tx)
+		return helpers.SendAccountDeletionEmail(c.Context(), mail, account.Email, deleteAt, offeredTo, h.Config)
+	})
+	if err != nil {
+		log.Printf("Error scheduling account deletion: %v", err)
+		return utils.HandleDBError(c, err, "Error scheduling account deletion")
+	}
+
+	event := audit.Success(c, audit.EventAccountDeletionRequest).WithAccount(account.ID)
+	if recipient != nil {
+		offered := h.offerServices(c, &account, recipient)
+		event = event.WithMetadata(map[string]interface{}{
+			"offered_to":       recipient.ID.String(),
+			"services_offered": offered,
+		})
 	}
+	h.Audit.Record(c.Context(), event)
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Account scheduled for deletion", fiber.Map{
+		"deletion_scheduled_at": deleteAt,
+	})
+}
+
+// offerServices offers each personal service of account to recipient and
+// returns how many offers went out. Organization services stay with their
+// organization.
+func (h *AccountHandler) offerServices(c *fiber.Ctx, account, recipient *models.Account) int {
+	var services []models.Service
+	if err := h.DB.Where("owner_id = ? AND organization_id IS NULL", account.ID).Find(&services).Error; err != nil {
+		log.Printf("Error loading services to offer: %v", err)
+		return 0
+	}
+
+	mail := h.MailFor(c, recipient, emailtemplate.Brand{})
+	offered := 0
+	for i := range services {
+		service := &services[i]
+		_, err := h.Transfers.Offer(c.Context(), transfer.Offer{ServiceID: service.ID, FromID: account.ID, ToID: recipient.ID}, func(token string) error {
+			reviewLink := fmt.Sprintf("%s/service/transfer/%s", strings.TrimRight(h.Config.Server.PublicURL, "/"), token)
+			return helpers.SendServiceTransferEmail(c.Context(), mail, recipient.Email, account.Email, service.ServiceName, reviewLink, h.Config.Transfer.TTL, h.Config)
+		})
+		if err != nil {
+			log.Printf("Error offering service %s: %v", services[i].ID, err)
+			continue
+		}
+		offered++
+		h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceTransferRequest).WithAccount(account.ID).WithService(services[i].ID).WithSubject(recipient.Email).WithMetadata(map[string]interface{}{
+			"to_id": recipient.ID.String(),
+		}))
+	}
+	return offered
+}
+
+// CancelDeletion keeps an account that is still within its grace period.
+// Services offered along with the deletion stay on offer until withdrawn.
+func (h *AccountHandler) CancelDeletion(c *fiber.Ctx) error {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	// The purge job locks the row, so a cancellation either wins or finds nothing left
+	result := h.DB.Model(&models.Account{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", authToken.UserID).
+		Update("deletion_scheduled_at", nil)
+	if result.Error != nil {
+		return utils.HandleDBError(c, result.Error, "Error cancelling account deletion")
+	}
+	if result.RowsAffected == 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "Account is not scheduled for deletion")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventAccountDeletionCancel).WithAccount(uuid.MustParse(authToken.UserID)))
 
-	return utils.SendSuccess(c, fiber.StatusOK, "Account deleted successfully", nil)
+	return utils.SendSuccess(c, fiber.StatusOK, "Account deletion cancelled", nil)
 }
@@ -0,0 +1,121 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/storage"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequestExport queues an archive of everything held about the account. It
+// is built in the background; poll GetExport for the download link.
+func (h *AccountHandler) RequestExport(c *fiber.Ctx) error {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+	accountID := uuid.MustParse(authToken.UserID)
+
+	requested, created, err := h.Exports.Request(c.Context(), accountID)
+	if err != nil {
+		log.Printf("Error queueing data export: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error requesting data export")
+	}
+
+	message := "Data export requested. It will be ready to download shortly."
+	if created {
+		h.Audit.Record(c.Context(), audit.Success(c, audit.EventDataExportRequest).WithAccount(accountID).WithSubject(requested.ID.String()))
+	} else {
+		message = "A data export is already being prepared"
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(response.AccountExportResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: message,
+		},
+		Export: *requested,
+	})
+}
+
+// GetExport reports the state of an export and, once it is ready, returns a
+// fresh short-lived download link.
+func (h *AccountHandler) GetExport(c *fiber.Ctx) error {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	exportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid export ID")
+	}
+
+	var requested models.AccountExport
+	if err := h.DB.Where("id = ? AND account_id = ?", exportID, authToken.UserID).First(&requested).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Export not found")
+	}
+
+	result := response.AccountExportResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Export retrieved successfully",
+		},
+		Export: requested,
+	}
+	if requested.Status == models.ExportReady {
+		link, expiresAt := h.Exports.Link(&requested)
+		result.DownloadURL = link
+		result.DownloadExpiresAt = &expiresAt
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// DownloadExport serves the archive behind a signed download link. The link
+// is the only credential, so it works from a browser without a session. When
+// the storage backend can sign its own URLs the client is redirected there;
+// otherwise the archive is streamed through this server.
+func (h *AccountHandler) DownloadExport(c *fiber.Ctx) error {
+	exportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid export ID")
+	}
+
+	if err := h.Exports.VerifyLink(exportID, c.Query("expires"), c.Query("signature")); err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventDataExportDownload, "invalid_link").WithSubject(exportID.String()))
+		return utils.SendError(c, fiber.StatusForbidden, "Invalid or expired download link")
+	}
+
+	var requested models.AccountExport
+	err = h.DB.Where("id = ? AND status = ? AND expires_at > ?", exportID, models.ExportReady, time.Now()).First(&requested).Error
+	if err != nil || requested.StorageKey == nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Export not found or no longer available")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventDataExportDownload).WithAccount(requested.AccountID).WithSubject(requested.ID.String()))
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	filename := "account-export-" + requested.CreatedAt.Format("2006-01-02") + ".zip"
+
+	signed, err := h.Storage.SignedURL(c.Context(), *requested.StorageKey, h.Config.Export.LinkTTL, filename)
+	if err == nil {
+		return c.Redirect(signed, fiber.StatusFound)
+	} else if !errors.Is(err, storage.ErrSignedURLUnsupported) {
+		log.Printf("Error signing export URL: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error downloading export")
+	}
+
+	body, object, err := h.Storage.Get(c.Context(), *requested.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return utils.SendError(c, fiber.StatusNotFound, "Export not found or no longer available")
+	} else if err != nil {
+		log.Printf("Error reading export archive: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error downloading export")
+	}
+
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, "application/zip")
+	return c.SendStream(body, int(object.Size))
+}
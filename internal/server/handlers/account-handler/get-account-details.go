@@ -22,16 +22,22 @@ func (h *AccountHandler) GetAccountDetails(c *fiber.Ctx) error {
 			Message: "Account details retrieved successfully",
 		},
 		Account: response.AccountResponse{
-			Username:    account.Username,
-			Email:       account.Email,
-			FirstName:   account.FirstName,
-			LastName:    account.LastName,
-			DateOfBirth: account.DateOfBirth,
-			Gender:      account.Gender,
-			RoleType:    account.RoleType,
-			Avatar:      account.Avatar,
-			CreatedAt:   account.CreatedAt,
-			UpdatedAt:   account.UpdatedAt,
+			Username:            account.Username,
+			Email:               account.Email,
+			FirstName:           account.FirstName,
+			LastName:            account.LastName,
+			DateOfBirth:         account.DateOfBirth,
+			Gender:              account.Gender,
+			RoleType:            account.RoleType,
+			Avatar:              account.Avatar,
+			Locale:              account.Locale,
+			PhoneNumber:         account.PhoneNumber,
+			PhoneVerified:       account.PhoneVerified,
+			OTPChannel:          account.OTPChannel,
+			MFAEnabled:          account.MFAEnabled,
+			DeletionScheduledAt: account.DeletionScheduledAt,
+			CreatedAt:           account.CreatedAt,
+			UpdatedAt:           account.UpdatedAt,
 		},
 	})
 }
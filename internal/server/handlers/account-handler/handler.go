@@ -1,11 +1,19 @@
 package account
 
-import "aspire-auth/internal/container"
+import (
+	"aspire-auth/internal/container"
+	"aspire-auth/internal/utils"
+	"time"
+)
 
 type AccountHandler struct {
 	*container.Container
+
+	// signupWrite tracks how long a successful signup spends writing the
+	// account, which a duplicate signup waits out in uniform mode
+	signupWrite *utils.LatencyTracker
 }
 
 func NewAccountHandler(base *container.Container) *AccountHandler {
-	return &AccountHandler{Container: base}
+	return &AccountHandler{Container: base, signupWrite: utils.NewLatencyTracker(20 * time.Millisecond)}
 }
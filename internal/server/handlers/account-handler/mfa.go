@@ -0,0 +1,67 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UpdateMFA sets the channel codes are sent over and whether sign-in needs
+// a code after the password. Both weaken or strengthen the account, so the
+// current password is required.
+func (h *AccountHandler) UpdateMFA(c *fiber.Ctx) error {
+	var req request.UpdateMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.CurrentPassword == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Current password is required")
+	}
+	if req.Enabled == nil && req.Channel == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Nothing to update")
+	}
+
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	if _, err := h.Passwords.Verify(req.CurrentPassword, account.HashedPassword); err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMFASettingsChange, "invalid_password").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Current password is incorrect")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Channel != "" {
+		channel, err := otp.ParseChannel(req.Channel, otp.ChannelEmail)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Channel must be email or sms")
+		}
+		if channel == otp.ChannelSMS && (account.PhoneNumber == nil || !account.PhoneVerified) {
+			return utils.SendError(c, fiber.StatusBadRequest, "Verify a phone number before choosing SMS")
+		}
+		updates["otp_channel"] = string(channel)
+	}
+	if req.Enabled != nil {
+		updates["mfa_enabled"] = *req.Enabled
+	}
+
+	if err := h.DB.Model(&models.Account{}).Where("id = ?", account.ID).Updates(updates).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error updating MFA settings")
+	}
+
+	event := audit.Success(c, audit.EventMFASettingsChange).WithAccount(account.ID)
+	if req.Enabled != nil {
+		event = event.WithSubject("enabled=" + strconv.FormatBool(*req.Enabled))
+	}
+	h.Audit.Record(c.Context(), event)
+
+	return utils.SendSuccess(c, fiber.StatusOK, "MFA settings updated", nil)
+}
@@ -0,0 +1,156 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/sms"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// UpdatePhone sets the account's phone number and texts it a confirmation
+// code. Until VerifyPhone succeeds the number cannot receive other codes, so
+// an SMS code preference falls back to email.
+func (h *AccountHandler) UpdatePhone(c *fiber.Ctx) error {
+	var req request.UpdatePhoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	req.PhoneNumber = strings.TrimSpace(req.PhoneNumber)
+	if !sms.ValidNumber(req.PhoneNumber) {
+		return utils.SendError(c, fiber.StatusBadRequest, "Phone number must be in E.164 format, e.g. +14155550123")
+	}
+
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	if account.PhoneNumber != nil && *account.PhoneNumber == req.PhoneNumber && account.PhoneVerified {
+		return utils.SendError(c, fiber.StatusBadRequest, "Phone number is already verified")
+	}
+
+	var taken int64
+	if err := h.DB.Model(&models.Account{}).
+		Where("phone_number = ? AND id <> ?", req.PhoneNumber, account.ID).
+		Count(&taken).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error checking phone number")
+	}
+	if taken > 0 {
+		return utils.SendError(c, fiber.StatusConflict, "Phone number is already in use")
+	}
+
+	code, err := h.OTP.Generate(c.Context(), otp.PurposePhoneVerification, account.ID.String())
+	var limited *otp.RateLimitError
+	if errors.As(err, &limited) {
+		return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+	} else if err != nil {
+		log.Printf("OTP error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating confirmation code")
+	}
+
+	result := h.DB.Model(&models.Account{}).Where("id = ?", account.ID).Updates(map[string]interface{}{
+		"phone_number":   req.PhoneNumber,
+		"phone_verified": false,
+		"otp_channel":    string(otp.ChannelEmail),
+	})
+	if result.Error != nil {
+		if strings.Contains(result.Error.Error(), "duplicate key") {
+			return utils.SendError(c, fiber.StatusConflict, "Phone number is already in use")
+		}
+		return utils.HandleDBError(c, result.Error, "Error updating phone number")
+	}
+
+	account.PhoneNumber = &req.PhoneNumber
+	sender := &helpers.SMSOTPSender{SMS: h.SMS, Mail: h.MailFor(c, &account, emailtemplate.Brand{}), Config: h.Config}
+	if err := sender.Send(c.Context(), h.CodeDelivery(&account, otp.PurposePhoneVerification, code)); err != nil {
+		log.Printf("SMS error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error sending confirmation code")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventPhoneChange).WithAccount(account.ID))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "A confirmation code has been sent to the phone number", nil)
+}
+
+// VerifyPhone confirms the phone number with the code texted to it.
+func (h *AccountHandler) VerifyPhone(c *fiber.Ctx) error {
+	var req request.VerifyPhoneRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.OTP == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Confirmation code is required")
+	}
+
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", authToken.UserID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+	if account.PhoneNumber == nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "No phone number to verify")
+	}
+
+	err := h.OTP.Verify(c.Context(), otp.PurposePhoneVerification, account.ID.String(), req.OTP)
+	switch {
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventPhoneVerify, "too_many_attempts").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusTooManyRequests, "Too many incorrect attempts. Please request a new code.")
+	case errors.Is(err, otp.ErrInvalidCode), errors.Is(err, otp.ErrExpired):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventPhoneVerify, "invalid_code").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired confirmation code")
+	case err != nil:
+		log.Printf("OTP verification error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error verifying confirmation code")
+	}
+
+	// The number may have been replaced since the code was sent
+	result := h.DB.Model(&models.Account{}).
+		Where("id = ? AND phone_number = ?", account.ID, *account.PhoneNumber).
+		Update("phone_verified", true)
+	if result.Error != nil {
+		return utils.HandleDBError(c, result.Error, "Error verifying phone number")
+	}
+	if result.RowsAffected == 0 {
+		return utils.SendError(c, fiber.StatusConflict, "Phone number was changed by another request")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventPhoneVerify).WithAccount(account.ID))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Phone number verified", nil)
+}
+
+// RemovePhone deletes the phone number. Codes go back to email, which also
+// keeps MFA working for accounts that received it by text.
+func (h *AccountHandler) RemovePhone(c *fiber.Ctx) error {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+
+	result := h.DB.Model(&models.Account{}).Where("id = ?", authToken.UserID).Updates(map[string]interface{}{
+		"phone_number":   nil,
+		"phone_verified": false,
+		"otp_channel":    string(otp.ChannelEmail),
+	})
+	if result.Error != nil {
+		return utils.HandleDBError(c, result.Error, "Error removing phone number")
+	}
+	if result.RowsAffected == 0 {
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventPhoneChange).WithAccount(uuid.MustParse(authToken.UserID)).WithSubject("removed"))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Phone number removed", nil)
+}
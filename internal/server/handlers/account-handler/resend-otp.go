@@ -1,14 +1,15 @@
 package account
 
 import (
-	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
 	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
-	"fmt"
+	"aspire-auth/internal/utils"
+	"errors"
 	"log"
-	"math/rand"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -22,8 +23,18 @@ func (h *AccountHandler) ResendOTP(c *fiber.Ctx) error {
 		})
 	}
 
+	if _, err := otp.ParseChannel(req.Channel, otp.ChannelEmail); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Channel must be email or sms")
+	}
+
+	uniform := h.Config.Security.UniformAuthResponses
+
 	var account models.Account
 	if err := h.DB.Where("id = ?", req.AccountID).First(&account).Error; err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountResendCode, "account_not_found").WithSubject(req.AccountID))
+		if uniform {
+			return h.uniformResend(c, req.AccountID)
+		}
 		return c.Status(404).JSON(response.APIResponse{
 			Success: false,
 			Message: "Account not found",
@@ -31,36 +42,79 @@ func (h *AccountHandler) ResendOTP(c *fiber.Ctx) error {
 	}
 
 	if account.IsVerified {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountResendCode, "already_verified").WithAccount(account.ID))
+		if uniform {
+			return h.uniformResend(c, req.AccountID)
+		}
 		return c.Status(400).JSON(response.APIResponse{
 			Success: false,
 			Message: "Account is already verified",
 		})
 	}
 
-	// Generate new OTP
-	otp := fmt.Sprintf("%06d", rand.Intn(999999))
-
-	// Store in Redis
-	err := h.Redis.Set(c.Context(), fmt.Sprintf("otp:%s", account.ID.String()), otp, 15*time.Minute).Err()
+	sender, err := h.CodeSender(h.MailFor(c, &account, emailtemplate.Brand{}), &account, req.Channel)
 	if err != nil {
-		log.Printf("Redis error: %v", err)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountResendCode, "channel_unavailable").WithAccount(account.ID))
+		if uniform {
+			return h.uniformResend(c, req.AccountID)
+		}
+		return utils.SendError(c, fiber.StatusBadRequest, "No phone number to send the code to")
+	}
+
+	// Generate new OTP, subject to the per-account resend limits
+	code, err := h.OTP.Generate(c.Context(), otp.PurposeAccountVerification, account.ID.String())
+	var limited *otp.RateLimitError
+	if errors.As(err, &limited) {
+		return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+	} else if err != nil {
+		log.Printf("OTP error: %v", err)
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
 			Message: "Error generating new OTP",
 		})
 	}
 
-	// Send new verification email
-	if err := helpers.SendVerificationEmail(account.Email, otp, h.Config); err != nil {
-		log.Printf("Email error: %v", err)
+	if err := h.OTP.RecordChannel(c.Context(), otp.PurposeAccountVerification, account.ID.String(), sender.Channel()); err != nil {
+		log.Printf("OTP error: %v", err)
+	}
+
+	// Send the new code
+	delivery := h.CodeDelivery(&account, otp.PurposeAccountVerification, code)
+	delivery.Link = h.VerificationLink(c.Context(), account.ID)
+	if err := sender.Send(c.Context(), delivery); err != nil {
+		log.Printf("Error sending verification code by %s: %v", sender.Channel(), err)
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
-			Message: "Error sending verification email",
+			Message: "Error sending verification code",
 		})
 	}
 
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventAccountResendCode).WithAccount(account.ID))
+
+	if uniform {
+		return c.Status(200).JSON(response.APIResponse{
+			Success: true,
+			Message: utils.UniformRecoveryMessage,
+		})
+	}
 	return c.Status(200).JSON(response.APIResponse{
 		Success: true,
 		Message: "New OTP sent successfully",
 	})
 }
+
+// uniformResend answers a resend that will not send anything while still
+// applying the resend throttle, so a 429 looks the same for every subject.
+func (h *AccountHandler) uniformResend(c *fiber.Ctx, subject string) error {
+	err := h.OTP.ThrottleSend(c.Context(), otp.PurposeAccountVerification, subject)
+	var limited *otp.RateLimitError
+	if errors.As(err, &limited) {
+		return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+	} else if err != nil {
+		log.Printf("OTP throttle error: %v", err)
+	}
+	return c.Status(200).JSON(response.APIResponse{
+		Success: true,
+		Message: utils.UniformRecoveryMessage,
+	})
+}
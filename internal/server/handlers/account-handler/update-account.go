@@ -1,12 +1,16 @@
 package account
 
 import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/events"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/utils"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func (h *AccountHandler) UpdateAccount(c *fiber.Ctx) error {
@@ -20,7 +24,6 @@ func (h *AccountHandler) UpdateAccount(c *fiber.Ctx) error {
 
 	account := models.Account{
 		Username:  req.Username,
-		Email:     req.Email,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 	}
@@ -31,17 +34,49 @@ func (h *AccountHandler) UpdateAccount(c *fiber.Ctx) error {
 	}
 
 	if req.Avatar != "" {
-		account.Avatar = &req.Avatar
+		return utils.SendError(c, fiber.StatusBadRequest, "Use PUT /account/avatar to change the avatar")
+	}
+
+	if req.Locale != "" {
+		if !h.Templates.SupportsLocale(req.Locale) {
+			return utils.SendError(c, fiber.StatusBadRequest, "Unsupported locale")
+		}
+		locale := h.Templates.ResolveLocale(&req.Locale, "")
+		account.Locale = &locale
 	}
 
 	if !req.DateOfBirth.IsZero() {
 		account.DateOfBirth = &req.DateOfBirth
 	}
 
-	if err := h.DB.Model(&models.Account{}).Where("id = ?", authToken.UserID).Updates(&account).Error; err != nil {
+	accountID, err := uuid.Parse(authToken.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token")
+	}
+
+	err = h.saveProfile(c, accountID, audit.Success(c, audit.EventAccountUpdate).WithAccount(accountID), func(tx *gorm.DB) error {
+		return tx.Model(&models.Account{}).Where("id = ?", accountID).Updates(&account).Error
+	})
+	if err != nil {
 		log.Printf("Database error: %v", err)
 		return utils.SendError(c, fiber.StatusInternalServerError, "Failed to update account")
 	}
 
 	return utils.SendSuccess(c, fiber.StatusOK, "Account updated successfully", nil)
 }
+
+// saveProfile applies change and publishes account.updated in the same
+// transaction, so subscribers such as webhooks hear of every committed
+// profile change. event is recorded once it commits.
+func (h *AccountHandler) saveProfile(c *fiber.Ctx, accountID uuid.UUID, event audit.Event, change func(tx *gorm.DB) error) error {
+	return h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx); err != nil {
+			return err
+		}
+		return events.Publish(c.Context(), tx, events.Event{
+			Type:    events.AccountUpdated,
+			Payload: events.AccountUpdatedPayload{AccountID: accountID},
+			Audit:   &event,
+		})
+	})
+}
@@ -1,10 +1,12 @@
 package account
 
 import (
+	"aspire-auth/internal/audit"
 	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
-	"fmt"
+	"errors"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
@@ -20,39 +22,59 @@ func (h *AccountHandler) VerifyAccount(c *fiber.Ctx) error {
 		})
 	}
 
-	log.Printf("Verifying account with ID: %s and OTP: %s", req.AccountID, req.OTP)
+	uniform := h.Config.Security.UniformAuthResponses
 
 	// First check if account exists
 	var account models.Account
 	if err := h.DB.Where("id = ?", req.AccountID).First(&account).Error; err != nil {
-		log.Printf("Account not found: %v", err)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountVerify, "account_not_found").WithSubject(req.AccountID))
+		if uniform {
+			return invalidVerification(c)
+		}
 		return c.Status(404).JSON(response.APIResponse{
 			Success: false,
 			Message: "Account not found",
 		})
 	}
 
-	// Check OTP in Redis
-	redisKey := fmt.Sprintf("otp:%s", req.AccountID)
-	storedOTP, err := h.Redis.Get(c.Context(), redisKey).Result()
-	if err != nil {
-		log.Printf("Redis error: %v", err)
-		return c.Status(400).JSON(response.APIResponse{
+	// Check OTP
+	channel, err := h.OTP.VerifyChannel(c.Context(), otp.PurposeAccountVerification, account.ID.String(), req.OTP)
+	switch {
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountVerify, "too_many_attempts").WithAccount(account.ID))
+		if uniform {
+			return invalidVerification(c)
+		}
+		return c.Status(429).JSON(response.APIResponse{
 			Success: false,
-			Message: "Invalid or expired OTP",
+			Message: "Too many incorrect attempts. Please request a new OTP.",
 		})
-	}
-
-	if storedOTP != req.OTP {
-		log.Printf("OTP mismatch. Got: %s, Expected: %s", req.OTP, storedOTP)
+	case errors.Is(err, otp.ErrInvalidCode):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountVerify, "invalid_code").WithAccount(account.ID))
+		if uniform {
+			return invalidVerification(c)
+		}
 		return c.Status(400).JSON(response.APIResponse{
 			Success: false,
 			Message: "Invalid OTP",
 		})
+	case errors.Is(err, otp.ErrExpired):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountVerify, "expired_code").WithAccount(account.ID))
+		return invalidVerification(c)
+	case err != nil:
+		log.Printf("OTP verification error: %v", err)
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error verifying OTP",
+		})
 	}
 
-	// Update verification status
-	if err := h.DB.Model(&account).Update("is_verified", true).Error; err != nil {
+	// Update verification status; a code received by text also proves the phone
+	updates := map[string]interface{}{"is_verified": true}
+	if channel == otp.ChannelSMS {
+		updates["phone_verified"] = true
+	}
+	if err := h.DB.Model(&account).Updates(updates).Error; err != nil {
 		log.Printf("Failed to update verification status: %v", err)
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
@@ -60,11 +82,17 @@ func (h *AccountHandler) VerifyAccount(c *fiber.Ctx) error {
 		})
 	}
 
-	// Clean up Redis
-	h.Redis.Del(c.Context(), redisKey)
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventAccountVerify).WithAccount(account.ID))
 
 	return c.Status(200).JSON(response.APIResponse{
 		Success: true,
 		Message: "Account verified successfully",
 	})
 }
+
+func invalidVerification(c *fiber.Ctx) error {
+	return c.Status(400).JSON(response.APIResponse{
+		Success: false,
+		Message: "Invalid or expired OTP",
+	})
+}
@@ -0,0 +1,43 @@
+package account
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/magiclink"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerifyAccountByLink is the landing endpoint for the link in the
+// verification email. It is an alternative to entering the OTP.
+func (h *AccountHandler) VerifyAccountByLink(c *fiber.Ctx) error {
+	claims, err := h.MagicLinks.Consume(c.Context(), magiclink.PurposeVerify, c.Params("token"))
+	if errors.Is(err, magiclink.ErrInvalidToken) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountVerify, "invalid_link"))
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired verification link")
+	} else if err != nil {
+		log.Printf("Magic link error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error verifying account")
+	}
+
+	result := h.DB.Model(&models.Account{}).Where("id = ?", claims.AccountID).Update("is_verified", true)
+	if result.Error != nil {
+		return utils.HandleDBError(c, result.Error, "Failed to verify account")
+	}
+	if result.RowsAffected == 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired verification link")
+	}
+
+	// The emailed code is no longer needed
+	if err := h.OTP.Invalidate(c.Context(), otp.PurposeAccountVerification, claims.AccountID.String()); err != nil {
+		log.Printf("Error invalidating verification code: %v", err)
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventAccountVerify).WithAccount(claims.AccountID))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Account verified successfully", nil)
+}
@@ -0,0 +1,262 @@
+package admin
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SearchAccounts lists accounts, newest first. ?q= matches the email,
+// username or name; ?disabled=true and ?role= narrow the results.
+func (h *AdminHandler) SearchAccounts(c *fiber.Ctx) error {
+	page, limit := pagination(c)
+
+	query := h.DB.Model(&models.Account{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := likePattern(q)
+		query = query.Where("email ILIKE ? OR username ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?", pattern, pattern, pattern, pattern)
+	}
+	if c.QueryBool("disabled") {
+		query = query.Where("disabled_at IS NOT NULL")
+	}
+	if role := models.RoleType(strings.ToUpper(c.Query("role"))); role != "" {
+		if role != models.RoleUser && role != models.RoleAdmin {
+			return utils.SendError(c, fiber.StatusBadRequest, "Role must be USER or ADMIN")
+		}
+		query = query.Where("role_type = ?", role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("Error counting accounts: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching accounts")
+	}
+
+	accounts := []models.Account{}
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&accounts).Error; err != nil {
+		log.Printf("Error fetching accounts: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching accounts")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.AdminAccountListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Accounts fetched successfully",
+		},
+		Accounts: accounts,
+		Total:    total,
+		Page:     page,
+		Limit:    limit,
+	})
+}
+
+// GetAccount shows an account with its memberships, owned services and the
+// number of sessions it has open.
+func (h *AdminHandler) GetAccount(c *fiber.Ctx) error {
+	account, missing := h.findAccount(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+
+	memberships := []response.AdminMembership{}
+	err := h.DB.Table("services_users").
+		Select("services_users.service_id, services.service_name, services_users.is_verified, services_users.created_at AS joined_at").
+		Joins("JOIN services ON services.id = services_users.service_id").
+		Where("services_users.user_id = ?", account.ID).
+		Order("services_users.created_at").
+		Scan(&memberships).Error
+	if err != nil {
+		log.Printf("Error fetching memberships: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching account")
+	}
+
+	owned := []response.AdminServiceSummary{}
+	if err := h.serviceSummaries().Where("services.owner_id = ?", account.ID).Order("services.created_at").Scan(&owned).Error; err != nil {
+		log.Printf("Error fetching owned services: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching account")
+	}
+
+	var accountSessions, serviceSessions int64
+	now := time.Now()
+	if err := h.DB.Model(&models.AccountRefreshToken{}).Where("user_id = ? AND expires_at > ?", account.ID, now).Count(&accountSessions).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error fetching account")
+	}
+	if err := h.DB.Model(&models.ServiceRefreshToken{}).Where("user_id = ? AND expires_at > ?", account.ID, now).Count(&serviceSessions).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error fetching account")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.AdminAccountResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Account fetched successfully",
+		},
+		Account:         *account,
+		Memberships:     memberships,
+		OwnedServices:   owned,
+		AccountSessions: accountSessions,
+		ServiceSessions: serviceSessions,
+	})
+}
+
+// VerifyAccount marks an account verified without the emailed code, for
+// owners who cannot receive it.
+func (h *AdminHandler) VerifyAccount(c *fiber.Ctx) error {
+	account, missing := h.findAccount(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+	if account.IsVerified {
+		return utils.SendError(c, fiber.StatusConflict, "Account is already verified")
+	}
+
+	if err := h.DB.Model(account).Update("is_verified", true).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error verifying account")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminAccountVerify, nil).WithAccount(account.ID))
+	return utils.SendSuccess(c, fiber.StatusOK, "Account verified", nil)
+}
+
+// DisableAccount stops an account from signing in and ends its sessions.
+// Access tokens already issued stop working on admin routes at once and
+// elsewhere when they expire.
+func (h *AdminHandler) DisableAccount(c *fiber.Ctx) error {
+	var req request.AdminDisableRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+		}
+	}
+
+	account, missing := h.findAccount(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+	if account.ID == adminID(c) {
+		return utils.SendError(c, fiber.StatusBadRequest, "You cannot disable your own account")
+	}
+	if account.DisabledAt != nil {
+		return utils.SendError(c, fiber.StatusConflict, "Account is already disabled")
+	}
+
+	var revoked int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Update("disabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		revoked, err = revokeSessions(tx, account.ID)
+		return err
+	})
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error disabling account")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminAccountDisable, map[string]interface{}{
+		"reason":           req.Reason,
+		"sessions_revoked": revoked,
+	}).WithAccount(account.ID))
+	return utils.SendSuccess(c, fiber.StatusOK, "Account disabled", nil)
+}
+
+// EnableAccount lets a disabled account sign in again.
+func (h *AdminHandler) EnableAccount(c *fiber.Ctx) error {
+	account, missing := h.findAccount(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+	if account.DisabledAt == nil {
+		return utils.SendError(c, fiber.StatusConflict, "Account is not disabled")
+	}
+
+	if err := h.DB.Model(account).Update("disabled_at", nil).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error enabling account")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminAccountEnable, nil).WithAccount(account.ID))
+	return utils.SendSuccess(c, fiber.StatusOK, "Account enabled", nil)
+}
+
+// ResetMFA turns off the second sign-in step for an owner who has lost
+// access to it. They can turn it back on from their account settings.
+func (h *AdminHandler) ResetMFA(c *fiber.Ctx) error {
+	account, missing := h.findAccount(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+	if !account.MFAEnabled {
+		return utils.SendError(c, fiber.StatusConflict, "Account does not have MFA enabled")
+	}
+
+	if err := h.DB.Model(account).Update("mfa_enabled", false).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error resetting MFA")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminMFAReset, nil).WithAccount(account.ID))
+	return utils.SendSuccess(c, fiber.StatusOK, "MFA reset", nil)
+}
+
+// RevokeSessions signs an account out of the platform and every service.
+func (h *AdminHandler) RevokeSessions(c *fiber.Ctx) error {
+	account, missing := h.findAccount(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+
+	var revoked int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revoked, err = revokeSessions(tx, account.ID)
+		return err
+	})
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error revoking sessions")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminSessionsRevoke, map[string]interface{}{
+		"sessions_revoked": revoked,
+	}).WithAccount(account.ID))
+	return utils.SendSuccess(c, fiber.StatusOK, "Sessions revoked", fiber.Map{"revoked": revoked})
+}
+
+// findAccount loads the account named by the :id parameter, or returns the
+// reason it could not.
+func (h *AdminHandler) findAccount(c *fiber.Ctx) (*models.Account, *fiber.Error) {
+	accountID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid account ID")
+	}
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", accountID).First(&account).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Account not found")
+	} else if err != nil {
+		log.Printf("Error fetching account: %v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Error fetching account")
+	}
+	return &account, nil
+}
+
+// revokeSessions deletes every refresh token of accountID and returns how
+// many there were.
+func revokeSessions(tx *gorm.DB, accountID uuid.UUID) (int64, error) {
+	accountTokens := tx.Where("user_id = ?", accountID).Delete(&models.AccountRefreshToken{})
+	if accountTokens.Error != nil {
+		return 0, accountTokens.Error
+	}
+	serviceTokens := tx.Where("user_id = ?", accountID).Delete(&models.ServiceRefreshToken{})
+	if serviceTokens.Error != nil {
+		return 0, serviceTokens.Error
+	}
+	return accountTokens.RowsAffected + serviceTokens.RowsAffected, nil
+}
@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxExportEvents caps one audit export; narrow it with since and until to
+// fetch the rest.
+const maxExportEvents = 100000
+
+var auditCSVHeader = []string{
+	"id", "created_at", "event_type", "outcome", "account_id", "service_id",
+	"subject", "reason", "ip_address", "user_agent", "metadata",
+}
+
+// SearchAuditEvents returns events from the whole platform, newest first,
+// narrowed by any of the filters audit.ParseQuery understands.
+func (h *AdminHandler) SearchAuditEvents(c *fiber.Ctx) error {
+	query, err := audit.ParseQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	events, total, err := audit.Search(c.Context(), h.DB, query)
+	if err != nil {
+		log.Printf("Error fetching audit events: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching audit events")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.AuditEventListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Audit events fetched successfully",
+		},
+		Events: events,
+		Total:  total,
+		Page:   query.Page,
+		Limit:  query.Limit,
+	})
+}
+
+// ExportAuditEvents streams every event matching the filters, oldest first,
+// as CSV or, with ?format=ndjson, one JSON object per line. Exports stop at
+// maxExportEvents and say so in the X-Export-Truncated header.
+func (h *AdminHandler) ExportAuditEvents(c *fiber.Ctx) error {
+	format := c.Query("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		return utils.SendError(c, fiber.StatusBadRequest, "format must be csv or ndjson")
+	}
+
+	query, err := audit.ParseQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	filter := query.Filter
+
+	total, err := audit.Count(c.Context(), h.DB, filter)
+	if err != nil {
+		log.Printf("Error counting audit events: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error exporting audit events")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminAuditExport, map[string]interface{}{
+		"format": format,
+		"events": min(total, maxExportEvents),
+		"filter": c.Context().QueryArgs().String(),
+	}))
+
+	filename := fmt.Sprintf("audit-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Attachment(filename)
+	if format == "csv" {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	} else {
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	}
+	c.Set("X-Export-Truncated", strconv.FormatBool(total > maxExportEvents))
+
+	// The body is written after the handler returns, so it cannot use the request context
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		var write func(*models.AuditEvent) error
+		if format == "csv" {
+			write = csvWriter(w)
+		} else {
+			encoder := json.NewEncoder(w)
+			write = func(event *models.AuditEvent) error { return encoder.Encode(event) }
+		}
+
+		err := audit.Export(context.Background(), h.DB, filter, maxExportEvents, func(event *models.AuditEvent) error {
+			if err := write(event); err != nil {
+				return err
+			}
+			// Flushing hands rows to the client as they are produced
+			return w.Flush()
+		})
+		if err != nil {
+			log.Printf("Error exporting audit events: %v", err)
+		}
+	})
+	return nil
+}
+
+// csvWriter writes the header row, then returns a function writing one row
+// per event.
+func csvWriter(w *bufio.Writer) func(*models.AuditEvent) error {
+	out := csv.NewWriter(w)
+	out.Write(auditCSVHeader)
+
+	return func(event *models.AuditEvent) error {
+		var accountID, serviceID, metadata string
+		if event.AccountID != nil {
+			accountID = event.AccountID.String()
+		}
+		if event.ServiceID != nil {
+			serviceID = event.ServiceID.String()
+		}
+		if event.Metadata != nil {
+			metadata = *event.Metadata
+		}
+		out.Write([]string{
+			event.ID.String(),
+			event.CreatedAt.UTC().Format(time.RFC3339),
+			event.EventType,
+			string(event.Outcome),
+			accountID,
+			serviceID,
+			spreadsheetSafe(event.Subject),
+			spreadsheetSafe(event.Reason),
+			spreadsheetSafe(event.IPAddress),
+			spreadsheetSafe(event.UserAgent),
+			spreadsheetSafe(metadata),
+		})
+		out.Flush()
+		return out.Error()
+	}
+}
+
+// spreadsheetSafe stops a cell that came from a request, such as a submitted
+// email or a user agent, from being run as a formula when the export is
+// opened in a spreadsheet, by prefixing it with a quote.
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
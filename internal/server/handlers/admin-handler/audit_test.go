@@ -0,0 +1,25 @@
+package admin
+
+import "testing"
+
+func TestSpreadsheetSafe(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"user@example.com", "user@example.com"},
+		{"Mozilla/5.0", "Mozilla/5.0"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1+1", "'+1+1"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := spreadsheetSafe(tt.value); got != tt.want {
+			t.Errorf("spreadsheetSafe(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
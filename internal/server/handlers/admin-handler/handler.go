@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/container"
+	"aspire-auth/internal/models"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// AdminHandler serves the platform administration API. Every route sits
+// behind RequireRole(models.RoleAdmin) and every change is audited with the
+// acting admin in the event metadata.
+type AdminHandler struct {
+	*container.Container
+}
+
+func NewAdminHandler(base *container.Container) *AdminHandler {
+	return &AdminHandler{Container: base}
+}
+
+// adminID returns the account of the admin making the request.
+func adminID(c *fiber.Ctx) uuid.UUID {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+	return uuid.MustParse(authToken.UserID)
+}
+
+// adminEvent is a successful admin action, tagged with who performed it.
+func adminEvent(c *fiber.Ctx, eventType string, metadata map[string]interface{}) audit.Event {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["admin_id"] = adminID(c).String()
+	return audit.Success(c, eventType).WithMetadata(metadata)
+}
+
+// pagination reads ?page= and ?limit=, clamping them to sane values.
+func pagination(c *fiber.Ctx) (page, limit int) {
+	page = max(c.QueryInt("page", 1), 1)
+	limit = c.QueryInt("limit", defaultPageSize)
+	if limit < 1 || limit > maxPageSize {
+		limit = defaultPageSize
+	}
+	return page, limit
+}
+
+// likePattern turns a search term into an ILIKE pattern matching it anywhere,
+// with the term's own wildcards taken literally.
+func likePattern(term string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListServices lists every service on the platform, newest first. ?q=
+// matches the service name and ?disabled=true narrows to disabled ones.
+func (h *AdminHandler) ListServices(c *fiber.Ctx) error {
+	page, limit := pagination(c)
+
+	filter := func(query *gorm.DB) *gorm.DB {
+		if q := strings.TrimSpace(c.Query("q")); q != "" {
+			query = query.Where("services.service_name ILIKE ?", likePattern(q))
+		}
+		if c.QueryBool("disabled") {
+			query = query.Where("services.disabled_at IS NOT NULL")
+		}
+		return query
+	}
+
+	var total int64
+	if err := filter(h.DB.Model(&models.Service{})).Count(&total).Error; err != nil {
+		log.Printf("Error counting services: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching services")
+	}
+
+	services := []response.AdminServiceSummary{}
+	err := filter(h.serviceSummaries()).
+		Order("services.created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Scan(&services).Error
+	if err != nil {
+		log.Printf("Error fetching services: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching services")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.AdminServiceListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Services fetched successfully",
+		},
+		Services: services,
+		Total:    total,
+		Page:     page,
+		Limit:    limit,
+	})
+}
+
+// DisableService stops a service's users from signing in to it and makes
+// its tokens fail verification, then ends its sessions.
+func (h *AdminHandler) DisableService(c *fiber.Ctx) error {
+	var req request.AdminDisableRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+		}
+	}
+
+	service, missing := h.findService(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+	if service.DisabledAt != nil {
+		return utils.SendError(c, fiber.StatusConflict, "Service is already disabled")
+	}
+
+	var revoked int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(service).Update("disabled_at", time.Now()).Error; err != nil {
+			return err
+		}
+		result := tx.Where("service_id = ?", service.ID).Delete(&models.ServiceRefreshToken{})
+		revoked = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error disabling service")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminServiceDisable, map[string]interface{}{
+		"reason":           req.Reason,
+		"sessions_revoked": revoked,
+	}).WithAccount(service.OwnerID).WithService(service.ID))
+	return utils.SendSuccess(c, fiber.StatusOK, "Service disabled", nil)
+}
+
+// EnableService lifts a service's suspension.
+func (h *AdminHandler) EnableService(c *fiber.Ctx) error {
+	service, missing := h.findService(c)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+	if service.DisabledAt == nil {
+		return utils.SendError(c, fiber.StatusConflict, "Service is not disabled")
+	}
+
+	if err := h.DB.Model(service).Update("disabled_at", nil).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error enabling service")
+	}
+
+	h.Audit.Record(c.Context(), adminEvent(c, audit.EventAdminServiceEnable, nil).WithAccount(service.OwnerID).WithService(service.ID))
+	return utils.SendSuccess(c, fiber.StatusOK, "Service enabled", nil)
+}
+
+// serviceSummaries selects services with their owner's email and user count,
+// ready to scan into response.AdminServiceSummary.
+func (h *AdminHandler) serviceSummaries() *gorm.DB {
+	return h.DB.Table("services").
+		Select("services.id, services.service_name, services.owner_id, accounts.email AS owner_email, services.organization_id, " +
+			"(SELECT COUNT(*) FROM services_users WHERE services_users.service_id = services.id) AS users_count, " +
+			"services.disabled_at, services.created_at").
+		Joins("LEFT JOIN accounts ON accounts.id = services.owner_id")
+}
+
+// findService loads the service named by the :id parameter, or returns the
+// reason it could not.
+func (h *AdminHandler) findService(c *fiber.Ctx) (*models.Service, *fiber.Error) {
+	serviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid service ID")
+	}
+
+	var service models.Service
+	if err := h.DB.Where("id = ?", serviceID).First(&service).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Service not found")
+	} else if err != nil {
+		log.Printf("Error fetching service: %v", err)
+		return nil, fiber.NewError(fiber.StatusInternalServerError, "Error fetching service")
+	}
+	return &service, nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ForgotPassword emails a reset code. With uniform responses enabled the
+// answer is the same whether or not the email belongs to an account, and
+// so is the work behind it: an unknown address is sent a notice that no
+// account uses it, queued like a code would be.
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req request.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	email := utils.NormalizeEmail(req.Email)
+	if email == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Email is required")
+	}
+
+	if _, err := otp.ParseChannel(req.Channel, otp.ChannelEmail); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Channel must be email or sms")
+	}
+
+	uniform := h.Config.Security.UniformAuthResponses
+
+	var account models.Account
+	if err := h.DB.Where("LOWER(email) = ?", email).First(&account).Error; err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventPasswordResetRequest, "account_not_found").WithSubject(email))
+		if !uniform {
+			return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+		}
+
+		// Apply the same throttle a real account would get
+		err := h.OTP.ThrottleSend(c.Context(), otp.PurposePasswordReset, email)
+		var limited *otp.RateLimitError
+		if errors.As(err, &limited) {
+			return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+		} else if err != nil {
+			log.Printf("OTP throttle error: %v", err)
+		}
+		if err := helpers.SendPasswordResetUnknownEmail(c.Context(), h.MailFor(c, nil, emailtemplate.Brand{}), email); err != nil {
+			log.Printf("Error queueing password reset notice: %v", err)
+		}
+		return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+	}
+
+	sender, err := h.CodeSender(h.MailFor(c, &account, emailtemplate.Brand{}), &account, req.Channel)
+	if err != nil {
+		if !uniform {
+			return utils.SendError(c, fiber.StatusBadRequest, "No verified phone number to send the code to")
+		}
+		// Falling back to email keeps the answer identical for every account
+		sender, _ = h.CodeSender(h.MailFor(c, &account, emailtemplate.Brand{}), &account, string(otp.ChannelEmail))
+	}
+
+	code, err := h.OTP.Generate(c.Context(), otp.PurposePasswordReset, email)
+	var limited *otp.RateLimitError
+	if errors.As(err, &limited) {
+		return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+	} else if err != nil {
+		log.Printf("OTP error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating reset code")
+	}
+
+	if err := sender.Send(c.Context(), h.CodeDelivery(&account, otp.PurposePasswordReset, code)); err != nil {
+		log.Printf("Error sending reset code by %s: %v", sender.Channel(), err)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventPasswordResetRequest, "send_failed").WithAccount(account.ID))
+		// A distinct error here would reveal that the account exists
+		if !uniform {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error sending reset code")
+		}
+		return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventPasswordResetRequest).WithAccount(account.ID))
+
+	if uniform {
+		return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Password reset code sent", nil)
+}
@@ -1,14 +1,14 @@
 package auth
 
 import (
+	"aspire-auth/internal/audit"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
 	"log"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -20,98 +20,50 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	if retryAfter := h.Lockout.Check(c.Context(), req.Email, c.IP()); retryAfter > 0 {
+		return utils.SendTooManyRequests(c, retryAfter, "Too many failed attempts. Please try again later.")
+	}
+
 	var account models.Account
 	if err := h.DB.Where("email = ?", req.Email).First(&account).Error; err != nil {
-		return c.Status(404).JSON(response.APIResponse{
-			Success: false,
-			Message: "Account not found",
-		})
+		// Spend the same time as a real check so unknown emails are not revealed by timing
+		h.Passwords.DummyVerify(req.Password)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventLogin, "account_not_found").WithSubject(req.Email))
+		return h.loginFailure(c, req.Email, false, fiber.StatusNotFound, "Account not found")
 	}
 
-	if !account.IsVerified {
-		return c.Status(401).JSON(response.APIResponse{
-			Success: false,
-			Message: "Account not verified",
-		})
+	needsRehash, err := h.Passwords.Verify(req.Password, account.HashedPassword)
+	if err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventLogin, "invalid_password").WithAccount(account.ID))
+		return h.loginFailure(c, req.Email, true, fiber.StatusUnauthorized, "Invalid password")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(account.HashedPassword), []byte(req.Password)); err != nil {
+	// Only revealed once the password is known to be correct
+	if !account.IsVerified {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventLogin, "account_not_verified").WithAccount(account.ID))
 		return c.Status(401).JSON(response.APIResponse{
 			Success: false,
-			Message: "Invalid password",
-		})
-	}
-
-	tokenModel := models.AccountRefreshToken{
-		UserID:    account.ID,
-		RoleType:  account.RoleType,
-		ExpiresAt: time.Now().Add(h.Config.JWT.Account.RefreshExpiry),
-	}
-
-	accessToken, err := h.Container.JWT.GenerateAccountAccessToken(&tokenModel)
-	if err != nil {
-		return c.Status(500).JSON(response.APIResponse{
-			Success: false,
-			Message: "Error generating access token",
+			Message: "Account not verified",
 		})
 	}
 
-	refreshToken, err := h.Container.JWT.GenerateAccountRefreshToken(&tokenModel)
-	if err != nil {
-		return c.Status(500).JSON(response.APIResponse{
-			Success: false,
-			Message: "Error generating refresh token",
-		})
+	h.Lockout.RecordSuccess(c.Context(), req.Email)
+	if needsRehash {
+		if err := h.Passwords.Upgrade(h.DB, &account, req.Password); err != nil {
+			log.Printf("Error upgrading password hash: %v", err)
+		}
 	}
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventLogin).WithAccount(account.ID))
 
-	tokenModel.RefreshToken = refreshToken
-	if err := h.DB.Create(&tokenModel).Error; err != nil {
-		log.Printf("Error saving refresh token: %v", err)
-		return c.Status(500).JSON(response.APIResponse{
-			Success: false,
-			Message: "Error saving refresh token",
-		})
-	}
+	return h.completeSignIn(c, &account, "Login successful")
+}
 
-	// Update CORS headers
-	origin := c.Get("Origin")
-	if origin == "" {
-		origin = "*" // Default to wildcard if no origin is provided
+// loginFailure counts a failed attempt and answers with either the detailed
+// or the uniform credential error
+func (h *AuthHandler) loginFailure(c *fiber.Ctx, email string, accountExists bool, status int, message string) error {
+	failure := h.Lockout.RecordFailure(c.Context(), email, c.IP(), accountExists)
+	if failure.Locked {
+		return utils.SendTooManyRequests(c, failure.RetryAfter, "Too many failed attempts. Please try again later.")
 	}
-	c.Set("Access-Control-Allow-Origin", origin)
-	c.Set("Access-Control-Allow-Credentials", "true")
-
-	// Set cookies with correct settings for persistence
-	c.Cookie(&fiber.Cookie{
-		Name:     "REFRESH_TOKEN",
-		Value:    refreshToken,
-		Path:     "/",
-		Expires:  time.Now().Add(h.Config.JWT.Account.RefreshExpiry),
-		MaxAge:   int(h.Config.JWT.Account.RefreshExpiry.Seconds()),
-		Domain:   "", // Let browser set the domain automatically
-		Secure:   true,
-		HTTPOnly: true,
-		SameSite: "None",
-	})
-
-	c.Cookie(&fiber.Cookie{
-		Name:     "ACCESS_TOKEN",
-		Value:    accessToken,
-		Path:     "/",
-		Expires:  time.Now().Add(h.Config.JWT.Account.AccessExpiry),
-		MaxAge:   int(h.Config.JWT.Account.AccessExpiry.Seconds()),
-		Domain:   "", // Let browser set the domain automatically
-		Secure:   false,
-		HTTPOnly: false, // Set to false to allow JavaScript access
-		SameSite: "None",
-	})
-
-	return c.Status(200).JSON(response.LoginResponse{
-		APIResponse: response.APIResponse{
-			Success: true,
-			Message: "Login successful",
-		},
-
-		ExpiresAt: tokenModel.ExpiresAt.Unix(),
-	})
+	return utils.SendCredentialError(c, h.Config.Security.UniformAuthResponses, status, message)
 }
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/magiclink"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestMagicLink emails a single-use sign-in link instead of checking a
+// password. The response does not reveal whether the email is registered.
+func (h *AuthHandler) RequestMagicLink(c *fiber.Ctx) error {
+	if !h.Config.MagicLink.SignInEnabled {
+		return utils.SendError(c, fiber.StatusNotFound, "Sign-in links are disabled")
+	}
+
+	var req request.MagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.Email == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Email is required")
+	}
+
+	uniform := h.Config.Security.UniformAuthResponses
+
+	var account models.Account
+	if err := h.DB.Where("email = ?", req.Email).First(&account).Error; err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMagicLinkRequest, "account_not_found").WithSubject(req.Email))
+		if uniform {
+			return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+		}
+		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+	}
+
+	if !account.IsVerified {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMagicLinkRequest, "account_not_verified").WithAccount(account.ID))
+		if uniform {
+			return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+		}
+		return utils.SendError(c, fiber.StatusUnauthorized, "Account not verified")
+	}
+
+	token, err := h.MagicLinks.Issue(c.Context(), magiclink.PurposeSignIn, account.ID, nil)
+	if err != nil {
+		log.Printf("Error issuing sign-in link: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating sign-in link")
+	}
+
+	link := fmt.Sprintf("%s/signin/magic/%s", strings.TrimRight(h.Config.Server.PublicURL, "/"), token)
+	if err := helpers.SendMagicLinkEmail(c.Context(), h.MailFor(c, &account, emailtemplate.Brand{}), account.Email, link, h.Config); err != nil {
+		log.Printf("Email error: %v", err)
+		if !uniform {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error sending sign-in link")
+		}
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventMagicLinkRequest).WithAccount(account.ID))
+
+	if uniform {
+		return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Sign-in link sent", nil)
+}
+
+// MagicLinkLogin consumes a sign-in link and starts a session.
+func (h *AuthHandler) MagicLinkLogin(c *fiber.Ctx) error {
+	if !h.Config.MagicLink.SignInEnabled {
+		return utils.SendError(c, fiber.StatusNotFound, "Sign-in links are disabled")
+	}
+
+	claims, err := h.MagicLinks.Consume(c.Context(), magiclink.PurposeSignIn, c.Params("token"))
+	// Service links carry a service ID and only work on the service endpoint
+	if errors.Is(err, magiclink.ErrInvalidToken) || (err == nil && claims.ServiceID != nil) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMagicLinkLogin, "invalid_link"))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in link")
+	} else if err != nil {
+		log.Printf("Magic link error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error signing in")
+	}
+
+	var account models.Account
+	if err := h.DB.Where("id = ?", claims.AccountID).First(&account).Error; err != nil || !account.IsVerified {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMagicLinkLogin, "account_unavailable").WithAccount(claims.AccountID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventMagicLinkLogin).WithAccount(account.ID))
+
+	return h.completeSignIn(c, &account, "Login successful")
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/mfa"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// completeSignIn issues a session once the first factor has been checked,
+// or starts an MFA challenge when the account requires a second step.
+func (h *AuthHandler) completeSignIn(c *fiber.Ctx, account *models.Account, message string) error {
+	// Checked before the second factor so no code goes out
+	if account.DisabledAt != nil {
+		return utils.SendError(c, fiber.StatusForbidden, "This account has been disabled")
+	}
+	if !account.MFAEnabled {
+		return h.issueAccountSession(c, account, message)
+	}
+	return h.startMFAChallenge(c, account)
+}
+
+// startMFAChallenge sends account a sign-in code and writes the challenge
+// token the code must be posted back with to VerifyMFA.
+func (h *AuthHandler) startMFAChallenge(c *fiber.Ctx, account *models.Account) error {
+	mail := h.MailFor(c, account, emailtemplate.Brand{})
+	sender, err := h.CodeSender(mail, account, "")
+	if err != nil {
+		// The phone was removed or is unverified; email always works
+		sender, _ = h.CodeSender(mail, account, string(otp.ChannelEmail))
+	}
+
+	token, err := h.MFA.Start(c.Context(), account, nil, sender, h.CodeDelivery(account, otp.PurposeMFA, ""))
+	var limited *otp.RateLimitError
+	if errors.As(err, &limited) {
+		return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+	} else if err != nil {
+		log.Printf("Error starting MFA challenge: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error sending sign-in code")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventMFAChallenge).WithAccount(account.ID).WithSubject(string(sender.Channel())))
+
+	return c.Status(fiber.StatusOK).JSON(response.MFAChallengeResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "A sign-in code has been sent",
+		},
+		MFARequired:    true,
+		ChallengeToken: token,
+		Channel:        string(sender.Channel()),
+		ExpiresAt:      time.Now().Add(h.Config.MFA.ChallengeTTL).Unix(),
+	})
+}
+
+// VerifyMFA finishes a sign-in started by completeSignIn with the code that
+// was sent to the account.
+func (h *AuthHandler) VerifyMFA(c *fiber.Ctx) error {
+	var req request.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.ChallengeToken == "" || req.OTP == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Challenge token and code are required")
+	}
+
+	account, _, err := h.MFA.Finish(c.Context(), req.ChallengeToken, req.OTP, false)
+	switch {
+	case errors.Is(err, mfa.ErrInvalidChallenge):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMFAVerify, "invalid_challenge"))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in challenge. Please sign in again.")
+	case errors.Is(err, mfa.ErrCodeExhausted):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMFAVerify, "code_exhausted").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "The sign-in code has expired or was entered incorrectly too many times. Please sign in again.")
+	case errors.Is(err, otp.ErrInvalidCode):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMFAVerify, "invalid_code").WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid sign-in code")
+	case err != nil:
+		log.Printf("Error verifying MFA challenge: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error verifying sign-in code")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventMFAVerify).WithAccount(account.ID))
+
+	return h.issueAccountSession(c, account, "Login successful")
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"aspire-auth/internal/audit"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
@@ -29,6 +30,7 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	authToken := &models.AccountAuthorizationToken{}
 
 	if err := h.Container.JWT.ParseAccountRefreshToken(tokenString, authToken); err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventTokenRefresh, "invalid_token"))
 		return c.Status(401).JSON(response.APIResponse{
 			Success: false,
 			Message: "Invalid token",
@@ -38,6 +40,7 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	// Check if refresh token exists in database
 	var refreshTokenModel models.AccountRefreshToken
 	if err := h.DB.Where("refresh_token = ? AND expires_at > ?", tokenString, time.Now()).First(&refreshTokenModel).Error; err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventTokenRefresh, "revoked_or_expired").WithSubject(authToken.UserID))
 		return c.Status(401).JSON(response.APIResponse{
 			Success: false,
 			Message: "Invalid or expired refresh token",
@@ -88,6 +91,8 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventTokenRefresh).WithAccount(refreshTokenModel.UserID))
+
 	c.Cookie(&fiber.Cookie{
 		Name:     "REFRESH_TOKEN",
 		Value:    newRefreshToken,
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/password"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// ResetPassword sets a new password using a code from ForgotPassword and
+// signs the account out everywhere.
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req request.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.Email == "" || req.OTP == "" || req.NewPassword == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Email, code and new password are required")
+	}
+
+	email := utils.NormalizeEmail(req.Email)
+
+	// Rules that do not depend on the account are checked before the code,
+	// and the answer cannot tell whether the email belongs to anyone
+	if violations := h.PasswordPolicy.Check(req.NewPassword, password.UserInfo{Email: email}); len(violations) > 0 {
+		return utils.SendPasswordViolations(c, violations)
+	}
+
+	// Unknown emails have no stored code, so they fail here like a wrong
+	// code. The code is kept until the password is accepted, so the user
+	// can retry with it
+	var account models.Account
+	found := h.DB.Where("LOWER(email) = ?", email).First(&account).Error == nil
+	err := h.OTP.Check(c.Context(), otp.PurposePasswordReset, email, req.OTP)
+	if err == nil && !found {
+		err = otp.ErrExpired
+	}
+	if err != nil {
+		reason := "invalid_code"
+		switch {
+		case errors.Is(err, otp.ErrTooManyAttempts):
+			reason = "too_many_attempts"
+		case errors.Is(err, otp.ErrExpired):
+			reason = "expired_code"
+		case !errors.Is(err, otp.ErrInvalidCode):
+			log.Printf("OTP verification error: %v", err)
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error verifying reset code")
+		}
+
+		event := audit.Failure(c, audit.EventPasswordReset, reason).WithSubject(email)
+		if found {
+			event = event.WithAccount(account.ID)
+		}
+		h.Audit.Record(c.Context(), event)
+
+		if !h.Config.Security.UniformAuthResponses && errors.Is(err, otp.ErrTooManyAttempts) {
+			return utils.SendError(c, fiber.StatusTooManyRequests, "Too many incorrect attempts. Please request a new code.")
+		}
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired reset code")
+	}
+
+	// Holding the code proves ownership of the mailbox, so the rules that
+	// use the account's details can now be reported
+	user := password.UserInfo{
+		Username:  account.Username,
+		Email:     account.Email,
+		FirstName: account.FirstName,
+		LastName:  account.LastName,
+	}
+	if violations := h.PasswordPolicy.Check(req.NewPassword, user); len(violations) > 0 {
+		return utils.SendPasswordViolations(c, violations)
+	}
+
+	// Only one request may act on the code, however many carry it
+	if err := h.OTP.Consume(c.Context(), otp.PurposePasswordReset, email, req.OTP); errors.Is(err, otp.ErrExpired) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventPasswordReset, "expired_code").WithSubject(email).WithAccount(account.ID))
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired reset code")
+	} else if err != nil {
+		log.Printf("Error consuming reset code: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error resetting password")
+	}
+
+	hashed, err := h.Passwords.Hash(req.NewPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error resetting password")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&account).Update("hashed_password", hashed).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", account.ID).Delete(&models.AccountRefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", account.ID).Delete(&models.ServiceRefreshToken{}).Error
+	})
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error resetting password")
+	}
+
+	// Proving ownership of the mailbox also clears any sign-in lockout
+	h.Lockout.RecordSuccess(c.Context(), account.Email)
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventPasswordReset).WithAccount(account.ID))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Password has been reset. Please sign in with your new password.", nil)
+}
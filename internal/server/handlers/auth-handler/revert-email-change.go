@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailchange"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var (
+	errRevertEmailTaken = errors.New("previous email is in use by another account")
+	errRevertStale      = errors.New("email changed outside the revert chain")
+)
+
+// ReviewEmailRevert is where the link sent to the previous address leads.
+// It describes the change without undoing it, so link scanners and
+// prefetchers that open the link cannot revert it; that takes a POST.
+func (h *AuthHandler) ReviewEmailRevert(c *fiber.Ctx) error {
+	change, err := h.EmailChange.LookupRevertToken(c.Context(), c.Params("token"))
+	if errors.Is(err, emailchange.ErrInvalidRevertToken) {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link")
+	} else if err != nil {
+		log.Printf("Error loading email revert token: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching email change")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.EmailRevertResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Confirm to restore your previous email address and sign out every session",
+		},
+		OldEmail: change.OldEmail,
+		NewEmail: change.NewEmail,
+	})
+}
+
+// RevertEmailChange consumes the link sent to the previous address after an
+// email change. It restores that address and signs out every session, since
+// an unexpected change usually means the account was compromised.
+//
+// Later changes are undone with it, and their links stop working, so
+// whoever controlled an address in between cannot take the account back.
+// The restore only applies while the account still has the address of the
+// latest change the link store knows of.
+func (h *AuthHandler) RevertEmailChange(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Revert token is required")
+	}
+
+	change, latest, err := h.EmailChange.ConsumeRevertToken(c.Context(), token)
+	if errors.Is(err, emailchange.ErrInvalidRevertToken) {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link")
+	} else if err != nil {
+		log.Printf("Error loading email revert token: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error restoring email address")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Account{}).
+			Where("LOWER(email) = LOWER(?) AND id <> ?", change.OldEmail, change.AccountID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errRevertEmailTaken
+		}
+
+		result := tx.Model(&models.Account{}).Where("id = ? AND email = ?", change.AccountID, latest.NewEmail).Update("email", change.OldEmail)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRevertStale
+		}
+		if err := tx.Where("user_id = ?", change.AccountID).Delete(&models.AccountRefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", change.AccountID).Delete(&models.ServiceRefreshToken{}).Error
+	})
+	if errors.Is(err, errRevertEmailTaken) || (err != nil && strings.Contains(err.Error(), "duplicate key")) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventEmailChangeRevert, "email_taken").WithAccount(change.AccountID))
+		return utils.SendError(c, fiber.StatusConflict, "Your previous email address is now used by another account. Please contact support.")
+	} else if errors.Is(err, errRevertStale) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventEmailChangeRevert, "stale_link").WithAccount(change.AccountID))
+		return utils.SendError(c, fiber.StatusConflict, "This link no longer applies to your account's email address")
+	} else if err != nil {
+		return utils.HandleDBError(c, err, "Error restoring email address")
+	}
+
+	// Drop any follow-up change started from the hijacked session
+	h.EmailChange.ClearPending(c.Context(), change.AccountID)
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventEmailChangeRevert).WithAccount(change.AccountID).WithSubject(change.OldEmail))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Your email address has been restored and all sessions signed out. Please reset your password.", nil)
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// issueAccountSession creates a refresh token for account, sets the session
+// cookies and writes the login response. Every sign-in method ends here.
+func (h *AuthHandler) issueAccountSession(c *fiber.Ctx, account *models.Account, message string) error {
+	if account.DisabledAt != nil {
+		return c.Status(403).JSON(response.APIResponse{
+			Success: false,
+			Message: "This account has been disabled",
+		})
+	}
+
+	tokenModel := models.AccountRefreshToken{
+		UserID:    account.ID,
+		RoleType:  account.RoleType,
+		ExpiresAt: time.Now().Add(h.Config.JWT.Account.RefreshExpiry),
+	}
+
+	accessToken, err := h.Container.JWT.GenerateAccountAccessToken(&tokenModel)
+	if err != nil {
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error generating access token",
+		})
+	}
+
+	refreshToken, err := h.Container.JWT.GenerateAccountRefreshToken(&tokenModel)
+	if err != nil {
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error generating refresh token",
+		})
+	}
+
+	tokenModel.RefreshToken = refreshToken
+	if err := h.DB.Create(&tokenModel).Error; err != nil {
+		log.Printf("Error saving refresh token: %v", err)
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error saving refresh token",
+		})
+	}
+
+	// Update CORS headers
+	origin := c.Get("Origin")
+	if origin == "" {
+		origin = "*" // Default to wildcard if no origin is provided
+	}
+	c.Set("Access-Control-Allow-Origin", origin)
+	c.Set("Access-Control-Allow-Credentials", "true")
+
+	// Set cookies with correct settings for persistence
+	c.Cookie(&fiber.Cookie{
+		Name:     "REFRESH_TOKEN",
+		Value:    refreshToken,
+		Path:     "/",
+		Expires:  time.Now().Add(h.Config.JWT.Account.RefreshExpiry),
+		MaxAge:   int(h.Config.JWT.Account.RefreshExpiry.Seconds()),
+		Domain:   "", // Let browser set the domain automatically
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: "None",
+	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "ACCESS_TOKEN",
+		Value:    accessToken,
+		Path:     "/",
+		Expires:  time.Now().Add(h.Config.JWT.Account.AccessExpiry),
+		MaxAge:   int(h.Config.JWT.Account.AccessExpiry.Seconds()),
+		Domain:   "", // Let browser set the domain automatically
+		Secure:   false,
+		HTTPOnly: false, // Set to false to allow JavaScript access
+		SameSite: "None",
+	})
+
+	return c.Status(200).JSON(response.LoginResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: message,
+		},
+
+		ExpiresAt: tokenModel.ExpiresAt.Unix(),
+	})
+}
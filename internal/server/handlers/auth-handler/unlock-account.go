@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/lockout"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UnlockAccount consumes the link emailed when an account gets locked after
+// repeated failed sign-in attempts.
+func (h *AuthHandler) UnlockAccount(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if token == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Unlock token is required")
+	}
+
+	email, err := h.Lockout.Unlock(c.Context(), token)
+	if err != nil {
+		if errors.Is(err, lockout.ErrInvalidUnlockToken) {
+			h.Audit.Record(c.Context(), audit.Failure(c, audit.EventAccountUnlock, "invalid_token"))
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired unlock link")
+		}
+		log.Printf("Error unlocking account: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error unlocking account")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventAccountUnlock).WithSubject(email))
+	return utils.SendSuccess(c, fiber.StatusOK, "Account unlocked. You can sign in again.", nil)
+}
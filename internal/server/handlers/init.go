@@ -3,21 +3,27 @@ package handlers
 import (
 	"aspire-auth/internal/container"
 	"aspire-auth/internal/server/handlers/account-handler"
+	"aspire-auth/internal/server/handlers/admin-handler"
 	"aspire-auth/internal/server/handlers/auth-handler"
+	"aspire-auth/internal/server/handlers/organization-handler"
 	"aspire-auth/internal/server/handlers/service-handler"
 )
 
 type Handlers struct {
-	Account *account.AccountHandler
-	Auth    *auth.AuthHandler
-	Service *service.ServiceHandler
+	Account      *account.AccountHandler
+	Admin        *admin.AdminHandler
+	Auth         *auth.AuthHandler
+	Organization *organization.OrganizationHandler
+	Service      *service.ServiceHandler
 }
 
 func InitHandlers(container *container.Container) *Handlers {
 
 	return &Handlers{
-		Account: account.NewAccountHandler(container),
-		Auth:    auth.NewAuthHandler(container),
-		Service: service.NewServiceHandler(container),
+		Account:      account.NewAccountHandler(container),
+		Admin:        admin.NewAdminHandler(container),
+		Auth:         auth.NewAuthHandler(container),
+		Organization: organization.NewOrganizationHandler(container),
+		Service:      service.NewServiceHandler(container),
 	}
 }
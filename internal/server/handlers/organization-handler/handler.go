@@ -0,0 +1,69 @@
+package organization
+
+import (
+	"aspire-auth/internal/container"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxOrganizationNameLength = 100
+
+// errLastOwner stops a change that would leave an organization without an
+// owner.
+var errLastOwner = errors.New("organization must keep at least one owner")
+
+// OrganizationHandler serves the organization API. Routes on a single
+// organization sit behind RequireOrganizationRole, which resolves the
+// organization and the caller's role in it.
+type OrganizationHandler struct {
+	*container.Container
+}
+
+func NewOrganizationHandler(base *container.Container) *OrganizationHandler {
+	return &OrganizationHandler{Container: base}
+}
+
+// accountID returns the account making the request.
+func accountID(c *fiber.Ctx) uuid.UUID {
+	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+	return uuid.MustParse(authToken.UserID)
+}
+
+// currentOrganization is the organization RequireOrganizationRole resolved,
+// with the caller's role in it.
+func currentOrganization(c *fiber.Ctx) (*models.Organization, models.OrganizationRole) {
+	return c.Locals("organization").(*models.Organization), c.Locals("organizationRole").(models.OrganizationRole)
+}
+
+// members returns the organization's members with their account details,
+// optionally only the one for accountID.
+func members(db *gorm.DB, organizationID uuid.UUID, accountID *uuid.UUID) ([]response.OrganizationMemberDetails, error) {
+	query := db.Model(&models.OrganizationMember{}).
+		Select("organization_members.*, accounts.username, accounts.email").
+		Joins("JOIN accounts ON accounts.id = organization_members.account_id").
+		Where("organization_members.organization_id = ?", organizationID)
+	if accountID != nil {
+		query = query.Where("organization_members.account_id = ?", *accountID)
+	}
+
+	var details []response.OrganizationMemberDetails
+	err := query.Order("organization_members.created_at").Scan(&details).Error
+	return details, err
+}
+
+// lockOwners locks the organization's owner memberships for the rest of tx,
+// so that two concurrent changes cannot each remove a different last owner,
+// and returns their accounts.
+func lockOwners(tx *gorm.DB, organizationID uuid.UUID) ([]uuid.UUID, error) {
+	var owners []uuid.UUID
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", organizationID, models.OrganizationOwner).
+		Pluck("account_id", &owners).Error
+	return owners, err
+}
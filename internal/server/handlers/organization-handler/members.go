@@ -0,0 +1,207 @@
+package organization
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AddMember adds an existing, verified account to the organization. Admins
+// may add anyone but owners; only owners may add another owner.
+func (h *OrganizationHandler) AddMember(c *fiber.Ctx) error {
+	var req request.AddOrganizationMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	organization, role := currentOrganization(c)
+	newRole := models.OrganizationRole(strings.ToUpper(strings.TrimSpace(req.Role)))
+	if rejected := grantable(role, newRole); rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+
+	var account models.Account
+	if err := h.DB.Where("email = ?", strings.TrimSpace(req.Email)).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "No account with that email")
+	}
+	if !account.IsVerified || account.DisabledAt != nil || account.DeletionScheduledAt != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Only verified, active accounts can be added to an organization")
+	}
+
+	var existing int64
+	if err := h.DB.Model(&models.OrganizationMember{}).Where("organization_id = ? AND account_id = ?", organization.ID, account.ID).Count(&existing).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error adding member")
+	}
+	if existing > 0 {
+		return utils.SendError(c, fiber.StatusConflict, "Account is already a member of this organization")
+	}
+
+	member := models.OrganizationMember{
+		OrganizationID: organization.ID,
+		AccountID:      account.ID,
+		Role:           newRole,
+	}
+	if err := h.DB.Create(&member).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error adding member")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventOrganizationMemberAdd).WithAccount(accountID(c)).WithSubject(organization.ID.String()).WithMetadata(map[string]interface{}{
+		"member_id": account.ID.String(),
+		"role":      newRole,
+	}))
+
+	return c.Status(fiber.StatusCreated).JSON(response.OrganizationMemberResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Member added successfully",
+		},
+		Member: response.OrganizationMemberDetails{OrganizationMember: member, Username: account.Username, Email: account.Email},
+	})
+}
+
+// UpdateMember changes a member's role. Admins may not change owners or
+// make anyone an owner, and the last owner cannot be demoted.
+func (h *OrganizationHandler) UpdateMember(c *fiber.Ctx) error {
+	var req request.UpdateOrganizationMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	organization, role := currentOrganization(c)
+	newRole := models.OrganizationRole(strings.ToUpper(strings.TrimSpace(req.Role)))
+	if rejected := grantable(role, newRole); rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+
+	member, rejected := h.findMember(c, organization)
+	if rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+	if member.Role == models.OrganizationOwner && role != models.OrganizationOwner {
+		return utils.SendError(c, fiber.StatusForbidden, "Only owners can change the role of another owner")
+	}
+	if member.Role == newRole {
+		return utils.SendError(c, fiber.StatusBadRequest, "Member already has this role")
+	}
+
+	previous := member.Role
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if previous == models.OrganizationOwner {
+			owners, err := lockOwners(tx, organization.ID)
+			if err != nil {
+				return err
+			}
+			if len(owners) <= 1 && slices.Contains(owners, member.AccountID) {
+				return errLastOwner
+			}
+		}
+		return tx.Model(member).Update("role", newRole).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		return utils.SendError(c, fiber.StatusConflict, "Make another member an owner before changing the last owner's role")
+	} else if err != nil {
+		return utils.HandleDBError(c, err, "Error updating member")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventOrganizationMemberUpdate).WithAccount(accountID(c)).WithSubject(organization.ID.String()).WithMetadata(map[string]interface{}{
+		"member_id":     member.AccountID.String(),
+		"previous_role": previous,
+		"role":          newRole,
+	}))
+
+	details, err := members(h.DB, organization.ID, &member.AccountID)
+	if err != nil || len(details) == 0 {
+		log.Printf("Error fetching organization member: %v", err)
+		return utils.SendSuccess(c, fiber.StatusOK, "Member updated successfully", nil)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.OrganizationMemberResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Member updated successfully",
+		},
+		Member: details[0],
+	})
+}
+
+// RemoveMember removes a member from the organization. Any member may leave;
+// removing someone else takes ADMIN, or OWNER to remove an owner. The last
+// owner can neither leave nor be removed.
+func (h *OrganizationHandler) RemoveMember(c *fiber.Ctx) error {
+	organization, role := currentOrganization(c)
+	caller := accountID(c)
+
+	member, rejected := h.findMember(c, organization)
+	if rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+	if member.AccountID != caller {
+		if !role.AtLeast(models.OrganizationAdmin) {
+			return utils.SendError(c, fiber.StatusForbidden, "Your role does not allow this action")
+		}
+		if member.Role == models.OrganizationOwner && role != models.OrganizationOwner {
+			return utils.SendError(c, fiber.StatusForbidden, "Only owners can remove another owner")
+		}
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if member.Role == models.OrganizationOwner {
+			owners, err := lockOwners(tx, organization.ID)
+			if err != nil {
+				return err
+			}
+			if len(owners) <= 1 && slices.Contains(owners, member.AccountID) {
+				return errLastOwner
+			}
+		}
+		return tx.Delete(member).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		return utils.SendError(c, fiber.StatusConflict, "Make another member an owner before the last owner leaves")
+	} else if err != nil {
+		return utils.HandleDBError(c, err, "Error removing member")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventOrganizationMemberRemove).WithAccount(caller).WithSubject(organization.ID.String()).WithMetadata(map[string]interface{}{
+		"member_id": member.AccountID.String(),
+		"role":      member.Role,
+	}))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Member removed successfully", nil)
+}
+
+// findMember loads the membership of the account in the :accountId path
+// parameter.
+func (h *OrganizationHandler) findMember(c *fiber.Ctx, organization *models.Organization) (*models.OrganizationMember, *fiber.Error) {
+	memberID, err := uuid.Parse(c.Params("accountId"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid account ID")
+	}
+
+	var member models.OrganizationMember
+	if err := h.DB.Where("organization_id = ? AND account_id = ?", organization.ID, memberID).First(&member).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Member not found")
+	}
+	return &member, nil
+}
+
+// grantable checks that a member holding granter may hand out role.
+func grantable(granter, role models.OrganizationRole) *fiber.Error {
+	if !role.Valid() {
+		return fiber.NewError(fiber.StatusBadRequest, "Role must be OWNER, ADMIN, DEVELOPER or VIEWER")
+	}
+	if role == models.OrganizationOwner && granter != models.OrganizationOwner {
+		return fiber.NewError(fiber.StatusForbidden, "Only owners can make another member an owner")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package organization
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// CreateOrganization creates an organization with the caller as its owner.
+func (h *OrganizationHandler) CreateOrganization(c *fiber.Ctx) error {
+	var req request.CreateOrganizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	name, rejected := organizationName(req.Name)
+	if rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+
+	creator := accountID(c)
+	organization := models.Organization{Name: name}
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&organization).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OrganizationMember{
+			OrganizationID: organization.ID,
+			AccountID:      creator,
+			Role:           models.OrganizationOwner,
+		}).Error
+	})
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error creating organization")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventOrganizationCreate).WithAccount(creator).WithSubject(organization.ID.String()).WithMetadata(map[string]interface{}{
+		"name": organization.Name,
+	}))
+
+	return c.Status(fiber.StatusCreated).JSON(response.OrganizationResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Organization created successfully",
+		},
+		Organization: response.OrganizationSummary{Organization: organization, Role: models.OrganizationOwner},
+	})
+}
+
+// ListOrganizations returns the organizations the caller belongs to.
+func (h *OrganizationHandler) ListOrganizations(c *fiber.Ctx) error {
+	var organizations []response.OrganizationSummary
+	err := h.DB.Model(&models.Organization{}).
+		Select("organizations.*, organization_members.role").
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.account_id = ?", accountID(c)).
+		Order("organizations.name").
+		Scan(&organizations).Error
+	if err != nil {
+		log.Printf("Error fetching organizations: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching organizations")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.OrganizationListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Organizations fetched successfully",
+		},
+		Organizations: organizations,
+	})
+}
+
+// GetOrganization returns an organization with its members and services.
+func (h *OrganizationHandler) GetOrganization(c *fiber.Ctx) error {
+	organization, role := currentOrganization(c)
+
+	details, err := members(h.DB, organization.ID, nil)
+	if err != nil {
+		log.Printf("Error fetching organization members: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching organization")
+	}
+
+	var services []response.OrganizationService
+	err = h.DB.Model(&models.Service{}).Select("id", "service_name", "owner_id", "created_at").
+		Where("organization_id = ?", organization.ID).Order("created_at").Scan(&services).Error
+	if err != nil {
+		log.Printf("Error fetching organization services: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching organization")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.OrganizationResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Organization fetched successfully",
+		},
+		Organization: response.OrganizationSummary{Organization: *organization, Role: role},
+		Members:      details,
+		Services:     services,
+	})
+}
+
+// UpdateOrganization renames an organization.
+func (h *OrganizationHandler) UpdateOrganization(c *fiber.Ctx) error {
+	var req request.UpdateOrganizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	name, rejected := organizationName(req.Name)
+	if rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+
+	organization, role := currentOrganization(c)
+	previous := organization.Name
+	if err := h.DB.Model(organization).Update("name", name).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error updating organization")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventOrganizationUpdate).WithAccount(accountID(c)).WithSubject(organization.ID.String()).WithMetadata(map[string]interface{}{
+		"previous_name": previous,
+		"name":          name,
+	}))
+
+	return c.Status(fiber.StatusOK).JSON(response.OrganizationResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Organization updated successfully",
+		},
+		Organization: response.OrganizationSummary{Organization: *organization, Role: role},
+	})
+}
+
+// DeleteOrganization deletes an organization that no longer has services;
+// they must first be deleted or moved out.
+func (h *OrganizationHandler) DeleteOrganization(c *fiber.Ctx) error {
+	organization, _ := currentOrganization(c)
+
+	var services int64
+	if err := h.DB.Model(&models.Service{}).Where("organization_id = ?", organization.ID).Count(&services).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error deleting organization")
+	}
+	if services > 0 {
+		return utils.SendError(c, fiber.StatusConflict, "Move or delete the organization's services before deleting it")
+	}
+
+	// Services may not be added concurrently: the foreign key on services
+	// refuses the delete if one was
+	if err := h.DB.Delete(organization).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error deleting organization")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventOrganizationDelete).WithAccount(accountID(c)).WithSubject(organization.ID.String()).WithMetadata(map[string]interface{}{
+		"name": organization.Name,
+	}))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Organization deleted successfully", nil)
+}
+
+// organizationName trims and checks an organization name.
+func organizationName(raw string) (string, *fiber.Error) {
+	name := strings.TrimSpace(raw)
+	if name == "" {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Organization name is required")
+	}
+	if utf8.RuneCountInString(name) > maxOrganizationNameLength {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Organization name must be at most 100 characters")
+	}
+	return name, nil
+}
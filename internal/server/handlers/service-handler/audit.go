@@ -0,0 +1,40 @@
+package service
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListAuditEvents returns the security events of a service, newest first.
+// It accepts the filters of audit.ParseQuery; service_id is always the
+// service in the path.
+func (h *ServiceHandler) ListAuditEvents(c *fiber.Ctx) error {
+	service := managedService(c)
+
+	query, err := audit.ParseQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	query.ServiceID = &service.ID
+
+	events, total, err := audit.Search(c.Context(), h.DB, query)
+	if err != nil {
+		log.Printf("Error fetching audit events: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching audit events")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.AuditEventListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Audit events fetched successfully",
+		},
+		Events: events,
+		Total:  total,
+		Page:   query.Page,
+		Limit:  query.Limit,
+	})
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
 	"aspire-auth/internal/utils"
 	"log"
 
@@ -24,13 +28,44 @@ func (h *ServiceHandler) CreateService(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	// Validate the secret key - ensure it's at least 16 characters for security
-	if len(req.SecretKey) < 16 {
+	if req.ServiceName == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Service name is required")
+	}
+
+	if req.BrandColor != nil && !emailtemplate.ValidColor(*req.BrandColor) {
+		return utils.SendError(c, fiber.StatusBadRequest, "Brand color must be a hex color such as #1aa19c")
+	}
+
+	// Hosted logos belong to the service that uploaded them
+	if _, hosted := helpers.HostedLogo(req.ServiceLogo); hosted {
+		return utils.SendError(c, fiber.StatusBadRequest, "Use PUT /service/:id/logo to upload a logo")
+	}
+
+	var organizationID *uuid.UUID
+	if req.OrganizationID != "" {
+		id, rejected := h.organizationForServices(c, req.OrganizationID, ownerID)
+		if rejected != nil {
+			return utils.SendError(c, rejected.Code, rejected.Message)
+		}
+		organizationID = &id
+	}
+
+	// Owners may still bring their own secret; otherwise generate one for them
+	secretKey := req.SecretKey
+	generated := false
+	if secretKey == "" {
+		secretKey, err = helpers.GenerateServiceSecret()
+		if err != nil {
+			log.Printf("Error generating service secret key: %v", err)
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error processing service secret key")
+		}
+		generated = true
+	} else if len(secretKey) < helpers.MinServiceSecretLength {
 		return utils.SendError(c, fiber.StatusBadRequest, "Service secret key must be at least 16 characters")
 	}
 
 	// Encrypt the service secret
-	encryptedSecret, err := h.Container.JWT.EncryptServiceSecretKey(req.SecretKey)
+	encryptedSecret, err := h.Container.JWT.EncryptServiceSecretKey(secretKey)
 	if err != nil {
 		log.Printf("Error encrypting service secret key: %v", err)
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error processing service secret key")
@@ -38,9 +73,11 @@ func (h *ServiceHandler) CreateService(c *fiber.Ctx) error {
 
 	service := models.Service{
 		OwnerID:            ownerID,
+		OrganizationID:     organizationID,
 		ServiceName:        req.ServiceName,
 		ServiceDescription: req.ServiceDescription,
 		ServiceLogo:        req.ServiceLogo,
+		BrandColor:         req.BrandColor,
 		SecretKey:          encryptedSecret,
 	}
 
@@ -49,10 +86,20 @@ func (h *ServiceHandler) CreateService(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating service")
 	}
 
-	// Return success but never return the secret key in the response
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"success":    true,
-		"message":    "Service created successfully",
-		"service_id": service.ID,
-	})
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceCreate).WithAccount(ownerID).WithService(service.ID).WithSubject(service.ServiceName))
+
+	// A generated secret is shown exactly once; owner-chosen secrets are never echoed back
+	res := response.CreateServiceResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Service created successfully",
+		},
+		ServiceID: service.ID,
+	}
+	if generated {
+		res.SecretKey = secretKey
+		res.Message = "Service created successfully. Store the secret key now, it will not be shown again."
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(res)
 }
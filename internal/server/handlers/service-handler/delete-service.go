@@ -1,6 +1,7 @@
 package service
 
 import (
+	"aspire-auth/internal/audit"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/utils"
 
@@ -8,24 +9,20 @@ import (
 )
 
 func (h *ServiceHandler) DeleteService(c *fiber.Ctx) error {
-	serviceID := c.Params("id")
-	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
-
-	// Check ownership
-	var service models.Service
-	if err := h.DB.Where("id = ? AND owner_id = ?", serviceID, authToken.UserID).First(&service).Error; err != nil {
-		return utils.SendError(c, fiber.StatusNotFound, "Service not found or not authorized")
-	}
+	service := managedService(c)
 
 	// Delete related records
-	if err := h.DB.Where("service_id = ?", serviceID).Delete(&models.ServicesUser{}).Error; err != nil {
+	if err := h.DB.Where("service_id = ?", service.ID).Delete(&models.ServicesUser{}).Error; err != nil {
 		return utils.HandleError(c, err)
 	}
 
 	// Delete service
-	if err := h.DB.Delete(&service).Error; err != nil {
+	if err := h.DB.Delete(service).Error; err != nil {
 		return utils.HandleError(c, err)
 	}
 
+	h.removeLogo(c.Context(), service.ServiceLogo)
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceDelete).WithAccount(actorID(c)).WithService(service.ID).WithSubject(service.ServiceName))
+
 	return utils.SendSuccess(c, fiber.StatusOK, "Service deleted successfully", nil)
 }
@@ -13,10 +13,6 @@ import (
 func (h *ServiceHandler) GetServiceUserDetails(context *fiber.Ctx) error {
 	authToken := context.Locals("auth").(*models.ServiceAuthorizationToken)
 
-	// Log authentication data for debugging
-	log.Printf("Service Auth Token: UserID=%s, ServiceID=%s, RoleType=%s",
-		authToken.UserID, authToken.ServiceID, authToken.RoleType)
-
 	// Convert string IDs to UUID
 	userID, err := uuid.Parse(authToken.UserID)
 	if err != nil {
@@ -1,6 +1,13 @@
 package service
 
-import "aspire-auth/internal/container"
+import (
+	"aspire-auth/internal/container"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
 
 type ServiceHandler struct {
 	*container.Container
@@ -9,3 +16,23 @@ type ServiceHandler struct {
 func NewServiceHandler(base *container.Container) *ServiceHandler {
 	return &ServiceHandler{Container: base}
 }
+
+// credentialFailure counts a failed attempt and answers with either the
+// detailed or the uniform credential error
+func (h *ServiceHandler) credentialFailure(c *fiber.Ctx, email string, accountExists bool, status int, message string) error {
+	failure := h.Lockout.RecordFailure(c.Context(), email, c.IP(), accountExists)
+	if failure.Locked {
+		return utils.SendTooManyRequests(c, failure.RetryAfter, "Too many failed attempts. Please try again later.")
+	}
+	return utils.SendCredentialError(c, h.Config.Security.UniformAuthResponses, status, message)
+}
+
+// managedService is the service RequireServiceRole resolved for the route.
+func managedService(c *fiber.Ctx) *models.Service {
+	return c.Locals("service").(*models.Service)
+}
+
+// actorID is the account calling a route behind AccountAuthMiddleware.
+func actorID(c *fiber.Ctx) uuid.UUID {
+	return uuid.MustParse(c.Locals("auth").(*models.AccountAuthorizationToken).UserID)
+}
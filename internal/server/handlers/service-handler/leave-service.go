@@ -1,23 +1,48 @@
 package service
 
 import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/events"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 func (h *ServiceHandler) LeaveService(c *fiber.Ctx) error {
-	serviceID := c.Params("id")
 	authToken := c.Locals("auth").(*models.ServiceAuthorizationToken)
 
-	// Delete service user record
-	result := h.DB.Where("service_id = ? AND user_id = ?", serviceID, authToken.UserID).Delete(&models.ServicesUser{})
-	if result.Error != nil {
+	serviceID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "You are not a member of this service")
+	}
+	accountID, err := uuid.Parse(authToken.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token")
+	}
+
+	// Delete service user record together with the event announcing it
+	left := audit.Success(c, audit.EventServiceLeave).WithAccount(accountID).WithService(serviceID).WithSubject(authToken.UserID)
+	var removed int64
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("service_id = ? AND user_id = ?", serviceID, accountID).Delete(&models.ServicesUser{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		removed = result.RowsAffected
+		return events.Publish(c.Context(), tx, events.Event{
+			Type:    events.MemberLeft,
+			Payload: events.MembershipPayload{AccountID: accountID, ServiceID: serviceID},
+			Audit:   &left,
+		})
+	})
+	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error leaving the service")
 	}
 
-	if result.RowsAffected == 0 {
+	if removed == 0 {
 		return utils.SendError(c, fiber.StatusNotFound, "You are not a member of this service")
 	}
 
@@ -1,25 +1,41 @@
 package service
 
 import (
+	"aspire-auth/internal/access"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/response"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
+// ListMyServices returns the caller's personal services and those of every
+// organization they belong to, each with the caller's role on it.
 func (h *ServiceHandler) ListMyServices(c *fiber.Ctx) error {
-	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
+	accountID := actorID(c)
 
 	var services []models.Service
 	var total int64
 
-	if err := h.DB.Where("owner_id = ?", authToken.UserID).Find(&services).Error; err != nil {
+	if err := access.ManagedServices(h.DB, accountID).Order("created_at").Find(&services).Error; err != nil {
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
 			Message: "Error fetching services",
 		})
 	}
 
+	var memberships []models.OrganizationMember
+	if err := h.DB.Where("account_id = ?", accountID).Find(&memberships).Error; err != nil {
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error fetching services",
+		})
+	}
+	roles := make(map[uuid.UUID]models.OrganizationRole, len(memberships))
+	for _, member := range memberships {
+		roles[member.OrganizationID] = member.Role
+	}
+
 	total = int64(len(services))
 
 	serviceResponses := make([]response.ServiceResponse, len(services))
@@ -27,12 +43,19 @@ func (h *ServiceHandler) ListMyServices(c *fiber.Ctx) error {
 		var usersCount int64
 		h.DB.Model(&models.ServicesUser{}).Where("service_id = ?", service.ID).Count(&usersCount)
 
+		role := models.OrganizationOwner
+		if service.OrganizationID != nil {
+			role = roles[*service.OrganizationID]
+		}
+
 		serviceResponses[i] = response.ServiceResponse{
-			ID:          service.ID.String(),
-			Name:        service.ServiceName,
-			Description: service.ServiceDescription,
-			Logo:        service.ServiceLogo,
-			UsersCount:  usersCount,
+			ID:             service.ID.String(),
+			Name:           service.ServiceName,
+			Description:    service.ServiceDescription,
+			Logo:           service.ServiceLogo,
+			UsersCount:     usersCount,
+			OrganizationID: service.OrganizationID,
+			Role:           role,
 		}
 	}
 
@@ -2,25 +2,31 @@ package service
 
 import (
 	"aspire-auth/internal/models"
-	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+const (
+	defaultUsersPage = 10
+	maxUsersPage     = 100
+)
+
+// ListServiceUsers returns a page of the service's members. Any role on the
+// service, viewer included, may see them.
 func (h *ServiceHandler) ListServiceUsers(c *fiber.Ctx) error {
-	var req request.ServiceUsersListRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(response.APIResponse{
-			Success: false,
-			Message: "Invalid request format",
-		})
+	service := managedService(c)
+
+	page := max(c.QueryInt("page", 1), 1)
+	limit := c.QueryInt("limit", defaultUsersPage)
+	if limit < 1 || limit > maxUsersPage {
+		limit = defaultUsersPage
 	}
 
 	var serviceUsers []models.ServicesUser
 	var total int64
 
-	query := h.DB.Model(&models.ServicesUser{}).Where("service_id = ?", req.ServiceID)
+	query := h.DB.Model(&models.ServicesUser{}).Where("service_id = ?", service.ID)
 
 	if err := query.Count(&total).Error; err != nil {
 		return c.Status(500).JSON(response.APIResponse{
@@ -30,8 +36,9 @@ func (h *ServiceHandler) ListServiceUsers(c *fiber.Ctx) error {
 	}
 
 	if err := query.Preload("User").
-		Offset((req.Page - 1) * req.Limit).
-		Limit(req.Limit).
+		Order("created_at").
+		Offset((page - 1) * limit).
+		Limit(limit).
 		Find(&serviceUsers).Error; err != nil {
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
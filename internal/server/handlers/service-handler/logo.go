@@ -0,0 +1,161 @@
+package service
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/imaging"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UpdateLogo replaces the service's logo with an uploaded image, sent either
+// as a multipart "logo" file or as base64 in a JSON body. It is stored in
+// every size in helpers.LogoVariants and served from /images/logos.
+func (h *ServiceHandler) UpdateLogo(c *fiber.Ctx) error {
+	service := managedService(c)
+
+	data, rejected := h.readLogoUpload(c)
+	if rejected != nil {
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+
+	processed, err := imaging.Process(data, helpers.LogoOptions(h.Config.Logo))
+	if err != nil {
+		rejected := h.logoRejection(err)
+		return utils.SendError(c, rejected.Code, rejected.Message)
+	}
+
+	filename, err := helpers.SaveLogo(c.Context(), h.Storage, processed)
+	if err != nil {
+		log.Printf("Error saving logo: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error saving logo")
+	}
+
+	logo := helpers.LogoPath(filename)
+	if err := h.DB.Model(service).Update("service_logo", logo).Error; err != nil {
+		if err := helpers.DeleteLogo(c.Context(), h.Storage, filename); err != nil {
+			log.Printf("Failed to delete logo file after error: %v", err)
+		}
+		return utils.HandleDBError(c, err, "Error updating logo")
+	}
+
+	h.removeLogo(c.Context(), service.ServiceLogo)
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceLogoChange).WithAccount(actorID(c)).WithService(service.ID))
+
+	return c.Status(fiber.StatusOK).JSON(response.LogoResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Logo updated successfully",
+		},
+		Logo:     logo,
+		Variants: h.logoURLs(filename),
+	})
+}
+
+// DeleteLogo clears the service's logo, deleting it if it was uploaded here.
+func (h *ServiceHandler) DeleteLogo(c *fiber.Ctx) error {
+	service := managedService(c)
+	if service.ServiceLogo == nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Service has no logo")
+	}
+
+	if err := h.DB.Model(service).Update("service_logo", nil).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error removing logo")
+	}
+
+	h.removeLogo(c.Context(), service.ServiceLogo)
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceLogoChange).WithAccount(actorID(c)).WithService(service.ID))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Logo removed", nil)
+}
+
+// readLogoUpload returns the raw image bytes of a multipart or base64
+// upload, or the reason the request was rejected.
+func (h *ServiceHandler) readLogoUpload(c *fiber.Ctx) ([]byte, *fiber.Error) {
+	maxBytes := h.Config.Logo.MaxBytes
+
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		header, err := c.FormFile("logo")
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Logo file is required")
+		}
+		if header.Size > maxBytes {
+			return nil, h.logoRejection(imaging.ErrTooLarge)
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Logo file could not be read")
+		}
+		defer file.Close()
+
+		// Read one byte past the limit so an inaccurate header cannot slip through
+		data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Logo file could not be read")
+		}
+		return data, nil
+	}
+
+	var req request.UpdateLogoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.Logo == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Logo is required")
+	}
+	data, err := helpers.DecodeBase64Image(req.Logo)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Logo must be base64 encoded")
+	}
+	return data, nil
+}
+
+// logoRejection explains why an image was rejected.
+func (h *ServiceHandler) logoRejection(err error) *fiber.Error {
+	cfg := h.Config.Logo
+	switch {
+	case errors.Is(err, imaging.ErrTooLarge):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("Logo must be at most %d KB", cfg.MaxBytes/1024))
+	case errors.Is(err, imaging.ErrUnsupportedType):
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, "Logo must be a JPEG, PNG or GIF image")
+	case errors.Is(err, imaging.ErrDimensions):
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Logo must be between %d and %d pixels on each side", cfg.MinDimension, cfg.MaxDimension))
+	case errors.Is(err, imaging.ErrInvalidImage):
+		return fiber.NewError(fiber.StatusBadRequest, "Logo image is corrupt or incomplete")
+	default:
+		log.Printf("Error processing logo: %v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "Error processing logo")
+	}
+}
+
+// removeLogo deletes a replaced logo if it was uploaded here. External URLs
+// are left alone, and failure only leaves an orphaned file, so it is logged.
+func (h *ServiceHandler) removeLogo(ctx context.Context, previous *string) {
+	filename, hosted := helpers.HostedLogo(previous)
+	if !hosted {
+		return
+	}
+	if err := helpers.DeleteLogo(ctx, h.Storage, filename); err != nil {
+		log.Printf("Failed to delete previous logo: %v", err)
+	}
+}
+
+func (h *ServiceHandler) logoURLs(filename string) map[string]string {
+	base := strings.TrimRight(h.Config.Server.PublicURL, "/") + helpers.LogoPath(url.PathEscape(filename))
+	urls := make(map[string]string, len(helpers.LogoVariants))
+	for _, variant := range helpers.LogoVariants {
+		urls[variant.Name] = base + "?size=" + variant.Name
+	}
+	return urls
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/magiclink"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestServiceMagicLink emails a single-use link that signs a verified
+// member into the service without a password.
+func (h *ServiceHandler) RequestServiceMagicLink(c *fiber.Ctx) error {
+	if !h.Config.MagicLink.SignInEnabled {
+		return utils.SendError(c, fiber.StatusNotFound, "Sign-in links are disabled")
+	}
+
+	var req request.ServiceMagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.Email == "" || req.ServiceID == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Email and service ID are required")
+	}
+
+	var service models.Service
+	if err := h.DB.Where("id = ?", req.ServiceID).First(&service).Error; err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceMagicLinkRequest, "service_not_found").WithSubject(req.Email))
+		if h.Config.Security.UniformAuthResponses {
+			return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+		}
+		return utils.SendError(c, fiber.StatusNotFound, "Service not found")
+	}
+
+	var account models.Account
+	var serviceUser models.ServicesUser
+	reason := ""
+	if err := h.DB.Where("email = ?", req.Email).First(&account).Error; err != nil {
+		reason = "account_not_found"
+	} else if err := h.DB.Where("user_id = ? AND service_id = ?", account.ID, service.ID).First(&serviceUser).Error; err != nil {
+		reason = "not_a_member"
+	} else if !account.IsVerified || !serviceUser.IsVerified {
+		reason = "not_verified"
+	}
+
+	if reason != "" {
+		event := audit.Failure(c, audit.EventServiceMagicLinkRequest, reason).WithService(service.ID).WithSubject(req.Email)
+		if reason != "account_not_found" {
+			event = event.WithAccount(account.ID)
+		}
+		h.Audit.Record(c.Context(), event)
+
+		if h.Config.Security.UniformAuthResponses {
+			return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+		}
+		return utils.SendError(c, fiber.StatusUnauthorized, "No verified membership for this email")
+	}
+
+	token, err := h.MagicLinks.Issue(c.Context(), magiclink.PurposeSignIn, account.ID, &service.ID)
+	if err != nil {
+		log.Printf("Error issuing sign-in link: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating sign-in link")
+	}
+
+	link := fmt.Sprintf("%s/service/login/magic/%s", strings.TrimRight(h.Config.Server.PublicURL, "/"), token)
+	brand := emailtemplate.ServiceBrand(service, h.Config.Server.PublicURL)
+	if err := helpers.SendMagicLinkEmail(c.Context(), h.MailFor(c, &account, brand), account.Email, link, h.Config); err != nil {
+		log.Printf("Email error: %v", err)
+		if !h.Config.Security.UniformAuthResponses {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error sending sign-in link")
+		}
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceMagicLinkRequest).WithAccount(account.ID).WithService(service.ID))
+
+	if h.Config.Security.UniformAuthResponses {
+		return utils.SendSuccess(c, fiber.StatusOK, utils.UniformRecoveryMessage, nil)
+	}
+	return utils.SendSuccess(c, fiber.StatusOK, "Sign-in link sent", nil)
+}
+
+// ServiceMagicLinkLogin consumes a service sign-in link and issues service
+// tokens, re-checking the membership in case it changed since the link was sent.
+func (h *ServiceHandler) ServiceMagicLinkLogin(c *fiber.Ctx) error {
+	if !h.Config.MagicLink.SignInEnabled {
+		return utils.SendError(c, fiber.StatusNotFound, "Sign-in links are disabled")
+	}
+
+	claims, err := h.MagicLinks.Consume(c.Context(), magiclink.PurposeSignIn, c.Params("token"))
+	if errors.Is(err, magiclink.ErrInvalidToken) || (err == nil && claims.ServiceID == nil) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceMagicLinkLogin, "invalid_link"))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in link")
+	} else if err != nil {
+		log.Printf("Magic link error: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error signing in")
+	}
+
+	var service models.Service
+	var account models.Account
+	var serviceUser models.ServicesUser
+	if err := h.DB.Where("id = ?", *claims.ServiceID).First(&service).Error; err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+	if err := h.DB.Where("id = ?", claims.AccountID).First(&account).Error; err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+	if err := h.DB.Where("user_id = ? AND service_id = ?", account.ID, service.ID).First(&serviceUser).Error; err != nil ||
+		!account.IsVerified || !serviceUser.IsVerified {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceMagicLinkLogin, "membership_unavailable").
+			WithAccount(account.ID).WithService(service.ID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in link")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceMagicLinkLogin).WithAccount(account.ID).WithService(service.ID))
+
+	return h.completeServiceSignIn(c, &account, &service, "Service logged in successfully")
+}
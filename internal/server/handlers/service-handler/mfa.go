@@ -0,0 +1,96 @@
+package service
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/mfa"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/otp"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// startServiceMFAChallenge sends account a sign-in code in the service's
+// branding and writes the challenge token the code must be posted back with
+// to VerifyServiceMFA. The challenge cannot finish an account sign-in.
+func (h *ServiceHandler) startServiceMFAChallenge(c *fiber.Ctx, account *models.Account, service *models.Service) error {
+	mail := h.MailFor(c, account, emailtemplate.ServiceBrand(*service, h.Config.Server.PublicURL))
+	sender, err := h.CodeSender(mail, account, "")
+	if err != nil {
+		// The phone was removed or is unverified; email always works
+		sender, _ = h.CodeSender(mail, account, string(otp.ChannelEmail))
+	}
+
+	token, err := h.MFA.Start(c.Context(), account, &service.ID, sender, h.CodeDelivery(account, otp.PurposeMFA, ""))
+	var limited *otp.RateLimitError
+	if errors.As(err, &limited) {
+		return utils.SendTooManyRequests(c, limited.RetryAfter, "Please wait before requesting another code")
+	} else if err != nil {
+		log.Printf("Error starting MFA challenge: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error sending sign-in code")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventMFAChallenge).WithAccount(account.ID).WithService(service.ID).WithSubject(string(sender.Channel())))
+
+	return c.Status(fiber.StatusOK).JSON(response.MFAChallengeResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "A sign-in code has been sent",
+		},
+		MFARequired:    true,
+		ChallengeToken: token,
+		Channel:        string(sender.Channel()),
+		ExpiresAt:      time.Now().Add(h.Config.MFA.ChallengeTTL).Unix(),
+	})
+}
+
+// VerifyServiceMFA finishes a service sign-in started by
+// completeServiceSignIn with the code that was sent to the account,
+// re-checking the membership in case it changed during the challenge.
+func (h *ServiceHandler) VerifyServiceMFA(c *fiber.Ctx) error {
+	var req request.MFAVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+	if req.ChallengeToken == "" || req.OTP == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Challenge token and code are required")
+	}
+
+	account, challenge, err := h.MFA.Finish(c.Context(), req.ChallengeToken, req.OTP, true)
+	switch {
+	case errors.Is(err, mfa.ErrInvalidChallenge):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMFAVerify, "invalid_challenge"))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in challenge. Please sign in again.")
+	case errors.Is(err, mfa.ErrCodeExhausted):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMFAVerify, "code_exhausted").WithAccount(account.ID).WithService(*challenge.ServiceID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "The sign-in code has expired or was entered incorrectly too many times. Please sign in again.")
+	case errors.Is(err, otp.ErrInvalidCode):
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventMFAVerify, "invalid_code").WithAccount(account.ID).WithService(*challenge.ServiceID))
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid sign-in code")
+	case err != nil:
+		log.Printf("Error verifying MFA challenge: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error verifying sign-in code")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventMFAVerify).WithAccount(account.ID).WithService(*challenge.ServiceID))
+
+	var service models.Service
+	var serviceUser models.ServicesUser
+	if err := h.DB.Where("id = ?", *challenge.ServiceID).First(&service).Error; err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in challenge. Please sign in again.")
+	}
+	if err := h.DB.Where("user_id = ? AND service_id = ?", account.ID, service.ID).First(&serviceUser).Error; err != nil ||
+		!account.IsVerified || !serviceUser.IsVerified {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceLogin, "membership_unavailable").
+			WithAccount(account.ID).WithService(service.ID))
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired sign-in challenge. Please sign in again.")
+	}
+
+	return h.issueServiceSession(c, account, &service, "Service logged in successfully")
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"aspire-auth/internal/access"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// MoveService places the service in an organization the caller administers,
+// or, with an empty organization_id, takes it out of its organization and
+// makes it the caller's personal service. Only the service's owner may move
+// it: its personal owner, or an owner of its current organization.
+func (h *ServiceHandler) MoveService(c *fiber.Ctx) error {
+	var req request.MoveServiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	service := managedService(c)
+	accountID := actorID(c)
+
+	metadata := map[string]interface{}{"from": nil, "to": nil}
+	if service.OrganizationID != nil {
+		metadata["from"] = service.OrganizationID.String()
+	}
+
+	updates := map[string]interface{}{}
+	if req.OrganizationID == "" {
+		if service.OrganizationID == nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Service does not belong to an organization")
+		}
+		updates["organization_id"] = nil
+		updates["owner_id"] = accountID
+	} else {
+		organizationID, rejected := h.organizationForServices(c, req.OrganizationID, accountID)
+		if rejected != nil {
+			return utils.SendError(c, rejected.Code, rejected.Message)
+		}
+		if service.OrganizationID != nil && *service.OrganizationID == organizationID {
+			return utils.SendError(c, fiber.StatusBadRequest, "Service already belongs to this organization")
+		}
+		updates["organization_id"] = organizationID
+		metadata["to"] = organizationID.String()
+	}
+
+	if err := h.DB.Model(service).Updates(updates).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error moving service")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceOrganization).WithAccount(accountID).WithService(service.ID).WithMetadata(metadata))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Service moved successfully", nil)
+}
+
+// organizationForServices parses the organization a service is being placed
+// in and checks that accountID may manage services there, which takes at
+// least the ADMIN role.
+func (h *ServiceHandler) organizationForServices(c *fiber.Ctx, raw string, accountID uuid.UUID) (uuid.UUID, *fiber.Error) {
+	organizationID, err := uuid.Parse(raw)
+	if err != nil {
+		return uuid.Nil, fiber.NewError(fiber.StatusBadRequest, "Invalid organization ID")
+	}
+
+	role, err := access.OrganizationRole(h.DB, organizationID, accountID)
+	if err != nil {
+		log.Printf("Error resolving organization role: %v", err)
+		return uuid.Nil, fiber.NewError(fiber.StatusInternalServerError, "Error checking organization access")
+	}
+	if role == "" {
+		return uuid.Nil, fiber.NewError(fiber.StatusNotFound, "Organization not found")
+	}
+	if !role.AtLeast(models.OrganizationAdmin) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventOrganizationAccessDenied, "insufficient_role").
+			WithAccount(accountID).WithSubject(organizationID.String()).
+			WithMetadata(map[string]interface{}{"role": role, "required": models.OrganizationAdmin, "route": c.Method() + " " + c.Path()}))
+		return uuid.Nil, fiber.NewError(fiber.StatusForbidden, "Only organization owners and admins can add services to it")
+	}
+	return organizationID, nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"aspire-auth/internal/audit"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
@@ -28,6 +29,7 @@ func (h *ServiceHandler) RefreshServiceToken(c *fiber.Ctx) error {
 	authToken := &models.ServiceAuthorizationToken{}
 	if err := h.Container.JWT.ParseServiceRefreshToken(tokenString, authToken); err != nil {
 		log.Printf("Invalid service refresh token: %v", err)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceTokenRefresh, "invalid_token"))
 		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token")
 	}
 
@@ -36,20 +38,13 @@ func (h *ServiceHandler) RefreshServiceToken(c *fiber.Ctx) error {
 	if err := h.DB.Where("refresh_token = ? AND expires_at > ?",
 		tokenString, time.Now()).First(&refreshTokenModel).Error; err != nil {
 		log.Printf("Refresh token not found in database or expired: %v", err)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceTokenRefresh, "revoked_or_expired").WithSubject(authToken.UserID))
 		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired refresh token")
 	}
 
 	// Generate new tokens
 	userID := uuid.MustParse(authToken.UserID)
 	serviceID := uuid.MustParse(authToken.ServiceID)
-	roleType := authToken.RoleType
-
-	newTokenModel := &models.ServiceRefreshToken{
-		UserID:    userID,
-		ServiceID: serviceID,
-		RoleType:  roleType,
-		ExpiresAt: time.Now().Add(h.Config.JWT.Service.RefreshExpiry),
-	}
 
 	// Get the service to retrieve its secret key
 	var service models.Service
@@ -57,6 +52,24 @@ func (h *ServiceHandler) RefreshServiceToken(c *fiber.Ctx) error {
 		log.Printf("Service not found: %v", err)
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error refreshing tokens")
 	}
+	if service.DisabledAt != nil {
+		return utils.SendError(c, fiber.StatusForbidden, "This service has been disabled")
+	}
+
+	// The role is derived afresh, so losing access to manage the service
+	// takes effect at the next refresh
+	roleType, err := h.serviceRoleType(&service, userID)
+	if err != nil {
+		log.Printf("Error resolving service role: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error refreshing tokens")
+	}
+
+	newTokenModel := &models.ServiceRefreshToken{
+		UserID:    userID,
+		ServiceID: serviceID,
+		RoleType:  roleType,
+		ExpiresAt: time.Now().Add(h.Config.JWT.Service.RefreshExpiry),
+	}
 
 	// Decrypt the service secret key
 	serviceSecret, err := h.Container.JWT.DecryptServiceSecretKey(service.SecretKey)
@@ -86,6 +99,8 @@ func (h *ServiceHandler) RefreshServiceToken(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating refresh token")
 	}
 
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceTokenRefresh).WithAccount(userID).WithService(serviceID))
+
 	// Set cookies
 	c.Cookie(&fiber.Cookie{
 		Name:     "SERVICE_REFRESH_TOKEN",
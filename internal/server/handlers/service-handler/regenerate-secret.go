@@ -0,0 +1,55 @@
+package service
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ServiceHandler) RegenerateServiceSecret(c *fiber.Ctx) error {
+	service := managedService(c)
+
+	secretKey, err := helpers.GenerateServiceSecret()
+	if err != nil {
+		log.Printf("Error generating service secret key: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating service secret key")
+	}
+
+	encryptedSecret, err := h.Container.JWT.EncryptServiceSecretKey(secretKey)
+	if err != nil {
+		log.Printf("Error encrypting service secret key: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error processing service secret key")
+	}
+
+	tx := h.DB.Begin()
+	if err := tx.Model(service).Update("secret_key", encryptedSecret).Error; err != nil {
+		tx.Rollback()
+		return utils.HandleDBError(c, err, "Error updating service secret key")
+	}
+
+	// Tokens signed with the old secret can no longer be verified, so drop their sessions too
+	if err := tx.Where("service_id = ?", service.ID).Delete(&models.ServiceRefreshToken{}).Error; err != nil {
+		tx.Rollback()
+		return utils.HandleDBError(c, err, "Error revoking service sessions")
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.HandleDBError(c, err, "Error updating service secret key")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceSecretRegenerate).WithAccount(actorID(c)).WithService(service.ID))
+
+	return c.Status(fiber.StatusOK).JSON(response.ServiceSecretResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Service secret regenerated. Store it now, it will not be shown again.",
+		},
+		ServiceID: service.ID,
+		SecretKey: secretKey,
+	})
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"aspire-auth/internal/access"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// completeServiceSignIn issues service tokens once the first factor has
+// been checked, or starts an MFA challenge when the account requires a
+// second step, as signing in to the account itself does.
+func (h *ServiceHandler) completeServiceSignIn(c *fiber.Ctx, account *models.Account, service *models.Service, message string) error {
+	// Checked before the second factor so no code goes out
+	if blocked := serviceSignInBlocked(account, service); blocked != nil {
+		return utils.SendError(c, blocked.Code, blocked.Message)
+	}
+	if !account.MFAEnabled {
+		return h.issueServiceSession(c, account, service, message)
+	}
+	return h.startServiceMFAChallenge(c, account, service)
+}
+
+// serviceSignInBlocked reports why account may not sign in to service.
+func serviceSignInBlocked(account *models.Account, service *models.Service) *fiber.Error {
+	// Accounts waiting to be purged may only sign in to cancel the deletion
+	if account.DeletionScheduledAt != nil {
+		return fiber.NewError(fiber.StatusForbidden, "This account is scheduled for deletion. Sign in to your account to cancel it.")
+	}
+	if account.DisabledAt != nil {
+		return fiber.NewError(fiber.StatusForbidden, "This account has been disabled")
+	}
+	if service.DisabledAt != nil {
+		return fiber.NewError(fiber.StatusForbidden, "This service has been disabled")
+	}
+	return nil
+}
+
+// issueServiceSession creates a refresh token for account in service, signed
+// with the service's own secret, sets the session cookies and writes the
+// login response. Every service sign-in method ends here.
+func (h *ServiceHandler) issueServiceSession(c *fiber.Ctx, account *models.Account, service *models.Service, message string) error {
+	if blocked := serviceSignInBlocked(account, service); blocked != nil {
+		return utils.SendError(c, blocked.Code, blocked.Message)
+	}
+
+	userRoleType, err := h.serviceRoleType(service, account.ID)
+	if err != nil {
+		log.Printf("Error resolving service role: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating service tokens")
+	}
+
+	// Retrieve and decrypt the service-specific secret key
+	serviceSecret, err := h.Container.JWT.DecryptServiceSecretKey(service.SecretKey)
+	if err != nil {
+		log.Printf("Error decrypting service secret key: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating service tokens")
+	}
+
+	tokenModel := models.ServiceRefreshToken{
+		UserID:    account.ID,
+		RoleType:  userRoleType,
+		ServiceID: service.ID,
+		ExpiresAt: time.Now().Add(h.Config.JWT.Service.RefreshExpiry),
+	}
+
+	// Generate tokens using the service-specific secret
+	accessToken, err := h.Container.JWT.GenerateServiceAccessTokenWithSecret(&tokenModel, serviceSecret)
+	if err != nil {
+		log.Printf("Error generating service access token: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating access token")
+	}
+
+	refreshToken, err := h.Container.JWT.GenerateServiceRefreshTokenWithSecret(&tokenModel, serviceSecret)
+	if err != nil {
+		log.Printf("Error generating service refresh token: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating refresh token")
+	}
+
+	tokenModel.RefreshToken = refreshToken
+
+	if err := h.DB.Create(&tokenModel).Error; err != nil {
+		log.Printf("Error saving refresh token: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error saving refresh token")
+	}
+
+	// Set cookies for service authentication
+	c.Cookie(&fiber.Cookie{
+		Name:     "SERVICE_REFRESH_TOKEN",
+		Value:    refreshToken,
+		Path:     "/",
+		Expires:  time.Now().Add(h.Config.JWT.Service.RefreshExpiry),
+		MaxAge:   int(h.Config.JWT.Service.RefreshExpiry.Seconds()),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: "None",
+	})
+
+	c.Cookie(&fiber.Cookie{
+		Name:     "SERVICE_ACCESS_TOKEN",
+		Value:    accessToken,
+		Path:     "/",
+		Expires:  time.Now().Add(h.Config.JWT.Service.AccessExpiry),
+		MaxAge:   int(h.Config.JWT.Service.AccessExpiry.Seconds()),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: "None",
+	})
+
+	return c.Status(200).JSON(response.LoginServiceResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: message,
+		},
+		RefreshToken: refreshToken,
+		AccessToken:  accessToken,
+	})
+}
+
+// serviceRoleType is the role a service token carries: ADMIN for accounts
+// that administer the service, whether as its owner or through its
+// organization, and USER for everyone else.
+func (h *ServiceHandler) serviceRoleType(service *models.Service, accountID uuid.UUID) (models.RoleType, error) {
+	role, err := access.ServiceRole(h.DB, service, accountID)
+	if err != nil {
+		return "", err
+	}
+	if role.AtLeast(models.OrganizationAdmin) {
+		return models.RoleAdmin, nil
+	}
+	return models.RoleUser, nil
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/events"
 	"aspire-auth/internal/models"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
@@ -9,7 +11,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 func (h *ServiceHandler) SignupToService(c *fiber.Ctx) error {
@@ -27,19 +29,56 @@ func (h *ServiceHandler) SignupToService(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid service ID")
 	}
 
+	if retryAfter := h.Lockout.Check(c.Context(), req.Email, c.IP()); retryAfter > 0 {
+		return utils.SendTooManyRequests(c, retryAfter, "Too many failed attempts. Please try again later.")
+	}
+
 	// Check if service exists
 	var service models.Service
 	if err := h.DB.Where("id = ?", serviceID).First(&service).Error; err != nil {
-		return utils.SendError(c, fiber.StatusNotFound, "Service not found")
+		h.Passwords.DummyVerify(req.Password)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceSignup, "service_not_found").
+			WithSubject(req.Email))
+		return utils.SendCredentialError(c, h.Config.Security.UniformAuthResponses, fiber.StatusNotFound, "Service not found")
 	}
 
 	var account models.Account
 	if err := h.DB.Where("email = ?", req.Email).First(&account).Error; err != nil {
-		return utils.SendError(c, fiber.StatusNotFound, "Account not found")
+		h.Passwords.DummyVerify(req.Password)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceSignup, "account_not_found").
+			WithService(service.ID).WithSubject(req.Email))
+		return h.credentialFailure(c, req.Email, false, fiber.StatusNotFound, "Account not found")
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(account.HashedPassword), []byte(req.Password)) != nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid credentials")
+	needsRehash, err := h.Passwords.Verify(req.Password, account.HashedPassword)
+	if err != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceSignup, "invalid_password").
+			WithAccount(account.ID).WithService(service.ID))
+		return h.credentialFailure(c, req.Email, true, fiber.StatusUnauthorized, "Invalid credentials")
+	}
+
+	h.Lockout.RecordSuccess(c.Context(), req.Email)
+
+	if needsRehash {
+		if err := h.Passwords.Upgrade(h.DB, &account, req.Password); err != nil {
+			log.Printf("Error upgrading password hash: %v", err)
+		}
+	}
+
+	if account.DeletionScheduledAt != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceSignup, "deletion_scheduled").
+			WithAccount(account.ID).WithService(service.ID))
+		return utils.SendError(c, fiber.StatusForbidden, "This account is scheduled for deletion")
+	}
+	if account.DisabledAt != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceSignup, "account_disabled").
+			WithAccount(account.ID).WithService(service.ID))
+		return utils.SendError(c, fiber.StatusForbidden, "This account has been disabled")
+	}
+	if service.DisabledAt != nil {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceSignup, "service_disabled").
+			WithAccount(account.ID).WithService(service.ID))
+		return utils.SendError(c, fiber.StatusForbidden, "This service has been disabled")
 	}
 
 	// Check if user is already signed up
@@ -57,8 +96,21 @@ func (h *ServiceHandler) SignupToService(c *fiber.Ctx) error {
 		IsVerified: true,
 	}
 
-	if err := h.DB.Create(&serviceUser).Error; err != nil {
+	joined := audit.Success(c, audit.EventServiceSignup).WithAccount(account.ID).WithService(service.ID)
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&serviceUser).Error; err != nil {
+			return err
+		}
+		return events.Publish(c.Context(), tx, events.Event{
+			Type:    events.MemberJoined,
+			Payload: events.MembershipPayload{AccountID: account.ID, ServiceID: service.ID},
+			Audit:   &joined,
+		})
+	})
+	if err != nil {
 		log.Printf("Error signing up to service: %v", err)
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceSignup, "database_error").
+			WithAccount(account.ID).WithService(service.ID))
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
 			Message: "Error signing up to service",
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"aspire-auth/internal/access"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/events"
+	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/transfer"
+	"aspire-auth/internal/utils"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errTransferStale     = errors.New("service changed hands since the transfer was offered")
+	errRecipientInactive = errors.New("recipient can no longer own services")
+)
+
+// RequestTransfer nominates another account as the owner of a personal
+// service and emails it a link to accept. Nominating someone else replaces
+// the previous offer. Services of an organization change hands through its
+// membership instead.
+func (h *ServiceHandler) RequestTransfer(c *fiber.Ctx) error {
+	var req request.TransferServiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	service := managedService(c)
+	if service.OrganizationID != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Services of an organization are managed through its members; move the service out of the organization to transfer it")
+	}
+
+	var owner models.Account
+	if err := h.DB.Where("id = ?", service.OwnerID).First(&owner).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error requesting transfer")
+	}
+
+	var recipient models.Account
+	if err := h.DB.Where("LOWER(email) = ?", utils.NormalizeEmail(req.Email)).First(&recipient).Error; err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Services can only be transferred to an existing account")
+	}
+	switch {
+	case recipient.ID == owner.ID:
+		return utils.SendError(c, fiber.StatusBadRequest, "You already own this service")
+	case !access.CanOwnServices(&recipient):
+		return utils.SendError(c, fiber.StatusBadRequest, "Services can only be transferred to a verified, active account")
+	}
+
+	offer, err := h.Transfers.Offer(c.Context(), transfer.Offer{ServiceID: service.ID, FromID: owner.ID, ToID: recipient.ID}, func(token string) error {
+		reviewLink := fmt.Sprintf("%s/service/transfer/%s", strings.TrimRight(h.Config.Server.PublicURL, "/"), token)
+		mail := h.MailFor(c, &recipient, emailtemplate.Brand{})
+		return helpers.SendServiceTransferEmail(c.Context(), mail, recipient.Email, owner.Email, service.ServiceName, reviewLink, h.Config.Transfer.TTL, h.Config)
+	})
+	if err != nil {
+		log.Printf("Error requesting service transfer: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error requesting transfer")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceTransferRequest).WithAccount(actorID(c)).WithService(service.ID).WithSubject(recipient.Email).WithMetadata(map[string]interface{}{
+		"to_id": recipient.ID.String(),
+	}))
+
+	return c.Status(fiber.StatusAccepted).JSON(response.ServiceTransferResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Transfer requested. It completes once " + recipient.Email + " accepts it from the emailed link.",
+		},
+		Transfer: response.ServiceTransfer{ServiceID: service.ID, ToID: recipient.ID, ToEmail: recipient.Email, ExpiresAt: offer.ExpiresAt},
+	})
+}
+
+// GetTransfer returns the transfer waiting to be accepted, if any.
+func (h *ServiceHandler) GetTransfer(c *fiber.Ctx) error {
+	service := managedService(c)
+
+	offer, err := h.Transfers.Pending(c.Context(), service.ID)
+	if errors.Is(err, transfer.ErrNoPendingTransfer) {
+		return utils.SendError(c, fiber.StatusNotFound, "No pending transfer")
+	} else if err != nil {
+		log.Printf("Error loading service transfer: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching transfer")
+	}
+
+	var recipient models.Account
+	if err := h.DB.Select("id", "email").Where("id = ?", offer.ToID).First(&recipient).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "No pending transfer")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.ServiceTransferResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Transfer fetched successfully",
+		},
+		Transfer: response.ServiceTransfer{ServiceID: service.ID, ToID: recipient.ID, ToEmail: recipient.Email, ExpiresAt: offer.ExpiresAt},
+	})
+}
+
+// CancelTransfer withdraws the pending transfer; its link stops working.
+func (h *ServiceHandler) CancelTransfer(c *fiber.Ctx) error {
+	service := managedService(c)
+
+	cancelled, err := h.Transfers.Cancel(c.Context(), service.ID)
+	if err != nil {
+		log.Printf("Error cancelling service transfer: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error cancelling transfer")
+	}
+	if !cancelled {
+		return utils.SendError(c, fiber.StatusNotFound, "No pending transfer")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceTransferCancel).WithAccount(actorID(c)).WithService(service.ID))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Transfer cancelled", nil)
+}
+
+// ReviewTransfer is where the emailed link leads. It describes the offer
+// without acting on it, so link scanners and prefetchers that open it
+// cannot accept the transfer; accepting takes a POST from the recipient.
+func (h *ServiceHandler) ReviewTransfer(c *fiber.Ctx) error {
+	offer, err := h.Transfers.Lookup(c.Context(), c.Params("token"))
+	if errors.Is(err, transfer.ErrInvalidToken) {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link")
+	} else if err != nil {
+		log.Printf("Error loading service transfer: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching transfer")
+	}
+
+	var service models.Service
+	if err := h.DB.Select("id", "service_name").Where("id = ?", offer.ServiceID).First(&service).Error; err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link")
+	}
+	var parties []models.Account
+	if err := h.DB.Select("id", "email").Where("id IN ?", []uuid.UUID{offer.FromID, offer.ToID}).Find(&parties).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error fetching transfer")
+	}
+
+	details := response.ServiceTransfer{ServiceID: service.ID, ServiceName: service.ServiceName, ToID: offer.ToID, ExpiresAt: offer.ExpiresAt}
+	for _, party := range parties {
+		switch party.ID {
+		case offer.FromID:
+			details.FromEmail = party.Email
+		case offer.ToID:
+			details.ToEmail = party.Email
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.ServiceTransferResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Sign in as " + details.ToEmail + " and confirm to accept this service",
+		},
+		Transfer: details,
+	})
+}
+
+// AcceptTransfer makes the signed-in account the owner of the service the
+// token offers it, provided the offer was made to that account and the
+// service has not changed hands in the meantime.
+//
+// The previous owner keeps a plain membership only if it had one, and its
+// service sessions are otherwise revoked, since nothing else ties it to the
+// service any more. The new owner keeps its membership, or is given one, and
+// its existing sessions are raised to ADMIN.
+func (h *ServiceHandler) AcceptTransfer(c *fiber.Ctx) error {
+	token := c.Params("token")
+	caller := actorID(c)
+
+	offer, err := h.Transfers.Lookup(c.Context(), token)
+	if errors.Is(err, transfer.ErrInvalidToken) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceTransfer, "invalid_link").WithAccount(caller))
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link")
+	} else if err != nil {
+		log.Printf("Error loading service transfer: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error accepting transfer")
+	}
+	if offer.ToID != caller {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceTransfer, "wrong_account").WithAccount(caller).WithService(offer.ServiceID))
+		return utils.SendError(c, fiber.StatusForbidden, "This transfer was offered to another account")
+	}
+
+	// Consuming checks the offer is still the one looked up
+	offer, err = h.Transfers.Consume(c.Context(), token)
+	if errors.Is(err, transfer.ErrInvalidToken) {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid or expired link")
+	} else if err != nil {
+		log.Printf("Error consuming service transfer: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error accepting transfer")
+	}
+
+	var service models.Service
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", offer.ServiceID).First(&service).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errTransferStale
+		} else if err != nil {
+			return err
+		}
+		if service.OwnerID != offer.FromID || service.OrganizationID != nil {
+			return errTransferStale
+		}
+
+		var recipient models.Account
+		if err := tx.Where("id = ?", offer.ToID).First(&recipient).Error; err != nil || !access.CanOwnServices(&recipient) {
+			return errRecipientInactive
+		}
+
+		if err := tx.Model(&service).Update("owner_id", offer.ToID).Error; err != nil {
+			return err
+		}
+
+		var formerMember int64
+		if err := tx.Model(&models.ServicesUser{}).Where("service_id = ? AND user_id = ?", service.ID, offer.FromID).Count(&formerMember).Error; err != nil {
+			return err
+		}
+		formerSessions := tx.Where("service_id = ? AND user_id = ?", service.ID, offer.FromID)
+		if formerMember > 0 {
+			err = formerSessions.Model(&models.ServiceRefreshToken{}).Update("role_type", models.RoleUser).Error
+		} else {
+			err = formerSessions.Delete(&models.ServiceRefreshToken{}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// The new owner signs in to the service like any member, so it keeps
+		// its membership or gets one, and its sessions become ADMIN
+		member := models.ServicesUser{ServiceID: service.ID, UserID: offer.ToID}
+		joined := tx.Where(&member).Attrs(models.ServicesUser{IsVerified: true}).FirstOrCreate(&member)
+		if joined.Error != nil {
+			return joined.Error
+		}
+		if joined.RowsAffected > 0 {
+			err := events.Publish(c.Context(), tx, events.Event{
+				Type:    events.MemberJoined,
+				Payload: events.MembershipPayload{AccountID: offer.ToID, ServiceID: service.ID},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.ServiceRefreshToken{}).
+			Where("service_id = ? AND user_id = ?", service.ID, offer.ToID).
+			Update("role_type", models.RoleAdmin).Error
+	})
+	if errors.Is(err, errTransferStale) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceTransfer, "stale_offer").WithAccount(offer.ToID).WithService(offer.ServiceID))
+		return utils.SendError(c, fiber.StatusConflict, "This service has changed hands since the transfer was offered")
+	} else if errors.Is(err, errRecipientInactive) {
+		h.Audit.Record(c.Context(), audit.Failure(c, audit.EventServiceTransfer, "recipient_inactive").WithAccount(offer.ToID).WithService(offer.ServiceID))
+		return utils.SendError(c, fiber.StatusConflict, "Your account can no longer own services")
+	} else if err != nil {
+		return utils.HandleDBError(c, err, "Error accepting transfer")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceTransfer).WithAccount(offer.ToID).WithService(service.ID).WithMetadata(map[string]interface{}{
+		"from_id": offer.FromID.String(),
+		"to_id":   offer.ToID.String(),
+	}))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "You are now the owner of "+service.ServiceName, nil)
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
-	"aspire-auth/internal/models"
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/emailtemplate"
+	"aspire-auth/internal/helpers"
 	"aspire-auth/internal/request"
 	"aspire-auth/internal/response"
 
@@ -9,9 +11,6 @@ import (
 )
 
 func (h *ServiceHandler) UpdateService(c *fiber.Ctx) error {
-	serviceID := c.Params("id")
-	authToken := c.Locals("auth").(*models.AccountAuthorizationToken)
-
 	var req request.UpdateServiceRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(response.APIResponse{
@@ -20,13 +19,7 @@ func (h *ServiceHandler) UpdateService(c *fiber.Ctx) error {
 		})
 	}
 
-	var service models.Service
-	if err := h.DB.Where("id = ? AND owner_id = ?", serviceID, authToken.UserID).First(&service).Error; err != nil {
-		return c.Status(404).JSON(response.APIResponse{
-			Success: false,
-			Message: "Service not found or not authorized",
-		})
-	}
+	service := managedService(c)
 
 	updates := map[string]interface{}{}
 	if req.ServiceName != "" {
@@ -36,16 +29,41 @@ func (h *ServiceHandler) UpdateService(c *fiber.Ctx) error {
 		updates["service_description"] = req.ServiceDescription
 	}
 	if req.ServiceLogo != nil {
+		// Hosted logos belong to the service that uploaded them
+		_, hosted := helpers.HostedLogo(req.ServiceLogo)
+		if hosted && (service.ServiceLogo == nil || *req.ServiceLogo != *service.ServiceLogo) {
+			return c.Status(400).JSON(response.APIResponse{
+				Success: false,
+				Message: "Use PUT /service/:id/logo to upload a logo",
+			})
+		}
 		updates["service_logo"] = req.ServiceLogo
 	}
 
-	if err := h.DB.Model(&service).Updates(updates).Error; err != nil {
+	if req.BrandColor != nil {
+		if !emailtemplate.ValidColor(*req.BrandColor) {
+			return c.Status(400).JSON(response.APIResponse{
+				Success: false,
+				Message: "Brand color must be a hex color such as #1aa19c",
+			})
+		}
+		updates["brand_color"] = req.BrandColor
+	}
+
+	if err := h.DB.Model(service).Updates(updates).Error; err != nil {
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
 			Message: "Error updating service",
 		})
 	}
 
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventServiceUpdate).WithAccount(actorID(c)).WithService(service.ID))
+
+	// A hosted logo replaced by an external URL is no longer referenced
+	if req.ServiceLogo != nil && (service.ServiceLogo == nil || *req.ServiceLogo != *service.ServiceLogo) {
+		h.removeLogo(c.Context(), service.ServiceLogo)
+	}
+
 	return c.Status(200).JSON(response.APIResponse{
 		Success: true,
 		Message: "Service updated successfully",
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/request"
+	"aspire-auth/internal/response"
+	"aspire-auth/internal/utils"
+	"aspire-auth/internal/webhook"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	maxWebhooksPerService = 10
+	defaultDeliveryPage   = 20
+	maxDeliveryPage       = 100
+)
+
+// ListWebhooks returns the service's webhooks and the events on offer.
+func (h *ServiceHandler) ListWebhooks(c *fiber.Ctx) error {
+	service := managedService(c)
+
+	var webhooks []models.Webhook
+	if err := h.DB.Where("service_id = ?", service.ID).Order("created_at").Find(&webhooks).Error; err != nil {
+		log.Printf("Error fetching webhooks: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching webhooks")
+	}
+
+	details := make([]response.WebhookDetails, len(webhooks))
+	for i := range webhooks {
+		details[i] = webhookDetails(&webhooks[i])
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.WebhookListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Webhooks fetched successfully",
+		},
+		Webhooks: details,
+		Catalog:  webhook.Catalog,
+	})
+}
+
+// CreateWebhook subscribes a URL to events of the service. The signing
+// secret is returned once and cannot be read back.
+func (h *ServiceHandler) CreateWebhook(c *fiber.Ctx) error {
+	var req request.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	service := managedService(c)
+
+	if err := webhook.ValidateURL(req.URL, h.Config.Webhook.AllowInsecure); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	events, ok := webhook.ParseEvents(req.Events)
+	if !ok {
+		return utils.SendError(c, fiber.StatusBadRequest, "Events must be one or more of "+strings.Join(webhook.Catalog, ", "))
+	}
+
+	var count int64
+	if err := h.DB.Model(&models.Webhook{}).Where("service_id = ?", service.ID).Count(&count).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error creating webhook")
+	}
+	if count >= maxWebhooksPerService {
+		return utils.SendError(c, fiber.StatusConflict, fmt.Sprintf("A service can have at most %d webhooks", maxWebhooksPerService))
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		log.Printf("Error generating webhook secret: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating webhook")
+	}
+	encryptedSecret, err := h.JWT.EncryptServiceSecretKey(secret)
+	if err != nil {
+		log.Printf("Error encrypting webhook secret: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating webhook")
+	}
+
+	hook := models.Webhook{
+		ServiceID: service.ID,
+		URL:       req.URL,
+		Events:    events,
+		Secret:    encryptedSecret,
+	}
+	if err := h.DB.Create(&hook).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error creating webhook")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventWebhookCreate).WithAccount(actorID(c)).WithService(service.ID).WithSubject(hook.ID.String()).WithMetadata(map[string]interface{}{
+		"url":    hook.URL,
+		"events": hook.Events,
+	}))
+
+	return c.Status(fiber.StatusCreated).JSON(response.WebhookResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Webhook created. Store the secret now, it will not be shown again.",
+		},
+		Webhook: webhookDetails(&hook),
+		Secret:  secret,
+	})
+}
+
+// UpdateWebhook changes a webhook's URL or events, or re-enables it.
+func (h *ServiceHandler) UpdateWebhook(c *fiber.Ctx) error {
+	var req request.UpdateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request format")
+	}
+
+	service := managedService(c)
+	hook, missing := h.findWebhook(c, service)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+
+	updates := map[string]interface{}{}
+	if req.URL != nil {
+		if err := webhook.ValidateURL(*req.URL, h.Config.Webhook.AllowInsecure); err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Events != nil {
+		events, ok := webhook.ParseEvents(req.Events)
+		if !ok {
+			return utils.SendError(c, fiber.StatusBadRequest, "Events must be one or more of "+strings.Join(webhook.Catalog, ", "))
+		}
+		updates["events"] = events
+	}
+	if req.Enabled != nil {
+		if *req.Enabled {
+			// A fresh start: the next failure begins a new DisableAfter window
+			updates["disabled_at"] = nil
+			updates["disabled_reason"] = ""
+			updates["failing_since"] = nil
+		} else if hook.DisabledAt == nil {
+			updates["disabled_at"] = gorm.Expr("CURRENT_TIMESTAMP")
+			updates["disabled_reason"] = "Disabled by the service owner"
+		}
+	}
+	if len(updates) == 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "Nothing to update")
+	}
+
+	if err := h.DB.Model(hook).Updates(updates).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error updating webhook")
+	}
+	if err := h.DB.Where("id = ?", hook.ID).First(hook).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error updating webhook")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventWebhookUpdate).WithAccount(actorID(c)).WithService(service.ID).WithSubject(hook.ID.String()).WithMetadata(map[string]interface{}{
+		"url":     hook.URL,
+		"events":  hook.Events,
+		"enabled": hook.DisabledAt == nil,
+	}))
+
+	return c.Status(fiber.StatusOK).JSON(response.WebhookResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Webhook updated successfully",
+		},
+		Webhook: webhookDetails(hook),
+	})
+}
+
+// DeleteWebhook removes a webhook together with its delivery log.
+func (h *ServiceHandler) DeleteWebhook(c *fiber.Ctx) error {
+	service := managedService(c)
+	hook, missing := h.findWebhook(c, service)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+
+	if err := h.DB.Delete(hook).Error; err != nil {
+		return utils.HandleDBError(c, err, "Error deleting webhook")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventWebhookDelete).WithAccount(actorID(c)).WithService(service.ID).WithSubject(hook.ID.String()))
+
+	return utils.SendSuccess(c, fiber.StatusOK, "Webhook deleted successfully", nil)
+}
+
+// ListWebhookDeliveries returns a webhook's delivery log, newest first.
+// ?status= narrows it to PENDING, DELIVERED or FAILED.
+func (h *ServiceHandler) ListWebhookDeliveries(c *fiber.Ctx) error {
+	service := managedService(c)
+	hook, missing := h.findWebhook(c, service)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+
+	page := max(c.QueryInt("page", 1), 1)
+	limit := c.QueryInt("limit", defaultDeliveryPage)
+	if limit < 1 || limit > maxDeliveryPage {
+		limit = defaultDeliveryPage
+	}
+
+	query := h.DB.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", hook.ID)
+	if status := strings.ToUpper(c.Query("status")); status != "" {
+		switch models.WebhookDeliveryStatus(status) {
+		case models.WebhookPending, models.WebhookDelivered, models.WebhookFailed:
+			query = query.Where("status = ?", status)
+		default:
+			return utils.SendError(c, fiber.StatusBadRequest, "Status must be PENDING, DELIVERED or FAILED")
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("Error counting webhook deliveries: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching webhook deliveries")
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		log.Printf("Error fetching webhook deliveries: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching webhook deliveries")
+	}
+
+	details := make([]response.WebhookDeliveryDetails, len(deliveries))
+	for i := range deliveries {
+		details[i] = deliveryDetails(&deliveries[i])
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.WebhookDeliveryListResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Webhook deliveries fetched successfully",
+		},
+		Deliveries: details,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+	})
+}
+
+// RedeliverWebhook queues a logged delivery's event again. The new delivery
+// keeps the event ID, so receivers can recognise the repeat.
+func (h *ServiceHandler) RedeliverWebhook(c *fiber.Ctx) error {
+	service := managedService(c)
+	hook, missing := h.findWebhook(c, service)
+	if missing != nil {
+		return utils.SendError(c, missing.Code, missing.Message)
+	}
+	if hook.DisabledAt != nil {
+		return utils.SendError(c, fiber.StatusConflict, "Enable the webhook before redelivering")
+	}
+
+	var delivery models.WebhookDelivery
+	if err := h.DB.Where("id = ? AND webhook_id = ?", c.Params("deliveryId"), hook.ID).First(&delivery).Error; err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Delivery not found")
+	}
+	if delivery.Status == models.WebhookPending {
+		return utils.SendError(c, fiber.StatusConflict, "Delivery is still pending")
+	}
+
+	again, err := webhook.Redeliver(c.Context(), h.DB, &delivery)
+	if err != nil {
+		return utils.HandleDBError(c, err, "Error queueing redelivery")
+	}
+
+	h.Audit.Record(c.Context(), audit.Success(c, audit.EventWebhookRedeliver).WithAccount(actorID(c)).WithService(service.ID).WithSubject(hook.ID.String()).WithMetadata(map[string]interface{}{
+		"delivery_id": delivery.ID.String(),
+		"event_id":    delivery.EventID.String(),
+	}))
+
+	return c.Status(fiber.StatusAccepted).JSON(response.WebhookDeliveryResponse{
+		APIResponse: response.APIResponse{
+			Success: true,
+			Message: "Redelivery queued",
+		},
+		Delivery: deliveryDetails(again),
+	})
+}
+
+// findWebhook loads the webhook in the path if it belongs to service.
+func (h *ServiceHandler) findWebhook(c *fiber.Ctx, service *models.Service) (*models.Webhook, *fiber.Error) {
+	var hook models.Webhook
+	if err := h.DB.Where("id = ? AND service_id = ?", c.Params("webhookId"), service.ID).First(&hook).Error; err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Webhook not found")
+	}
+	return &hook, nil
+}
+
+func webhookDetails(hook *models.Webhook) response.WebhookDetails {
+	return response.WebhookDetails{Webhook: *hook, Events: webhook.Events(hook)}
+}
+
+func deliveryDetails(delivery *models.WebhookDelivery) response.WebhookDeliveryDetails {
+	return response.WebhookDeliveryDetails{WebhookDelivery: *delivery, Payload: json.RawMessage(delivery.Payload)}
+}
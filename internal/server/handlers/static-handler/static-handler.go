@@ -3,14 +3,15 @@ package static
 import (
 	"aspire-auth/internal/container"
 	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/imaging"
 	"aspire-auth/internal/response"
+	"aspire-auth/internal/storage"
+	"errors"
 	"fmt"
 	"log"
 	"mime"
 	"net/http"
-	"os"
 	"path"
-	"path/filepath"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -29,8 +30,6 @@ func (h *StaticHandler) ServeImage(c *fiber.Ctx) error {
 	directory := c.Params("directory")
 	filename := c.Params("filename")
 
-	log.Printf("Requested image path: directory=%s, filename=%s", directory, filename)
-
 	// Validate the path
 	if !helpers.IsValidImagePath(directory, filename) {
 		log.Printf("Invalid image path requested: %s/%s", directory, filename)
@@ -40,29 +39,35 @@ func (h *StaticHandler) ServeImage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get the absolute path to the image
-	imagePath := filepath.Join(".", "images", directory, filename)
-	absPath, err := filepath.Abs(imagePath)
-	if err != nil {
-		log.Printf("Error getting absolute path: %v", err)
-		return c.Status(500).JSON(response.APIResponse{
-			Success: false,
-			Message: "Error accessing image",
-		})
+	store := h.Container.Storage
+	key := directory + "/" + filename
+
+	// Avatars and logos are stored in several sizes; avatars uploaded before
+	// sizes existed only have the default one, which is served instead
+	if size := c.Query("size"); size != "" {
+		variants, ok := helpers.ImageVariants(directory)
+		if !ok || !imaging.HasVariant(variants, size) {
+			return c.Status(400).JSON(response.APIResponse{
+				Success: false,
+				Message: "Invalid image size",
+			})
+		}
+		variantKey := directory + "/" + imaging.VariantName(filename, variants, size)
+		if _, err := store.Stat(c.Context(), variantKey); err == nil {
+			key = variantKey
+		}
 	}
 
-	log.Printf("Attempting to serve image from: %s", absPath)
-
-	// Check if file exists and get file info
-	fileInfo, err := os.Stat(absPath)
-	if os.IsNotExist(err) {
-		log.Printf("Image not found: %s", absPath)
+	// Check if the image exists and get its metadata
+	object, err := store.Stat(c.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("Image not found: %s", key)
 		return c.Status(404).JSON(response.APIResponse{
 			Success: false,
 			Message: "Image not found",
 		})
 	} else if err != nil {
-		log.Printf("Error checking file: %v", err)
+		log.Printf("Error checking image: %v", err)
 		return c.Status(500).JSON(response.APIResponse{
 			Success: false,
 			Message: "Error accessing image",
@@ -72,15 +77,15 @@ func (h *StaticHandler) ServeImage(c *fiber.Ctx) error {
 	// Handle If-Modified-Since header
 	if modifiedSince := c.Get("If-Modified-Since"); modifiedSince != "" {
 		if clientModTime, err := http.ParseTime(modifiedSince); err == nil {
-			if fileInfo.ModTime().Unix() <= clientModTime.Unix() {
+			if object.ModTime.Unix() <= clientModTime.Unix() {
 				return c.SendStatus(304) // Not Modified
 			}
 		}
 	}
 
 	// Handle ETag
-	etag := fmt.Sprintf(`"%x-%x"`, fileInfo.ModTime().Unix(), fileInfo.Size())
-	if c.Get("If-None-Match") == etag {
+	etag := object.ETag
+	if etag != "" && c.Get("If-None-Match") == etag {
 		return c.SendStatus(304) // Not Modified
 	}
 
@@ -91,13 +96,29 @@ func (h *StaticHandler) ServeImage(c *fiber.Ctx) error {
 		contentType = "image/png" // fallback to png if type cannot be determined
 	}
 
+	body, object, err := store.Get(c.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.Status(404).JSON(response.APIResponse{
+			Success: false,
+			Message: "Image not found",
+		})
+	} else if err != nil {
+		log.Printf("Error reading image: %v", err)
+		return c.Status(500).JSON(response.APIResponse{
+			Success: false,
+			Message: "Error accessing image",
+		})
+	}
+
 	maxAge := 86400 // 24 hours in seconds
 
 	// Set response headers
 	c.Set("Content-Type", contentType)
 	c.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
-	c.Set("ETag", etag)
-	c.Set("Last-Modified", fileInfo.ModTime().UTC().Format(http.TimeFormat))
+	if object.ETag != "" {
+		c.Set("ETag", object.ETag)
+	}
+	c.Set("Last-Modified", object.ModTime.UTC().Format(http.TimeFormat))
 
-	return c.SendFile(absPath)
+	return c.SendStream(body, int(object.Size))
 }
@@ -3,10 +3,16 @@ package server
 import (
 	"aspire-auth/internal/config"
 	"aspire-auth/internal/container"
+	"aspire-auth/internal/emailtemplate"
 	"aspire-auth/internal/helpers"
+	"aspire-auth/internal/keyprovider"
+	"aspire-auth/internal/mailer"
 	"aspire-auth/internal/middleware"
+	"aspire-auth/internal/models"
 	"aspire-auth/internal/server/handlers"
 	"aspire-auth/internal/server/handlers/static-handler"
+	"aspire-auth/internal/sms"
+	"aspire-auth/internal/storage"
 	"context"
 	"encoding/json"
 	"log"
@@ -36,8 +42,12 @@ func NewAPIServer() *APIServer {
 
 	redis := initRedis(cfg)
 	app := initFiber(cfg)
-	jwtHelpers := helpers.InitJWTHelpers(cfg)
-	container := container.NewContainer(cfg, db, redis, app, jwtHelpers)
+	jwtHelpers := initJWTHelpers(cfg)
+	mail := initMailer(cfg)
+	templates := initEmailTemplates(cfg)
+	text := initSMS(cfg)
+	store := initStorage(cfg)
+	container := container.NewContainer(cfg, db, redis, app, jwtHelpers, mail, templates, text, store)
 	middleWare := middleware.InitMiddleware(container)
 	static := static.NewStaticHandler(container)
 
@@ -60,6 +70,54 @@ func initDatabase(cfg *config.Config) *gorm.DB {
 	return db
 }
 
+func initJWTHelpers(cfg *config.Config) *helpers.JWTHelpers {
+	provider, err := keyprovider.New(cfg.Keys)
+	if err != nil {
+		log.Fatalf("Error initializing key provider: %v", err)
+	}
+
+	jwtHelpers, err := helpers.InitJWTHelpers(cfg, provider)
+	if err != nil {
+		log.Fatalf("Error loading secrets: %v", err)
+	}
+	log.Printf("Loaded secrets from %s key provider", provider.Name())
+
+	return jwtHelpers
+}
+
+func initMailer(cfg *config.Config) mailer.Mailer {
+	mail, err := mailer.New(cfg.Email)
+	if err != nil {
+		log.Fatalf("Error initializing email provider: %v", err)
+	}
+	return mail
+}
+
+func initSMS(cfg *config.Config) sms.Sender {
+	text, err := sms.New(cfg.SMS)
+	if err != nil {
+		log.Fatalf("Error initializing SMS provider: %v", err)
+	}
+	return text
+}
+
+func initStorage(cfg *config.Config) storage.Store {
+	store, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Error initializing storage backend: %v", err)
+	}
+	log.Printf("Storing uploads in %s storage", store.Name())
+	return store
+}
+
+func initEmailTemplates(cfg *config.Config) *emailtemplate.Renderer {
+	templates, err := emailtemplate.New(cfg.Email)
+	if err != nil {
+		log.Fatalf("Error loading email templates: %v", err)
+	}
+	return templates
+}
+
 func initRedis(config *config.Config) *redis.Client {
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:        config.Redis.Address,
@@ -88,6 +146,7 @@ func initFiber(cfg *config.Config) *fiber.App {
 	app := fiber.New(fiber.Config{
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
+		BodyLimit:    cfg.Server.BodyLimit,
 		JSONDecoder:  json.Unmarshal,
 		JSONEncoder:  json.Marshal,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -122,45 +181,126 @@ func (s *APIServer) InitHandlers() {
 		})
 	})
 
+	// Per-IP ceiling for every route below; individual routes add stricter policies
+	s.app.Use(s.middleware.RateLimit("global_ip"))
+
 	// Public routes
 	s.app.Get("/images/:directory/:filename", s.static.ServeImage)
-	s.app.Post("/account", s.handlers.Account.CreateAccount)
-	s.app.Post("/verify", s.handlers.Account.VerifyAccount)
-	s.app.Post("/resend-otp", s.handlers.Account.ResendOTP)
-	s.app.Post("/signin", s.handlers.Auth.Login)
-	s.app.Post("/refresh-token", s.handlers.Auth.RefreshToken)
-	s.app.Post("/service/login", s.handlers.Service.LoginService)
-	s.app.Post("/service/signup", s.handlers.Service.SignupToService)
-	s.app.Post("/service/refresh-token", s.handlers.Service.RefreshServiceToken)
-
-
+	s.app.Post("/account", s.middleware.RateLimit("signup_ip"), s.handlers.Account.CreateAccount)
+	s.app.Post("/verify", s.middleware.RateLimit("verify_ip", "verify_account"), s.handlers.Account.VerifyAccount)
+	s.app.Get("/verify/:token", s.middleware.RateLimit("verify_ip"), s.handlers.Account.VerifyAccountByLink)
+	s.app.Post("/resend-otp", s.middleware.RateLimit("resend_otp_ip", "resend_otp_account"), s.handlers.Account.ResendOTP)
+	s.app.Post("/signin", s.middleware.RateLimit("signin_ip"), s.handlers.Auth.Login)
+	s.app.Post("/signin/mfa", s.middleware.RateLimit("signin_ip"), s.handlers.Auth.VerifyMFA)
+	s.app.Post("/signin/magic-link", s.middleware.RateLimit("magic_link_ip", "magic_link_email"), s.handlers.Auth.RequestMagicLink)
+	s.app.Get("/signin/magic/:token", s.middleware.RateLimit("signin_ip"), s.handlers.Auth.MagicLinkLogin)
+	s.app.Post("/refresh-token", s.middleware.RateLimit("refresh_ip"), s.handlers.Auth.RefreshToken)
+	s.app.Get("/unlock/:token", s.middleware.RateLimit("signin_ip"), s.handlers.Auth.UnlockAccount)
+	s.app.Get("/export/:id/download", s.middleware.RateLimit("download_ip"), s.handlers.Account.DownloadExport)
+	s.app.Post("/forgot-password", s.middleware.RateLimit("forgot_password_ip", "forgot_password_email"), s.handlers.Auth.ForgotPassword)
+	s.app.Post("/reset-password", s.middleware.RateLimit("reset_password_ip"), s.handlers.Auth.ResetPassword)
+	s.app.Get("/revert-email/:token", s.middleware.RateLimit("reset_password_ip"), s.handlers.Auth.ReviewEmailRevert)
+	s.app.Post("/revert-email/:token", s.middleware.RateLimit("reset_password_ip"), s.handlers.Auth.RevertEmailChange)
+	s.app.Post("/service/login", s.middleware.RateLimit("service_login_ip", "service_login_service"), s.handlers.Service.LoginService)
+	s.app.Post("/service/login/mfa", s.middleware.RateLimit("service_login_ip"), s.handlers.Service.VerifyServiceMFA)
+	s.app.Post("/service/login/magic-link", s.middleware.RateLimit("magic_link_ip", "magic_link_email"), s.handlers.Service.RequestServiceMagicLink)
+	s.app.Get("/service/login/magic/:token", s.middleware.RateLimit("service_login_ip"), s.handlers.Service.ServiceMagicLinkLogin)
+	s.app.Post("/service/signup", s.middleware.RateLimit("service_login_ip", "service_login_service"), s.handlers.Service.SignupToService)
+	s.app.Post("/service/refresh-token", s.middleware.RateLimit("refresh_ip"), s.handlers.Service.RefreshServiceToken)
+	s.app.Get("/service/transfer/:token", s.middleware.RateLimit("verify_ip"), s.handlers.Service.ReviewTransfer)
 
 	// Account protected routes group
-	accountGroup := s.app.Group("/account", s.middleware.AccountAuthMiddleware)
+	accountGroup := s.app.Group("/account", s.middleware.AccountAuthMiddleware, s.middleware.RateLimit("account_api_account"))
 	accountGroup.Put("/", s.handlers.Account.UpdateAccount)
 	accountGroup.Delete("/", s.handlers.Account.DeleteAccount)
+	accountGroup.Post("/deletion/cancel", s.handlers.Account.CancelDeletion)
+	accountGroup.Post("/export", s.middleware.RateLimit("data_export_account"), s.handlers.Account.RequestExport)
+	accountGroup.Get("/export/:id", s.handlers.Account.GetExport)
 	accountGroup.Get("/", s.handlers.Account.GetAccountDetails)
+	accountGroup.Put("/password", s.handlers.Account.ChangePassword)
+	accountGroup.Post("/email", s.handlers.Account.RequestEmailChange)
+	accountGroup.Post("/email/confirm", s.handlers.Account.ConfirmEmailChange)
+	accountGroup.Put("/phone", s.handlers.Account.UpdatePhone)
+	accountGroup.Post("/phone/verify", s.handlers.Account.VerifyPhone)
+	accountGroup.Delete("/phone", s.handlers.Account.RemovePhone)
+	accountGroup.Put("/avatar", s.handlers.Account.UpdateAvatar)
+	accountGroup.Delete("/avatar", s.handlers.Account.DeleteAvatar)
+	accountGroup.Put("/mfa", s.handlers.Account.UpdateMFA)
+	accountGroup.Get("/audit", s.handlers.Account.ListAuditEvents)
 
 	// IMPORTANT: Routes that need service auth middleware must come BEFORE routes with account auth middleware
 	// Service user routes (protected by service auth)
-	s.app.Get("/service/user", s.middleware.ServiceAuthMiddleware, s.handlers.Service.GetServiceUserDetails)
-	s.app.Get("/service/user/details", s.middleware.ServiceAuthMiddleware, s.handlers.Service.GetServiceUserDetails)
-	serviceUserGroup := s.app.Group("/service-user", s.middleware.ServiceAuthMiddleware)
+	serviceRateLimit := s.middleware.RateLimit("service_api_service")
+	s.app.Get("/service/user", s.middleware.ServiceAuthMiddleware, serviceRateLimit, s.handlers.Service.GetServiceUserDetails)
+	s.app.Get("/service/user/details", s.middleware.ServiceAuthMiddleware, serviceRateLimit, s.handlers.Service.GetServiceUserDetails)
+	serviceUserGroup := s.app.Group("/service-user", s.middleware.ServiceAuthMiddleware, serviceRateLimit)
 	serviceUserGroup.Delete("/:id/leave", s.handlers.Service.LeaveService)
 	serviceUserGroup.Get("/details", s.handlers.Service.GetServiceUserDetails)
 
 	// Service management routes (protected by account auth)
 	// IMPORTANT: These must come AFTER the service auth routes to prevent path conflicts
-	serviceManageGroup := s.app.Group("/service", s.middleware.AccountAuthMiddleware)
+	serviceManageGroup := s.app.Group("/service", s.middleware.AccountAuthMiddleware, s.middleware.RateLimit("account_api_account"))
 	serviceManageGroup.Post("/", s.handlers.Service.CreateService)
-	serviceManageGroup.Put("/:id", s.handlers.Service.UpdateService)
 	serviceManageGroup.Get("/list", s.handlers.Service.ListMyServices)
-	serviceManageGroup.Get("/users", s.handlers.Service.ListServiceUsers)
-	serviceManageGroup.Delete("/:id", s.handlers.Service.DeleteService)
+	serviceManageGroup.Post("/transfer/:token", s.handlers.Service.AcceptTransfer)
+
+	// Routes on one service authorize through its owner or organization role
+	viewer := s.middleware.RequireServiceRole(models.OrganizationViewer)
+	developer := s.middleware.RequireServiceRole(models.OrganizationDeveloper)
+	admin := s.middleware.RequireServiceRole(models.OrganizationAdmin)
+	owner := s.middleware.RequireServiceRole(models.OrganizationOwner)
+	serviceManageGroup.Get("/:id/users", viewer, s.handlers.Service.ListServiceUsers)
+	serviceManageGroup.Put("/:id", admin, s.handlers.Service.UpdateService)
+	serviceManageGroup.Post("/:id/secret", admin, s.handlers.Service.RegenerateServiceSecret)
+	serviceManageGroup.Put("/:id/logo", admin, s.handlers.Service.UpdateLogo)
+	serviceManageGroup.Delete("/:id/logo", admin, s.handlers.Service.DeleteLogo)
+	serviceManageGroup.Put("/:id/organization", owner, s.handlers.Service.MoveService)
+	serviceManageGroup.Post("/:id/transfer", owner, s.handlers.Service.RequestTransfer)
+	serviceManageGroup.Get("/:id/transfer", owner, s.handlers.Service.GetTransfer)
+	serviceManageGroup.Delete("/:id/transfer", owner, s.handlers.Service.CancelTransfer)
+	serviceManageGroup.Get("/:id/audit", admin, s.handlers.Service.ListAuditEvents)
+	serviceManageGroup.Get("/:id/webhooks", viewer, s.handlers.Service.ListWebhooks)
+	serviceManageGroup.Post("/:id/webhooks", developer, s.handlers.Service.CreateWebhook)
+	serviceManageGroup.Put("/:id/webhooks/:webhookId", developer, s.handlers.Service.UpdateWebhook)
+	serviceManageGroup.Delete("/:id/webhooks/:webhookId", developer, s.handlers.Service.DeleteWebhook)
+	serviceManageGroup.Get("/:id/webhooks/:webhookId/deliveries", viewer, s.handlers.Service.ListWebhookDeliveries)
+	serviceManageGroup.Post("/:id/webhooks/:webhookId/deliveries/:deliveryId/redeliver", developer, s.handlers.Service.RedeliverWebhook)
+	serviceManageGroup.Delete("/:id", owner, s.handlers.Service.DeleteService)
+
+	// Organizations, whose members manage services together
+	organizationGroup := s.app.Group("/organization", s.middleware.AccountAuthMiddleware, s.middleware.RateLimit("account_api_account"))
+	organizationGroup.Post("/", s.handlers.Organization.CreateOrganization)
+	organizationGroup.Get("/", s.handlers.Organization.ListOrganizations)
+	organizationGroup.Get("/:id", s.middleware.RequireOrganizationRole(models.OrganizationViewer), s.handlers.Organization.GetOrganization)
+	organizationGroup.Put("/:id", s.middleware.RequireOrganizationRole(models.OrganizationAdmin), s.handlers.Organization.UpdateOrganization)
+	organizationGroup.Delete("/:id", s.middleware.RequireOrganizationRole(models.OrganizationOwner), s.handlers.Organization.DeleteOrganization)
+	organizationGroup.Post("/:id/members", s.middleware.RequireOrganizationRole(models.OrganizationAdmin), s.handlers.Organization.AddMember)
+	organizationGroup.Put("/:id/members/:accountId", s.middleware.RequireOrganizationRole(models.OrganizationAdmin), s.handlers.Organization.UpdateMember)
+	organizationGroup.Delete("/:id/members/:accountId", s.middleware.RequireOrganizationRole(models.OrganizationViewer), s.handlers.Organization.RemoveMember)
+
+	// Platform administration, for accounts with the ADMIN role
+	adminGroup := s.app.Group("/admin", s.middleware.AccountAuthMiddleware, s.middleware.RequireRole(models.RoleAdmin), s.middleware.RateLimit("account_api_account"))
+	adminGroup.Get("/accounts", s.handlers.Admin.SearchAccounts)
+	adminGroup.Get("/accounts/:id", s.handlers.Admin.GetAccount)
+	adminGroup.Post("/accounts/:id/verify", s.handlers.Admin.VerifyAccount)
+	adminGroup.Post("/accounts/:id/disable", s.handlers.Admin.DisableAccount)
+	adminGroup.Post("/accounts/:id/enable", s.handlers.Admin.EnableAccount)
+	adminGroup.Delete("/accounts/:id/mfa", s.handlers.Admin.ResetMFA)
+	adminGroup.Delete("/accounts/:id/sessions", s.handlers.Admin.RevokeSessions)
+	adminGroup.Get("/services", s.handlers.Admin.ListServices)
+	adminGroup.Post("/services/:id/disable", s.handlers.Admin.DisableService)
+	adminGroup.Post("/services/:id/enable", s.handlers.Admin.EnableService)
+	adminGroup.Get("/audit", s.handlers.Admin.SearchAuditEvents)
+	adminGroup.Get("/audit/export", s.handlers.Admin.ExportAuditEvents)
 }
 
 func (s *APIServer) Run() error {
 	s.InitHandlers()
+	go s.container.Outbox.Run(context.Background())
+	go s.container.Purger.Run(context.Background())
+	go s.container.Exports.Run(context.Background())
+	go s.container.Webhooks.Run(context.Background())
+	go s.container.Events.Run(context.Background())
 	log.Printf("Server is running on port %s", s.container.Config.Server.Port)
 	return s.app.Listen(s.container.Config.Server.Port)
 }
@@ -0,0 +1,71 @@
+package sms
+
+import (
+	"aspire-auth/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPSender hands messages to an SMS gateway that accepts a JSON body of
+// from, to and text, authenticated with a bearer key. cmd/smsfake serves the
+// same API locally for development.
+type HTTPSender struct {
+	from   string
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func NewHTTPSender(cfg config.SMSConfig) (*HTTPSender, error) {
+	if cfg.HTTPURL == "" {
+		return nil, errors.New("SMS_HTTP_URL is required for the http SMS provider")
+	}
+	return &HTTPSender{
+		from:   cfg.From,
+		url:    cfg.HTTPURL,
+		apiKey: cfg.HTTPAPIKey,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (s *HTTPSender) Name() string { return "http" }
+
+// Message is the JSON body posted to the gateway.
+type Message struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+	Text string `json:"text"`
+}
+
+func (s *HTTPSender) Send(ctx context.Context, to, body string) error {
+	payload, err := json.Marshal(Message{From: s.from, To: to, Text: body})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS gateway returned %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
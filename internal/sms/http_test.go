@@ -0,0 +1,105 @@
+package sms_test
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/sms"
+	"aspire-auth/internal/sms/smsfake"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPSender(t *testing.T) {
+	tests := []struct {
+		name       string
+		gatewayKey string
+		senderKey  string
+		wantErr    string
+	}{
+		{name: "delivers", gatewayKey: "secret", senderKey: "secret"},
+		{name: "no key required", gatewayKey: "", senderKey: ""},
+		{name: "wrong key", gatewayKey: "secret", senderKey: "other", wantErr: "401 Unauthorized"},
+		{name: "missing key", gatewayKey: "secret", senderKey: "", wantErr: "401 Unauthorized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := smsfake.New(tt.gatewayKey, 10)
+			server := httptest.NewServer(gateway)
+			t.Cleanup(server.Close)
+
+			sender, err := sms.NewHTTPSender(config.SMSConfig{
+				From:       "Aspire",
+				HTTPURL:    server.URL,
+				HTTPAPIKey: tt.senderKey,
+				Timeout:    5 * time.Second,
+			})
+			if err != nil {
+				t.Fatalf("NewHTTPSender: %v", err)
+			}
+
+			err = sender.Send(context.Background(), "+14155550123", "Your code is 123456")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Send() error = %v, want %q", err, tt.wantErr)
+				}
+				if got := len(gateway.Messages()); got != 0 {
+					t.Fatalf("gateway kept %d messages from a rejected send", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			messages := gateway.Messages()
+			if len(messages) != 1 {
+				t.Fatalf("gateway has %d messages, want 1", len(messages))
+			}
+			if got := messages[0].Message; got != (sms.Message{From: "Aspire", To: "+14155550123", Text: "Your code is 123456"}) {
+				t.Fatalf("gateway received %+v", got)
+			}
+		})
+	}
+}
+
+func TestHTTPSenderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	sender, err := sms.NewHTTPSender(config.SMSConfig{HTTPURL: server.URL, Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewHTTPSender: %v", err)
+	}
+	if err := sender.Send(context.Background(), "+14155550123", "hello"); err == nil {
+		t.Fatal("Send() to a stalled gateway succeeded")
+	}
+}
+
+func TestValidNumber(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"+14155550123", true},
+		{"+447911123456", true},
+		{"14155550123", false},
+		{"+0123456789", false},
+		{"+1 415 555 0123", false},
+		{"+12345", false},
+		{"+1234567890123456", false},
+	}
+
+	for _, tt := range tests {
+		if got := sms.ValidNumber(tt.number); got != tt.want {
+			t.Errorf("ValidNumber(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package sms
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+)
+
+// e164Pattern matches phone numbers in E.164 form, e.g. +14155550123.
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ValidNumber reports whether number is in E.164 form, the only form
+// accepted for account phone numbers.
+func ValidNumber(number string) bool {
+	return e164Pattern.MatchString(number)
+}
+
+// Sender delivers a single text message. Implementations must be safe for
+// concurrent use.
+type Sender interface {
+	// Name identifies the provider in logs
+	Name() string
+	Send(ctx context.Context, to, body string) error
+}
+
+// New builds the sender selected by cfg.Provider.
+func New(cfg config.SMSConfig) (Sender, error) {
+	switch cfg.Provider {
+	case "", "log":
+		return LogSender{}, nil
+	case "http":
+		return NewHTTPSender(cfg)
+	default:
+		return nil, fmt.Errorf("unknown SMS provider %q", cfg.Provider)
+	}
+}
+
+// LogSender is for development: it only logs that a message was sent. The
+// body carries a code, so it is never logged.
+type LogSender struct{}
+
+func (LogSender) Name() string { return "log" }
+
+func (LogSender) Send(ctx context.Context, to, body string) error {
+	log.Printf("SMS to %s (%d characters)", to, len(body))
+	return nil
+}
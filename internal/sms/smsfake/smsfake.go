@@ -0,0 +1,70 @@
+// Package smsfake is a stand-in for an SMS gateway speaking the API of the
+// http SMS provider. It keeps the most recent messages so codes can be read
+// back, over GET or from Go in tests. cmd/smsfake serves it locally.
+package smsfake
+
+import (
+	"aspire-auth/internal/sms"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Received is a message the gateway accepted.
+type Received struct {
+	sms.Message
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Gateway accepts messages by POST and lists them by GET. When APIKey is set
+// a POST must carry it as a bearer token.
+type Gateway struct {
+	APIKey string
+	// Keep is how many messages are kept; older ones are dropped
+	Keep int
+
+	mu       sync.Mutex
+	messages []Received
+}
+
+func New(apiKey string, keep int) *Gateway {
+	return &Gateway{APIKey: apiKey, Keep: keep}
+}
+
+// Messages returns the kept messages, oldest first.
+func (g *Gateway) Messages() []Received {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Received(nil), g.messages...)
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		if g.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+g.APIKey {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var msg sms.Message
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&msg); err != nil || msg.To == "" {
+			http.Error(w, "invalid message", http.StatusBadRequest)
+			return
+		}
+		log.Printf("SMS to %s: %s", msg.To, msg.Text)
+
+		g.mu.Lock()
+		g.messages = append(g.messages, Received{Message: msg, ReceivedAt: time.Now()})
+		if len(g.messages) > g.Keep {
+			g.messages = g.messages[len(g.messages)-g.Keep:]
+		}
+		g.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.Messages())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
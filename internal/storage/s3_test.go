@@ -0,0 +1,160 @@
+package storage_test
+
+import (
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/storage"
+	"aspire-auth/internal/storage/s3fake"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newS3Store(t *testing.T, secretKey string) *storage.S3Store {
+	t.Helper()
+	server := httptest.NewServer(s3fake.New("fake-access-key", "fake-secret-key", "us-east-1", 1<<20))
+	t.Cleanup(server.Close)
+
+	store, err := storage.NewS3Store(config.StorageConfig{
+		S3Endpoint:        server.URL,
+		S3Region:          "us-east-1",
+		S3Bucket:          "aspire",
+		S3AccessKeyID:     "fake-access-key",
+		S3SecretAccessKey: secretKey,
+		S3PathStyle:       true,
+		Timeout:           5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store: %v", err)
+	}
+	return store
+}
+
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	store := newS3Store(t, "fake-secret-key")
+
+	tests := []struct {
+		name        string
+		key         string
+		body        string
+		contentType string
+	}{
+		{name: "image", key: "avatars/a.png", body: "png bytes", contentType: "image/png"},
+		{name: "key needing escapes", key: "exports/a b+c.zip", body: "zip bytes", contentType: "application/zip"},
+		{name: "no content type", key: "logos/plain", body: "data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Put(ctx, tt.key, strings.NewReader(tt.body), tt.contentType); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			object, err := store.Stat(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Stat: %v", err)
+			}
+			if object.Size != int64(len(tt.body)) || object.ETag == "" || object.ModTime.IsZero() {
+				t.Fatalf("Stat() = %+v", object)
+			}
+			if tt.contentType != "" && object.ContentType != tt.contentType {
+				t.Fatalf("content type %q, want %q", object.ContentType, tt.contentType)
+			}
+
+			body, _, err := store.Get(ctx, tt.key)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			data, _ := io.ReadAll(body)
+			body.Close()
+			if string(data) != tt.body {
+				t.Fatalf("Get() = %q, want %q", data, tt.body)
+			}
+
+			signed, err := store.SignedURL(ctx, tt.key, time.Minute, "download.bin")
+			if err != nil {
+				t.Fatalf("SignedURL: %v", err)
+			}
+			resp, err := http.Get(signed)
+			if err != nil {
+				t.Fatalf("fetching signed URL: %v", err)
+			}
+			data, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || string(data) != tt.body {
+				t.Fatalf("signed URL returned %s %q", resp.Status, data)
+			}
+			if got := resp.Header.Get("Content-Disposition"); !strings.Contains(got, "download.bin") {
+				t.Fatalf("Content-Disposition %q does not name the download", got)
+			}
+
+			if err := store.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if _, err := store.Stat(ctx, tt.key); !errors.Is(err, storage.ErrNotFound) {
+				t.Fatalf("Stat() after Delete error = %v, want ErrNotFound", err)
+			}
+			// Deleting again is not an error
+			if err := store.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("second Delete: %v", err)
+			}
+		})
+	}
+}
+
+func TestS3StoreErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		secret  string
+		run     func(store *storage.S3Store) error
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:    "missing object",
+			secret:  "fake-secret-key",
+			run:     func(s *storage.S3Store) error { _, _, err := s.Get(ctx, "avatars/missing.png"); return err },
+			wantErr: storage.ErrNotFound,
+		},
+		{
+			name:    "bad credentials",
+			secret:  "wrong-secret",
+			run:     func(s *storage.S3Store) error { return s.Put(ctx, "avatars/a.png", bytes.NewReader([]byte("x")), "") },
+			wantMsg: "403",
+		},
+		{
+			name:    "escaping key",
+			secret:  "fake-secret-key",
+			run:     func(s *storage.S3Store) error { return s.Put(ctx, "../a.png", bytes.NewReader([]byte("x")), "") },
+			wantMsg: "invalid key",
+		},
+		{
+			name:   "too large",
+			secret: "fake-secret-key",
+			run: func(s *storage.S3Store) error {
+				return s.Put(ctx, "avatars/big", bytes.NewReader(make([]byte, 2<<20)), "")
+			},
+			wantMsg: "413",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(newS3Store(t, tt.secret))
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.wantMsg)) {
+				t.Fatalf("error = %v, want it to mention %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Package s3fake is an in-memory stand-in for an S3-compatible object store.
+// It serves path-style PUT, GET, HEAD and DELETE and rejects requests whose
+// SigV4 signature or payload hash does not check out, including presigned
+// GET URLs. cmd/s3fake serves it locally; tests mount it on httptest.
+package s3fake
+
+import (
+	"aspire-auth/internal/storage"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+	etag        string
+}
+
+// Server holds the objects and the credentials clients must sign with.
+type Server struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	// MaxSize is the largest object accepted, in bytes
+	MaxSize int64
+
+	mu      sync.Mutex
+	objects map[string]*object
+}
+
+func New(accessKey, secretKey, region string, maxSize int64) *Server {
+	return &Server{
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Region:    region,
+		MaxSize:   maxSize,
+		objects:   map[string]*object{},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if !found || bucket == "" || key == "" {
+		http.Error(w, "path-style /bucket/key addressing is required", http.StatusBadRequest)
+		return
+	}
+	if err := storage.VerifySigV4(r, s.AccessKey, s.SecretKey, s.Region, time.Now()); err != nil {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+	name := bucket + "/" + key
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.MaxSize))
+		if err != nil {
+			http.Error(w, "EntityTooLarge", http.StatusRequestEntityTooLarge)
+			return
+		}
+		sum := sha256.Sum256(data)
+		if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		digest := md5.Sum(data)
+		obj := &object{
+			data:        data,
+			contentType: r.Header.Get("Content-Type"),
+			modTime:     time.Now().UTC().Truncate(time.Second),
+			etag:        `"` + hex.EncodeToString(digest[:]) + `"`,
+		}
+		s.mu.Lock()
+		s.objects[name] = obj
+		s.mu.Unlock()
+		log.Printf("PUT %s (%d bytes)", name, len(data))
+		w.Header().Set("ETag", obj.etag)
+		w.WriteHeader(http.StatusOK)
+
+	case http.MethodGet, http.MethodHead:
+		s.mu.Lock()
+		obj, ok := s.objects[name]
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		if obj.contentType != "" {
+			w.Header().Set("Content-Type", obj.contentType)
+		}
+		if disposition := r.URL.Query().Get("response-content-disposition"); disposition != "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+		w.Header().Set("ETag", obj.etag)
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, name)
+		s.mu.Unlock()
+		log.Printf("DELETE %s", name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("object not found")
+	// ErrSignedURLUnsupported is returned by stores that can only be read
+	// through this server, so callers stream the object instead.
+	ErrSignedURLUnsupported = errors.New("storage backend does not issue signed URLs")
+)
+
+// Object describes a stored blob.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+	ETag        string
+}
+
+// Store keeps blobs under slash-separated keys such as "avatars/<file>".
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Name identifies the backend in logs
+	Name() string
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+	// Get returns the object's content, which the caller must close
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that fetches the object without credentials
+	// until ttl passes. downloadName, if set, is the file name offered to
+	// the browser.
+	SignedURL(ctx context.Context, key string, ttl time.Duration, downloadName string) (string, error)
+}
+
+// New builds the store selected by cfg.Backend.
+func New(cfg config.StorageConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// Key joins path segments into a key, refusing segments that could climb
+// out of their prefix.
+func Key(segments ...string) (string, error) {
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, "/\\") {
+			return "", fmt.Errorf("invalid key segment %q", segment)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// checkKey rejects keys that are empty, absolute or climb out of the store.
+func checkKey(key string) error {
+	if key == "" || path.Clean("/"+key) != "/"+key {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	return nil
+}
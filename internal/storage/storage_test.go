@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{"avatars/abc.png", false},
+		{"exports/2f1c.zip", false},
+		{"file", false},
+		{"", true},
+		{"/etc/passwd", true},
+		{"../secret", true},
+		{"avatars/../../secret", true},
+		{"avatars/./abc.png", true},
+		{"avatars//abc.png", true},
+		{"avatars/", true},
+		{"..", true},
+	}
+
+	for _, tt := range tests {
+		if err := checkKey(tt.key); (err != nil) != tt.wantErr {
+			t.Errorf("checkKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		segments []string
+		want     string
+		wantErr  bool
+	}{
+		{segments: []string{"avatars", "abc.png"}, want: "avatars/abc.png"},
+		{segments: []string{"logos", "svc", "logo.webp"}, want: "logos/svc/logo.webp"},
+		{segments: []string{"avatars", ".."}, wantErr: true},
+		{segments: []string{"avatars", "."}, wantErr: true},
+		{segments: []string{"avatars", ""}, wantErr: true},
+		{segments: []string{"avatars", "a/b"}, wantErr: true},
+		{segments: []string{"avatars", `..\secret`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Key(tt.segments...)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Key(%q) = %q, %v, want %q (error %v)", tt.segments, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLocalStoreRejectsEscapingKeys(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(root, "store"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"../outside", "a/../../outside", "/outside"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), ""); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, _, err := store.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want a key error", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "outside")); !os.IsNotExist(err) {
+		t.Fatalf("a file was written outside the store: %v", err)
+	}
+
+	if err := store.Put(ctx, "avatars/a.png", strings.NewReader("png"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	body, object, err := store.Get(ctx, "avatars/a.png")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "png" || object.Size != 3 {
+		t.Fatalf("Get() = %q, %+v", data, object)
+	}
+}
+
+func TestSigV4(t *testing.T) {
+	signer := sigv4{accessKey: "AKID", secretKey: "secret", region: "us-east-1"}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newRequest := func() *http.Request {
+		req, _ := http.NewRequest(http.MethodPut, "http://s3.local/bucket/avatars/a%20b.png?x-id=PutObject", bytes.NewReader([]byte("data")))
+		signer.sign(req, "payload-hash", now)
+		return req
+	}
+
+	tests := []struct {
+		name    string
+		tamper  func(r *http.Request)
+		secret  string
+		region  string
+		at      time.Time
+		wantErr bool
+	}{
+		{name: "valid", at: now},
+		{name: "shortly after", at: now.Add(10 * time.Minute)},
+		{name: "wrong secret", secret: "other", at: now, wantErr: true},
+		{name: "wrong region", region: "eu-west-1", at: now, wantErr: true},
+		{name: "too old", at: now.Add(time.Hour), wantErr: true},
+		{name: "from the future", at: now.Add(-time.Hour), wantErr: true},
+		{name: "other path", tamper: func(r *http.Request) { r.URL.Path = "/bucket/avatars/other.png"; r.URL.RawPath = "" }, at: now, wantErr: true},
+		{name: "other method", tamper: func(r *http.Request) { r.Method = http.MethodDelete }, at: now, wantErr: true},
+		{name: "other payload hash", tamper: func(r *http.Request) { r.Header.Set("X-Amz-Content-Sha256", "changed") }, at: now, wantErr: true},
+		{name: "other query", tamper: func(r *http.Request) { r.URL.RawQuery = "x-id=DeleteObject" }, at: now, wantErr: true},
+		{name: "unsigned", tamper: func(r *http.Request) { r.Header.Del("Authorization") }, at: now, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest()
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+			secret, region := signer.secretKey, signer.region
+			if tt.secret != "" {
+				secret = tt.secret
+			}
+			if tt.region != "" {
+				region = tt.region
+			}
+
+			err := VerifySigV4(req, signer.accessKey, secret, region, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifySigV4() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPresign(t *testing.T) {
+	signer := sigv4{accessKey: "AKID", secretKey: "secret", region: "us-east-1"}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	object, _ := url.Parse("http://s3.local/bucket/exports/a.zip")
+
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		tamper  func(u *url.URL)
+		at      time.Time
+		wantErr bool
+	}{
+		{name: "within ttl", ttl: time.Minute, at: now.Add(30 * time.Second)},
+		{name: "expired", ttl: time.Minute, at: now.Add(2 * time.Minute), wantErr: true},
+		{name: "ttl capped at a week", ttl: 30 * 24 * time.Hour, at: now.Add(8 * 24 * time.Hour), wantErr: true},
+		{name: "other object", ttl: time.Minute, tamper: func(u *url.URL) { u.Path = "/bucket/exports/b.zip"; u.RawPath = "" }, at: now, wantErr: true},
+		{name: "extended expiry", ttl: time.Minute, tamper: func(u *url.URL) {
+			query := u.Query()
+			query.Set("X-Amz-Expires", "604800")
+			u.RawQuery = encodeQuery(query)
+		}, at: now.Add(2 * time.Minute), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			presigned := signer.presign(object, tt.ttl, now)
+			if tt.tamper != nil {
+				tt.tamper(presigned)
+			}
+			req, _ := http.NewRequest(http.MethodGet, presigned.String(), nil)
+
+			err := VerifySigV4(req, signer.accessKey, signer.secretKey, signer.region, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifySigV4() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package transfer keeps service ownership transfers that are waiting for
+// the nominated account to accept them.
+package transfer
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrNoPendingTransfer = errors.New("no pending transfer")
+	ErrInvalidToken      = errors.New("invalid or expired transfer link")
+)
+
+// Offer is a nomination of ToID as the new owner of a service by FromID,
+// its owner at the time.
+type Offer struct {
+	ServiceID uuid.UUID `json:"service_id"`
+	FromID    uuid.UUID `json:"from_id"`
+	ToID      uuid.UUID `json:"to_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// record is what is stored: the offer and the hash of the token that
+// accepts it.
+type record struct {
+	Offer
+	TokenHash string `json:"token_hash"`
+}
+
+// Store holds at most one offer per service; nominating someone else
+// replaces the earlier offer and invalidates its link. Only a hash of the
+// token is kept with the offer, so reading the store does not yield a
+// working link.
+type Store struct {
+	redis  *redis.Client
+	config config.TransferConfig
+}
+
+func NewStore(redis *redis.Client, cfg config.TransferConfig) *Store {
+	return &Store{redis: redis, config: cfg}
+}
+
+func offerKey(serviceID uuid.UUID) string {
+	return fmt.Sprintf("service_transfer:%s", serviceID)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// consumeScript deletes the offer only if it is still the one that was
+// read, so a link is accepted once and only while its offer is current.
+var consumeScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Create stores offer, replacing any earlier one for the service, and
+// returns the token that accepts it. Tokens name their service so they can
+// be looked up without an index.
+func (s *Store) Create(ctx context.Context, offer Offer) (string, *Offer, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := offer.ServiceID.String() + "." + base64.RawURLEncoding.EncodeToString(buf)
+
+	offer.ExpiresAt = time.Now().Add(s.config.TTL).UTC()
+	payload, err := json.Marshal(record{Offer: offer, TokenHash: hashToken(token)})
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.redis.Set(ctx, offerKey(offer.ServiceID), payload, s.config.TTL).Err(); err != nil {
+		return "", nil, err
+	}
+	return token, &offer, nil
+}
+
+// Offer creates offer and hands its token to notify, which tells the
+// nominated account. The offer is withdrawn again if notify fails, so none
+// is left waiting on a link nobody received.
+func (s *Store) Offer(ctx context.Context, offer Offer, notify func(token string) error) (*Offer, error) {
+	token, created, err := s.Create(ctx, offer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store service transfer: %w", err)
+	}
+	if err := notify(token); err != nil {
+		if _, cancelErr := s.Cancel(ctx, offer.ServiceID); cancelErr != nil {
+			err = fmt.Errorf("%w (withdrawing the offer also failed: %v)", err, cancelErr)
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// Pending returns the offer waiting on a service.
+func (s *Store) Pending(ctx context.Context, serviceID uuid.UUID) (*Offer, error) {
+	stored, _, err := s.load(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	return &stored.Offer, nil
+}
+
+// Cancel withdraws the offer on a service, if there is one.
+func (s *Store) Cancel(ctx context.Context, serviceID uuid.UUID) (bool, error) {
+	deleted, err := s.redis.Del(ctx, offerKey(serviceID)).Result()
+	return deleted > 0, err
+}
+
+// Lookup returns the offer token accepts without consuming it.
+func (s *Store) Lookup(ctx context.Context, token string) (*Offer, error) {
+	stored, _, err := s.match(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &stored.Offer, nil
+}
+
+// Consume returns the offer token accepts and removes it.
+func (s *Store) Consume(ctx context.Context, token string) (*Offer, error) {
+	stored, raw, err := s.match(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := consumeScript.Run(ctx, s.redis, []string{offerKey(stored.ServiceID)}, raw).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume transfer: %w", err)
+	}
+	if deleted != 1 {
+		return nil, ErrInvalidToken
+	}
+	return &stored.Offer, nil
+}
+
+// match loads the offer on the service token names and checks that token
+// is the one that accepts it.
+func (s *Store) match(ctx context.Context, token string) (*record, string, error) {
+	prefix, _, found := strings.Cut(token, ".")
+	if !found {
+		return nil, "", ErrInvalidToken
+	}
+	serviceID, err := uuid.Parse(prefix)
+	if err != nil {
+		return nil, "", ErrInvalidToken
+	}
+
+	stored, raw, err := s.load(ctx, serviceID)
+	if errors.Is(err, ErrNoPendingTransfer) {
+		return nil, "", ErrInvalidToken
+	} else if err != nil {
+		return nil, "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(stored.TokenHash), []byte(hashToken(token))) != 1 {
+		return nil, "", ErrInvalidToken
+	}
+	return stored, raw, nil
+}
+
+func (s *Store) load(ctx context.Context, serviceID uuid.UUID) (*record, string, error) {
+	raw, err := s.redis.Get(ctx, offerKey(serviceID)).Result()
+	if err == redis.Nil {
+		return nil, "", ErrNoPendingTransfer
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	var stored record
+	if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+		return nil, "", fmt.Errorf("corrupt transfer payload: %w", err)
+	}
+	return &stored, raw, nil
+}
@@ -0,0 +1,185 @@
+package transfer
+
+import (
+	"aspire-auth/internal/config"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewStore(client, config.TransferConfig{TTL: time.Hour}), mr
+}
+
+func newOffer() Offer {
+	return Offer{ServiceID: uuid.New(), FromID: uuid.New(), ToID: uuid.New()}
+}
+
+func TestCreateLookupConsume(t *testing.T) {
+	s, mr := newTestStore(t)
+	ctx := context.Background()
+	offer := newOffer()
+
+	token, created, err := s.Create(ctx, offer)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ToID != offer.ToID || created.ExpiresAt.IsZero() {
+		t.Fatalf("Create returned %+v", created)
+	}
+	if raw, _ := mr.Get(offerKey(offer.ServiceID)); strings.Contains(raw, token) {
+		t.Fatal("token is stored in plaintext")
+	}
+
+	// Looking up leaves the offer in place
+	for i := 0; i < 2; i++ {
+		found, err := s.Lookup(ctx, token)
+		if err != nil || found.ToID != offer.ToID {
+			t.Fatalf("Lookup %d = %+v, %v", i, found, err)
+		}
+	}
+
+	if _, err := s.Consume(ctx, token); err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	if _, err := s.Consume(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("second Consume = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := s.Pending(ctx, offer.ServiceID); !errors.Is(err, ErrNoPendingTransfer) {
+		t.Fatalf("Pending after Consume = %v, want %v", err, ErrNoPendingTransfer)
+	}
+}
+
+func TestInvalidTokens(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	offer := newOffer()
+	token, _, err := s.Create(ctx, offer)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no separator", "abc"},
+		{"not a service", "abc.def"},
+		{"wrong secret", offer.ServiceID.String() + ".wrong"},
+		{"other service", uuid.NewString() + token[strings.Index(token, "."):]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Lookup(ctx, tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Lookup = %v, want %v", err, ErrInvalidToken)
+			}
+			if _, err := s.Consume(ctx, tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Consume = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+
+	if _, err := s.Consume(ctx, token); err != nil {
+		t.Errorf("rejected tokens consumed the offer: %v", err)
+	}
+}
+
+func TestNewOfferReplacesOld(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	offer := newOffer()
+
+	first, _, err := s.Create(ctx, offer)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	offer.ToID = uuid.New()
+	second, _, err := s.Create(ctx, offer)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := s.Consume(ctx, first); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("replaced link: Consume = %v, want %v", err, ErrInvalidToken)
+	}
+	found, err := s.Consume(ctx, second)
+	if err != nil || found.ToID != offer.ToID {
+		t.Errorf("current link: Consume = %+v, %v", found, err)
+	}
+}
+
+func TestCancelAndExpiry(t *testing.T) {
+	s, mr := newTestStore(t)
+	ctx := context.Background()
+
+	offer := newOffer()
+	token, _, _ := s.Create(ctx, offer)
+	if cancelled, err := s.Cancel(ctx, offer.ServiceID); err != nil || !cancelled {
+		t.Fatalf("Cancel = %v, %v", cancelled, err)
+	}
+	if cancelled, _ := s.Cancel(ctx, offer.ServiceID); cancelled {
+		t.Error("second Cancel reported an offer")
+	}
+	if _, err := s.Lookup(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("cancelled link: Lookup = %v, want %v", err, ErrInvalidToken)
+	}
+
+	offer = newOffer()
+	token, _, _ = s.Create(ctx, offer)
+	mr.FastForward(time.Hour)
+	if _, err := s.Consume(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expired link: Consume = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestOffer(t *testing.T) {
+	errUndelivered := errors.New("mail queue unavailable")
+
+	tests := []struct {
+		name        string
+		notifyErr   error
+		wantPending bool
+	}{
+		{name: "notified", wantPending: true},
+		{name: "notification fails", notifyErr: errUndelivered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			ctx := context.Background()
+			offer := newOffer()
+
+			var sent string
+			created, err := s.Offer(ctx, offer, func(token string) error {
+				sent = token
+				return tt.notifyErr
+			})
+			if !errors.Is(err, tt.notifyErr) {
+				t.Fatalf("Offer error = %v, want %v", err, tt.notifyErr)
+			}
+			if (created != nil) != tt.wantPending {
+				t.Fatalf("Offer returned %+v", created)
+			}
+
+			_, err = s.Lookup(ctx, sent)
+			if tt.wantPending && err != nil {
+				t.Fatalf("notified link: Lookup = %v", err)
+			}
+			if !tt.wantPending && !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("withdrawn link: Lookup = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail is the form an email takes as a key, so that codes and
+// lookups keyed on it do not depend on how the address was typed.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
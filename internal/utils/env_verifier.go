@@ -13,11 +13,17 @@ func VerifyEnvironmentSetup() {
 	requiredVars := []string{
 		"PORT_ADDRESS",
 		"DB_CONNECTION_URL",
-		"ACCOUNT_ACCESS_TOKEN_SECRET_KEY",
-		"ACCOUNT_REFRESH_TOKEN_SECRET_KEY",
-		"SERVICE_ACCESS_TOKEN_SECRET_KEY",
-		"SERVICE_REFRESH_TOKEN_SECRET_KEY",
-		"SERVICE_ENCRYPT_SECRET_KEY",
+	}
+
+	// Secrets only live in the environment with the env key provider
+	envSecrets := os.Getenv("KEY_PROVIDER") == "" || os.Getenv("KEY_PROVIDER") == "env"
+	if envSecrets {
+		requiredVars = append(requiredVars,
+			"ACCOUNT_ACCESS_TOKEN_SECRET_KEY",
+			"ACCOUNT_REFRESH_TOKEN_SECRET_KEY",
+			"SERVICE_ACCESS_TOKEN_SECRET_KEY",
+			"SERVICE_REFRESH_TOKEN_SECRET_KEY",
+		)
 	}
 
 	missingVars := []string{}
@@ -27,12 +33,21 @@ func VerifyEnvironmentSetup() {
 		}
 	}
 
+	// Either the versioned key ring or the legacy single key must be present
+	if envSecrets && os.Getenv("SERVICE_ENCRYPT_KEYS") == "" && os.Getenv("SERVICE_ENCRYPT_SECRET_KEY") == "" {
+		missingVars = append(missingVars, "SERVICE_ENCRYPT_KEYS")
+	}
+
 	if len(missingVars) > 0 {
 		log.Printf("WARNING: Missing required environment variables: %v", missingVars)
 	} else {
 		log.Println("All required environment variables are set")
 	}
 
+	if !envSecrets {
+		return
+	}
+
 	// Verify service and account secrets are different
 	if os.Getenv("SERVICE_ACCESS_TOKEN_SECRET_KEY") == os.Getenv("ACCOUNT_ACCESS_TOKEN_SECRET_KEY") {
 		log.Printf("WARNING: Service and account access token secrets are identical. This can cause validation issues.")
@@ -71,19 +86,15 @@ func checkSecretKeyDuplicates() {
 func PrintConfigSummary(cfg *config.Config) {
 	fmt.Println("===== Configuration Summary =====")
 	fmt.Printf("Server Port: %s\n", cfg.Server.Port)
-	fmt.Printf("Account JWT Secrets: %s... / %s...\n",
-		maskString(cfg.JWT.Account.AccessTokenSecret, 8),
-		maskString(cfg.JWT.Account.RefreshTokenSecret, 8))
-	fmt.Printf("Service JWT Secrets: %s... / %s...\n",
-		maskString(cfg.JWT.Service.AccessTokenSecret, 8),
-		maskString(cfg.JWT.Service.RefreshTokenSecret, 8))
-	fmt.Println("===============================")
-}
-
-// maskString masks a string for secure display
-func maskString(s string, showChars int) string {
-	if len(s) <= showChars {
-		return s
+	fmt.Printf("Key Provider: %s\n", cfg.Keys.Backend)
+	fmt.Printf("Email Provider: %s\n", cfg.Email.Provider)
+	fmt.Printf("SMS Provider: %s\n", cfg.SMS.Provider)
+	fmt.Printf("Storage Backend: %s\n", cfg.Storage.Backend)
+	fmt.Printf("Account Deletion Grace Period: %s\n", cfg.Deletion.GracePeriod)
+	if cfg.JWT.Service.ServiceEncryptKeyVersion != 0 {
+		fmt.Printf("Service Encryption Key Version: %d\n", cfg.JWT.Service.ServiceEncryptKeyVersion)
+	} else {
+		fmt.Println("Service Encryption Key Version: latest")
 	}
-	return s[:showChars] + "***"
+	fmt.Println("===============================")
 }
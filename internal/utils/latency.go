@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LatencyTracker keeps a moving average of how long an operation takes, so a
+// path that skips the operation can take as long as it would have.
+type LatencyTracker struct {
+	mu      sync.Mutex
+	average time.Duration
+}
+
+// NewLatencyTracker starts the average at initial until real samples arrive.
+func NewLatencyTracker(initial time.Duration) *LatencyTracker {
+	return &LatencyTracker{average: initial}
+}
+
+// Observe folds one measured duration into the average.
+func (t *LatencyTracker) Observe(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// Weight each sample by 1/8 so a single slow request barely moves it
+	t.average += (d - t.average) / 8
+}
+
+// Average returns the current moving average.
+func (t *LatencyTracker) Average() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.average
+}
+
+// Wait blocks for the current average, or until ctx is done.
+func (t *LatencyTracker) Wait(ctx context.Context) {
+	timer := time.NewTimer(t.Average())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial time.Duration
+		samples []time.Duration
+		want    time.Duration
+	}{
+		{name: "no samples", initial: 20 * time.Millisecond, want: 20 * time.Millisecond},
+		{name: "one sample moves an eighth", initial: 0, samples: []time.Duration{80 * time.Millisecond}, want: 10 * time.Millisecond},
+		{name: "steady samples", initial: 40 * time.Millisecond, samples: []time.Duration{40 * time.Millisecond, 40 * time.Millisecond}, want: 40 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewLatencyTracker(tt.initial)
+			for _, d := range tt.samples {
+				tracker.Observe(d)
+			}
+			if got := tracker.Average(); got != tt.want {
+				t.Fatalf("Average() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyTrackerWait(t *testing.T) {
+	tracker := NewLatencyTracker(50 * time.Millisecond)
+
+	start := time.Now()
+	tracker.Wait(context.Background())
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("Wait() returned after %s, want at least the average", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	tracker.Wait(ctx)
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("Wait() ignored a cancelled context for %s", elapsed)
+	}
+}
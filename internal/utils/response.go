@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"aspire-auth/internal/password"
 	"aspire-auth/internal/response"
 	"log"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -26,3 +30,34 @@ func HandleDBError(c *fiber.Ctx, err error, message string) error {
 	log.Printf("Database error: %v", err)
 	return SendError(c, fiber.StatusInternalServerError, message)
 }
+
+// SendTooManyRequests responds with 429 and a Retry-After header in whole seconds
+func SendTooManyRequests(c *fiber.Ctx, retryAfter time.Duration, message string) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return SendError(c, fiber.StatusTooManyRequests, message)
+}
+
+const (
+	UniformCredentialMessage = "Invalid credentials"
+	UniformRecoveryMessage   = "If the account exists, we have sent further instructions"
+)
+
+// SendCredentialError hides the specific failure behind a single 401 response
+// when uniform responses are enabled
+func SendCredentialError(c *fiber.Ctx, uniform bool, status int, message string) error {
+	if uniform {
+		return SendError(c, fiber.StatusUnauthorized, UniformCredentialMessage)
+	}
+	return SendError(c, status, message)
+}
+
+// SendPasswordViolations rejects a password with 422 and the list of broken rules
+func SendPasswordViolations(c *fiber.Ctx, violations []password.Violation) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(response.PasswordPolicyErrorResponse{
+		APIResponse: response.APIResponse{
+			Success: false,
+			Message: "Password does not meet the password policy",
+		},
+		Violations: violations,
+	})
+}
@@ -0,0 +1,261 @@
+package webhook
+
+import (
+	"aspire-auth/internal/audit"
+	"aspire-auth/internal/config"
+	"aspire-auth/internal/models"
+	"aspire-auth/internal/worker"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// claimLease is how long a claimed delivery is hidden from other senders.
+const claimLease = 5 * time.Minute
+
+// maxRetryDelay caps the exponential backoff between attempts.
+const maxRetryDelay = 6 * time.Hour
+
+// cleanupInterval is how often deliveries past retention are removed.
+const cleanupInterval = time.Hour
+
+// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>", where the
+// HMAC is keyed with the webhook secret over "<t>.<request body>".
+const SignatureHeader = "X-Webhook-Signature"
+
+// Secrets decrypts stored webhook secrets.
+type Secrets interface {
+	DecryptServiceSecretKey(encrypted string) (string, error)
+}
+
+// Dispatcher sends queued deliveries from a background loop.
+type Dispatcher struct {
+	db      *gorm.DB
+	client  *http.Client
+	secrets Secrets
+	audit   audit.Recorder
+	config  config.WebhookConfig
+}
+
+func NewDispatcher(db *gorm.DB, secrets Secrets, recorder audit.Recorder, cfg config.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		db:      db,
+		client:  newClient(cfg.Timeout, cfg.AllowInsecure),
+		secrets: secrets,
+		audit:   recorder,
+		config:  cfg,
+	}
+}
+
+// Sign returns the signature header value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run sends pending deliveries until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("Webhook dispatcher started, polling every %s", d.config.PollInterval)
+
+	worker.Loop{
+		Name:            "Webhook dispatcher",
+		Interval:        d.config.PollInterval,
+		BatchSize:       d.config.BatchSize,
+		Process:         d.dispatch,
+		Cleanup:         d.cleanup,
+		CleanupInterval: cleanupInterval,
+	}.Run(ctx)
+}
+
+// dispatch claims one batch of due deliveries and attempts each of them.
+func (d *Dispatcher) dispatch(ctx context.Context) (int, error) {
+	batch, err := worker.ClaimDue[models.WebhookDelivery](ctx, d.db, models.WebhookPending, d.config.BatchSize, claimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range batch {
+		d.deliver(ctx, &batch[i])
+	}
+	return len(batch), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	var webhook models.Webhook
+	if err := d.db.WithContext(ctx).Where("id = ?", delivery.WebhookID).First(&webhook).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Error loading webhook %s: %v", delivery.WebhookID, err)
+		}
+		return
+	}
+	if webhook.DisabledAt != nil {
+		d.finish(delivery, map[string]interface{}{
+			"status":     models.WebhookFailed,
+			"last_error": "webhook disabled",
+		})
+		return
+	}
+
+	status, err := d.send(ctx, &webhook, delivery)
+
+	attempts := delivery.Attempts + 1
+	updates := map[string]interface{}{
+		"attempts":        attempts,
+		"response_status": status,
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = models.WebhookDelivered
+		updates["delivered_at"] = time.Now()
+		updates["last_error"] = ""
+	case attempts >= d.config.MaxAttempts:
+		log.Printf("Giving up on webhook delivery %s to %s after %d attempts: %v", delivery.ID, webhook.URL, attempts, err)
+		updates["status"] = models.WebhookFailed
+		updates["last_error"] = err.Error()
+	default:
+		updates["next_attempt_at"] = time.Now().Add(worker.RetryDelay(d.config.RetryBaseDelay, maxRetryDelay, attempts))
+		updates["last_error"] = err.Error()
+	}
+	d.finish(delivery, updates)
+	d.recordHealth(ctx, &webhook, err)
+}
+
+// send posts the delivery and returns the response status, with an error
+// unless it was 2xx.
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	secret, err := d.secrets.DecryptServiceSecretKey(webhook.Secret)
+	if err != nil {
+		return 0, fmt.Errorf("decrypting webhook secret: %w", err)
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(sendCtx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "aspire-auth-webhooks/1.0")
+	req.Header.Set("X-Webhook-Id", delivery.ID.String())
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) finish(delivery *models.WebhookDelivery, updates map[string]interface{}) {
+	if err := d.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		log.Printf("Error updating webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// healthChange is what a delivery attempt changes about its webhook.
+type healthChange int
+
+const (
+	healthUnchanged healthChange = iota
+	// healthRecovered clears the failure streak
+	healthRecovered
+	// healthFailing starts a failure streak
+	healthFailing
+	// healthDisable ends a streak that has lasted DisableAfter
+	healthDisable
+)
+
+// nextHealth decides what an attempt that ended with sendErr at now means
+// for a webhook failing since failingSince, or healthy when it is nil.
+func nextHealth(failingSince *time.Time, sendErr error, now time.Time, disableAfter time.Duration) healthChange {
+	switch {
+	case sendErr == nil && failingSince == nil:
+		return healthUnchanged
+	case sendErr == nil:
+		return healthRecovered
+	case failingSince == nil:
+		return healthFailing
+	case now.Sub(*failingSince) < disableAfter:
+		return healthUnchanged
+	default:
+		return healthDisable
+	}
+}
+
+// recordHealth tracks how long the webhook has been failing and disables it
+// once that exceeds DisableAfter, failing its remaining deliveries.
+func (d *Dispatcher) recordHealth(ctx context.Context, webhook *models.Webhook, sendErr error) {
+	db := d.db.WithContext(ctx)
+	switch nextHealth(webhook.FailingSince, sendErr, time.Now(), d.config.DisableAfter) {
+	case healthUnchanged:
+		return
+	case healthRecovered:
+		if err := db.Model(webhook).Update("failing_since", nil).Error; err != nil {
+			log.Printf("Error updating webhook %s: %v", webhook.ID, err)
+		}
+		return
+	case healthFailing:
+		if err := db.Model(webhook).Where("failing_since IS NULL").Update("failing_since", time.Now()).Error; err != nil {
+			log.Printf("Error updating webhook %s: %v", webhook.ID, err)
+		}
+		return
+	}
+
+	reason := fmt.Sprintf("Failing since %s, last error: %v", webhook.FailingSince.UTC().Format(time.RFC3339), sendErr)
+	var disabled int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Webhook{}).
+			Where("id = ? AND disabled_at IS NULL", webhook.ID).
+			Updates(map[string]interface{}{"disabled_at": time.Now(), "disabled_reason": reason})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		disabled = result.RowsAffected
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("webhook_id = ? AND status = ?", webhook.ID, models.WebhookPending).
+			Updates(map[string]interface{}{"status": models.WebhookFailed, "last_error": "webhook disabled"}).Error
+	})
+	if err != nil {
+		log.Printf("Error disabling webhook %s: %v", webhook.ID, err)
+		return
+	}
+	if disabled > 0 {
+		log.Printf("Disabled webhook %s to %s: %s", webhook.ID, webhook.URL, reason)
+		d.audit.Record(ctx, audit.System(audit.EventWebhookDisable).WithService(webhook.ServiceID).WithSubject(webhook.ID.String()).WithMetadata(map[string]interface{}{
+			"url":    webhook.URL,
+			"reason": reason,
+		}))
+	}
+}
+
+// cleanup removes finished deliveries older than the retention period.
+func (d *Dispatcher) cleanup(ctx context.Context) error {
+	return d.db.WithContext(ctx).
+		Where("status <> ? AND created_at < ?", models.WebhookPending, time.Now().Add(-d.config.Retention)).
+		Delete(&models.WebhookDelivery{}).Error
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"aspire-auth/internal/events"
+	"aspire-auth/internal/models"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscribedEvents are the domain events that become webhook deliveries.
+var SubscribedEvents = []string{events.MemberJoined, events.MemberLeft, events.AccountUpdated, events.AccountDeleted}
+
+// Subscriber queues webhook deliveries for domain events. Deliveries reuse
+// the domain event's ID, so a retried event is not queued twice.
+func Subscriber(db *gorm.DB) events.Handler {
+	return func(ctx context.Context, event *events.Envelope) error {
+		switch event.Type {
+		case events.MemberJoined:
+			var payload events.MembershipPayload
+			if err := event.Decode(&payload); err != nil {
+				return err
+			}
+			account, err := loadAccount(ctx, db, payload.AccountID)
+			if account == nil {
+				return err
+			}
+			return Publish(ctx, db, event.ID, payload.ServiceID, EventUserSignedUp, UserData(account))
+
+		case events.MemberLeft:
+			var payload events.MembershipPayload
+			if err := event.Decode(&payload); err != nil {
+				return err
+			}
+			return Publish(ctx, db, event.ID, payload.ServiceID, EventUserLeft, UserRef{UserID: payload.AccountID})
+
+		case events.AccountUpdated:
+			var payload events.AccountUpdatedPayload
+			if err := event.Decode(&payload); err != nil {
+				return err
+			}
+			account, err := loadAccount(ctx, db, payload.AccountID)
+			if account == nil {
+				return err
+			}
+			var serviceIDs []uuid.UUID
+			err = db.WithContext(ctx).Model(&models.ServicesUser{}).Where("user_id = ?", account.ID).Distinct().Pluck("service_id", &serviceIDs).Error
+			if err != nil {
+				return err
+			}
+			return publishAll(ctx, db, event.ID, serviceIDs, EventUserUpdated, UserData(account))
+
+		case events.AccountDeleted:
+			var payload events.AccountDeletedPayload
+			if err := event.Decode(&payload); err != nil {
+				return err
+			}
+			return publishAll(ctx, db, event.ID, payload.ServiceIDs, EventUserDeleted, UserRef{UserID: payload.AccountID})
+		}
+		return nil
+	}
+}
+
+func publishAll(ctx context.Context, db *gorm.DB, eventID uuid.UUID, serviceIDs []uuid.UUID, eventType string, data interface{}) error {
+	for _, serviceID := range serviceIDs {
+		if err := Publish(ctx, db, eventID, serviceID, eventType, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadAccount returns nil without error when the account no longer exists,
+// as there is then nobody left to describe.
+func loadAccount(ctx context.Context, db *gorm.DB, id uuid.UUID) (*models.Account, error) {
+	var account models.Account
+	err := db.WithContext(ctx).Where("id = ?", id).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
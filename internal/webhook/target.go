@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var errForbiddenAddress = errors.New("webhook URL resolves to a private or reserved address")
+
+// ValidateURL checks that raw is somewhere webhooks may be sent. Unless
+// allowInsecure is set it must use https and must not name a private,
+// loopback or otherwise internal address.
+func ValidateURL(raw string, allowInsecure bool) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" || target.Hostname() == "" {
+		return errors.New("webhook URL must be an absolute URL")
+	}
+	switch {
+	case target.Scheme == "https":
+	case target.Scheme == "http" && allowInsecure:
+	default:
+		return errors.New("webhook URL must use https")
+	}
+	if target.User != nil {
+		return errors.New("webhook URL must not contain credentials")
+	}
+	if addr, err := netip.ParseAddr(target.Hostname()); err == nil && !allowInsecure && !publicAddress(addr) {
+		return errForbiddenAddress
+	}
+	return nil
+}
+
+// publicAddress reports whether addr is routable on the public internet.
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() && !addr.IsLinkLocalUnicast()
+}
+
+// newClient returns the client deliveries are sent with. Unless
+// allowInsecure is set, it refuses to connect to internal addresses at dial
+// time, so a hostname that later resolves inside the network is caught too.
+// Redirects are not followed.
+func newClient(timeout time.Duration, allowInsecure bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowInsecure {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("unexpected dial address %q: %w", address, err)
+			}
+			if !publicAddress(addrPort.Addr()) {
+				return errForbiddenAddress
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     time.Minute,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,151 @@
+// Package webhook tells service owners about their users. Events are queued
+// as WEBHOOK_DELIVERIES rows for every webhook subscribed to them, then sent
+// by a background loop that signs each request and retries failures.
+package webhook
+
+import (
+	"aspire-auth/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Event types a webhook can subscribe to.
+const (
+	EventUserSignedUp = "user.signed_up"
+	EventUserLeft     = "user.left"
+	EventUserUpdated  = "user.updated"
+	EventUserDeleted  = "user.deleted"
+)
+
+// Catalog lists every event type in the order they are documented.
+var Catalog = []string{EventUserSignedUp, EventUserLeft, EventUserUpdated, EventUserDeleted}
+
+// Payload is the JSON body of every delivery.
+type Payload struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	ServiceID uuid.UUID   `json:"service_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// User is the profile sent with user.signed_up and user.updated.
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Avatar    *string   `json:"avatar,omitempty"`
+	Locale    *string   `json:"locale,omitempty"`
+}
+
+// UserRef identifies the user in user.left and user.deleted.
+type UserRef struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func UserData(account *models.Account) User {
+	return User{
+		ID:        account.ID,
+		Username:  account.Username,
+		Email:     account.Email,
+		FirstName: account.FirstName,
+		LastName:  account.LastName,
+		Avatar:    account.Avatar,
+		Locale:    account.Locale,
+	}
+}
+
+// ParseEvents checks requested against the catalog and returns the stored
+// form, or false if any entry is unknown or none were given.
+func ParseEvents(requested []string) (string, bool) {
+	var events []string
+	for _, event := range requested {
+		if !slices.Contains(Catalog, event) {
+			return "", false
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	return strings.Join(events, ","), len(events) > 0
+}
+
+// Events splits the stored form of a webhook's subscriptions.
+func Events(webhook *models.Webhook) []string {
+	return strings.Split(webhook.Events, ",")
+}
+
+// NewSecret returns a random signing secret for a new webhook.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Publish queues an event for every enabled webhook of serviceID subscribed
+// to eventType. eventID identifies the event to receivers; webhooks that
+// already have a delivery for it are skipped, so publishing again is safe.
+func Publish(ctx context.Context, db *gorm.DB, eventID, serviceID uuid.UUID, eventType string, data interface{}) error {
+	var webhooks []models.Webhook
+	err := db.WithContext(ctx).
+		Where("service_id = ? AND disabled_at IS NULL", serviceID).
+		Where("NOT EXISTS (SELECT 1 FROM webhook_deliveries WHERE webhook_deliveries.webhook_id = webhooks.id AND webhook_deliveries.event_id = ?)", eventID).
+		Find(&webhooks).Error
+	if err != nil {
+		return err
+	}
+
+	payload := Payload{ID: eventID, Type: eventType, ServiceID: serviceID, CreatedAt: time.Now().UTC(), Data: data}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for i := range webhooks {
+		if !slices.Contains(Events(&webhooks[i]), eventType) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhooks[i].ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			Payload:       string(body),
+			Status:        models.WebhookPending,
+			NextAttemptAt: time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return db.WithContext(ctx).Create(&deliveries).Error
+}
+
+// Redeliver queues delivery's event again for its webhook, keeping the
+// event ID so the receiver can tell it is a repeat.
+func Redeliver(ctx context.Context, db *gorm.DB, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	again := models.WebhookDelivery{
+		WebhookID:     delivery.WebhookID,
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Payload:       delivery.Payload,
+		Status:        models.WebhookPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := db.WithContext(ctx).Create(&again).Error; err != nil {
+		return nil, err
+	}
+	return &again, nil
+}
@@ -0,0 +1,114 @@
+// Package worker holds the pieces shared by the background queues: a polling
+// loop, claiming rows with a lease and capped exponential backoff.
+//
+// Queue rows are claimed with SELECT ... FOR UPDATE SKIP LOCKED and pushed
+// out of view for a lease while they are worked on, so several instances can
+// drain one table and a worker that dies mid-batch leaves its rows to be
+// picked up once the lease runs out.
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Loop polls a queue until its context is cancelled.
+type Loop struct {
+	// Name identifies the worker in logs
+	Name string
+	// Interval is how long to wait between polls once the queue is drained
+	Interval time.Duration
+	// BatchSize is the most rows Process handles at once. A full batch means
+	// more may be waiting, so Process is called again straight away.
+	BatchSize int
+	// Process handles one batch and returns how many rows it took
+	Process func(ctx context.Context) (int, error)
+
+	// Cleanup, when set, runs after draining at most once per CleanupInterval,
+	// or after every poll when CleanupInterval is zero
+	Cleanup         func(ctx context.Context) error
+	CleanupInterval time.Duration
+}
+
+// Run processes batches until ctx is cancelled.
+func (l Loop) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.Interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		// Keep draining while full batches come back
+		for {
+			handled, err := l.Process(ctx)
+			if err != nil {
+				log.Printf("%s error: %v", l.Name, err)
+				break
+			}
+			if handled < l.BatchSize {
+				break
+			}
+		}
+
+		if l.Cleanup != nil && time.Since(lastCleanup) >= l.CleanupInterval {
+			if err := l.Cleanup(ctx); err != nil {
+				log.Printf("%s cleanup error: %v", l.Name, err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Claim locks up to limit rows of T matching query, oldest first by order,
+// skipping rows other workers hold, and applies claim to them before the
+// lock is released. T must have a primary key.
+func Claim[T any](ctx context.Context, db *gorm.DB, limit int, order string, claim map[string]interface{}, query string, args ...interface{}) ([]T, error) {
+	var batch []T
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where(query, args...).
+			Order(order).
+			Limit(limit).
+			Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+		// A slice model is updated by the primary keys of its rows
+		return tx.Model(&batch).Updates(claim).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// ClaimDue claims up to limit rows of T in status whose next_attempt_at has
+// passed, hiding them from other workers for lease.
+func ClaimDue[T any](ctx context.Context, db *gorm.DB, status interface{}, limit int, lease time.Duration) ([]T, error) {
+	now := time.Now()
+	return Claim[T](ctx, db, limit, "next_attempt_at",
+		map[string]interface{}{"next_attempt_at": now.Add(lease)},
+		"status = ? AND next_attempt_at <= ?", status, now)
+}
+
+// RetryDelay is the wait before the next attempt after attempts failures:
+// base doubled for each failure after the first, capped at max.
+func RetryDelay(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{name: "first failure", attempts: 1, want: 30 * time.Second},
+		{name: "doubles", attempts: 2, want: time.Minute},
+		{name: "keeps doubling", attempts: 4, want: 4 * time.Minute},
+		{name: "capped", attempts: 10, want: 10 * time.Minute},
+		{name: "far past the cap", attempts: 1000, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetryDelay(30*time.Second, 10*time.Minute, tt.attempts); got != tt.want {
+				t.Fatalf("RetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoopDrainsFullBatches(t *testing.T) {
+	tests := []struct {
+		name        string
+		batches     []int
+		err         error
+		wantCalls   int
+		wantCleanup int
+	}{
+		{name: "empty queue", batches: []int{0}, wantCalls: 1, wantCleanup: 1},
+		{name: "full batches", batches: []int{5, 5, 2}, wantCalls: 3, wantCleanup: 1},
+		{name: "error stops draining", batches: []int{5}, err: errors.New("db down"), wantCalls: 1, wantCleanup: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			calls, cleanups := 0, 0
+			Loop{
+				Name:      "test",
+				Interval:  time.Hour,
+				BatchSize: 5,
+				Process: func(context.Context) (int, error) {
+					calls++
+					if tt.err != nil {
+						return 0, tt.err
+					}
+					return tt.batches[calls-1], nil
+				},
+				Cleanup: func(context.Context) error {
+					cleanups++
+					// Stop after the first poll
+					cancel()
+					return nil
+				},
+			}.Run(ctx)
+
+			if calls != tt.wantCalls || cleanups != tt.wantCleanup {
+				t.Fatalf("Process called %d times and Cleanup %d, want %d and %d", calls, cleanups, tt.wantCalls, tt.wantCleanup)
+			}
+		})
+	}
+}
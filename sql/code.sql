@@ -17,25 +17,63 @@ CREATE TABLE IF NOT EXISTS ACCOUNTS (
     role_type ROLE_TYPE DEFAULT 'USER',
     is_verified BOOLEAN DEFAULT FALSE,
     avatar TEXT,
+    locale TEXT, -- Preferred language for emails, e.g. 'en' or 'es'
+    phone_number TEXT UNIQUE, -- E.164, e.g. +14155550123
+    phone_verified BOOLEAN DEFAULT FALSE,
+    otp_channel TEXT DEFAULT 'email' NOT NULL, -- 'email' or 'sms'
+    mfa_enabled BOOLEAN DEFAULT FALSE,
+    deletion_scheduled_at TIMESTAMP, -- Set while a requested deletion is in its grace period
+    disabled_at TIMESTAMP, -- Set by a platform admin; disabled accounts cannot sign in
 
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
+CREATE INDEX IF NOT EXISTS ACCOUNTS_DELETION_IDX ON ACCOUNTS (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
+
+-- Organizations Table (Teams that manage services together)
+CREATE TABLE IF NOT EXISTS ORGANIZATIONS (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    name TEXT NOT NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Organization Members Table
+CREATE TABLE IF NOT EXISTS ORGANIZATION_MEMBERS (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    organization_id UUID NOT NULL REFERENCES ORGANIZATIONS(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES ACCOUNTS(id) ON DELETE CASCADE,
+    role TEXT NOT NULL, -- OWNER, ADMIN, DEVELOPER or VIEWER
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    UNIQUE (organization_id, account_id)
+);
+CREATE INDEX IF NOT EXISTS ORGANIZATION_MEMBERS_ACCOUNT_IDX ON ORGANIZATION_MEMBERS (account_id);
+
 -- Services Table
 CREATE TABLE IF NOT EXISTS SERVICES (
     id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
     
     owner_id UUID REFERENCES ACCOUNTS(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES ORGANIZATIONS(id) ON DELETE RESTRICT, -- Set when the service is managed by an organization
     service_name TEXT UNIQUE NOT NULL,
     service_logo TEXT,
     secret_key TEXT NOT NULL, -- Added column for storing encrypted service secret
     service_description TEXT,
+    brand_color TEXT, -- Hex accent color used in emails sent for this service
+    disabled_at TIMESTAMP, -- Set by a platform admin; disabled services reject sign-ins and tokens
 
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
+CREATE INDEX IF NOT EXISTS SERVICES_ORGANIZATION_IDX ON SERVICES (organization_id) WHERE organization_id IS NOT NULL;
+
 -- Service Users Table (Tracks which users are using which services)
 CREATE TABLE IF NOT EXISTS SERVICES_USERS (
     id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
@@ -61,6 +99,121 @@ CREATE TABLE IF NOT EXISTS REFRESH_TOKENS (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
+-- Audit Events Table (append-only record of security-relevant actions)
+CREATE TABLE IF NOT EXISTS AUDIT_EVENTS (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    event_type TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    account_id UUID,
+    service_id UUID,
+    subject TEXT,
+    reason TEXT,
+    ip_address TEXT,
+    user_agent TEXT,
+    metadata JSONB,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS AUDIT_EVENTS_ACCOUNT_IDX ON AUDIT_EVENTS (account_id, created_at);
+CREATE INDEX IF NOT EXISTS AUDIT_EVENTS_SERVICE_IDX ON AUDIT_EVENTS (service_id, created_at);
+CREATE INDEX IF NOT EXISTS AUDIT_EVENTS_TYPE_IDX ON AUDIT_EVENTS (event_type, created_at);
+
+-- Outgoing email queue drained by the background sender
+CREATE TABLE IF NOT EXISTS OUTBOX_EMAILS (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    html_body TEXT NOT NULL, -- Encrypted envelope, blanked once sent
+    text_body TEXT,
+    status TEXT NOT NULL DEFAULT 'PENDING', -- PENDING, SENT or FAILED
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS OUTBOX_EMAILS_PENDING_IDX ON OUTBOX_EMAILS (next_attempt_at) WHERE status = 'PENDING';
+
+-- Domain events written with the change that raised them, handed to subscribers by the event bus
+CREATE TABLE IF NOT EXISTS DOMAIN_EVENTS (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    audit JSONB, -- Audit event recorded once the change is committed
+    status TEXT NOT NULL DEFAULT 'PENDING', -- PENDING, PROCESSED or FAILED
+    completed TEXT NOT NULL DEFAULT '', -- Comma-separated subscribers that have handled the event
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL,
+    processed_at TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS DOMAIN_EVENTS_PENDING_IDX ON DOMAIN_EVENTS (next_attempt_at) WHERE status = 'PENDING';
+
+-- Personal data export archives built by the background worker
+CREATE TABLE IF NOT EXISTS ACCOUNT_EXPORTS (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    account_id UUID NOT NULL REFERENCES ACCOUNTS(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'PENDING', -- PENDING, PROCESSING, READY or FAILED
+    storage_key TEXT, -- Archive key in blob storage, set once READY
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP, -- The archive and this row are removed after this time
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS ACCOUNT_EXPORTS_ACCOUNT_IDX ON ACCOUNT_EXPORTS (account_id, created_at);
+CREATE INDEX IF NOT EXISTS ACCOUNT_EXPORTS_STATUS_IDX ON ACCOUNT_EXPORTS (status, updated_at);
+
+-- Webhook subscriptions of services to user events
+CREATE TABLE IF NOT EXISTS WEBHOOKS (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    service_id UUID NOT NULL REFERENCES SERVICES(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    events TEXT NOT NULL, -- Comma-separated event types, e.g. 'user.signed_up,user.left'
+    secret TEXT NOT NULL, -- Encrypted signing secret
+    failing_since TIMESTAMP, -- First failure since the last successful delivery
+    disabled_at TIMESTAMP, -- Set when the endpoint kept failing; deliveries stop until re-enabled
+    disabled_reason TEXT,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS WEBHOOKS_SERVICE_IDX ON WEBHOOKS (service_id);
+
+-- Webhook delivery queue and log
+CREATE TABLE IF NOT EXISTS WEBHOOK_DELIVERIES (
+    id UUID PRIMARY KEY DEFAULT GEN_RANDOM_UUID(),
+
+    webhook_id UUID NOT NULL REFERENCES WEBHOOKS(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL, -- Shared by redeliveries of the same event
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING', -- PENDING, DELIVERED or FAILED
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL,
+    delivered_at TIMESTAMP,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS WEBHOOK_DELIVERIES_WEBHOOK_IDX ON WEBHOOK_DELIVERIES (webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS WEBHOOK_DELIVERIES_PENDING_IDX ON WEBHOOK_DELIVERIES (next_attempt_at) WHERE status = 'PENDING';
+
 -- Function to update updated_at timestamp
 CREATE OR REPLACE FUNCTION update_updated_at()
 RETURNS TRIGGER AS $$
@@ -85,6 +238,16 @@ BEFORE UPDATE ON ACCOUNTS
 FOR EACH ROW
 EXECUTE FUNCTION update_updated_at();
 
+CREATE TRIGGER ORGANIZATIONS_UPDATE_TRIGGER
+BEFORE UPDATE ON ORGANIZATIONS
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at();
+
+CREATE TRIGGER ORGANIZATION_MEMBERS_UPDATE_TRIGGER
+BEFORE UPDATE ON ORGANIZATION_MEMBERS
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at();
+
 CREATE TRIGGER SERVICES_UPDATE_TRIGGER
 BEFORE UPDATE ON SERVICES
 FOR EACH ROW
@@ -100,6 +263,26 @@ BEFORE UPDATE ON REFRESH_TOKENS
 FOR EACH ROW
 EXECUTE FUNCTION update_updated_at();
 
+CREATE TRIGGER ACCOUNT_EXPORTS_UPDATE_TRIGGER
+BEFORE UPDATE ON ACCOUNT_EXPORTS
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at();
+
+CREATE TRIGGER DOMAIN_EVENTS_UPDATE_TRIGGER
+BEFORE UPDATE ON DOMAIN_EVENTS
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at();
+
+CREATE TRIGGER WEBHOOKS_UPDATE_TRIGGER
+BEFORE UPDATE ON WEBHOOKS
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at();
+
+CREATE TRIGGER WEBHOOK_DELIVERIES_UPDATE_TRIGGER
+BEFORE UPDATE ON WEBHOOK_DELIVERIES
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at();
+
 -- Trigger to delete expired refresh tokens automatically
 CREATE TRIGGER DELETE_EXPIRED_TOKENS_TRIGGER
 AFTER INSERT OR UPDATE ON REFRESH_TOKENS
@@ -0,0 +1,12 @@
+// Package templates holds the transactional email templates and their
+// translations. They are embedded so the binary does not depend on the
+// working directory it is started from.
+package templates
+
+import "embed"
+
+// FS contains layout.html and layout.txt, one <name>.html and <name>.txt
+// pair per email, and a locales/<locale>.json message catalog per language.
+//
+//go:embed *.html *.txt locales/*.json
+var FS embed.FS